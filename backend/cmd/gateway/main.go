@@ -15,6 +15,7 @@ type session struct {
 	PlayerID string    `json:"player_id"`
 	Name     string    `json:"name"`
 	Token    string    `json:"token"`
+	Role     string    `json:"role"`
 	LastSeen time.Time `json:"last_seen"`
 }
 
@@ -22,10 +23,23 @@ type gateway struct {
 	mu         sync.Mutex
 	sessions   map[string]session // token -> session
 	simAddress string
+	// simSecure advertises wss:// and https:// sim addresses
+	simSecure bool
+	// observerKey must be presented to obtain an observer-role token; empty disables observer logins
+	observerKey string
 }
 
-func newGateway(simAddr string) *gateway {
-	return &gateway{sessions: make(map[string]session), simAddress: simAddr}
+func newGateway(simAddr, observerKey string, simSecure bool) *gateway {
+	return &gateway{sessions: make(map[string]session), simAddress: simAddr, observerKey: observerKey, simSecure: simSecure}
+}
+
+// simURL builds an advertised sim address for scheme ("ws" or "http"),
+// upgrading it to the TLS variant when the sim is served over TLS.
+func (g *gateway) simURL(scheme, path string) string {
+	if g.simSecure {
+		scheme += "s"
+	}
+	return scheme + "://" + g.simAddress + path
 }
 
 func (g *gateway) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -33,27 +47,56 @@ func (g *gateway) handleLogin(w http.ResponseWriter, r *http.Request) {
 	if name == "" {
 		name = "Player"
 	}
+	// Observers (QA, streamers) watch the world read-only via /observe
+	role := "player"
+	sim := simEndpoint{Address: g.simURL("ws", "/ws"), Protocol: "ws-json"}
+	fallback := simEndpoint{Address: g.simURL("http", "/sse"), Protocol: "sse-json"}
+	// Players whose proxies kill WebSockets ask for the HTTP fallback up front
+	// (?transport=sse); everyone else gets it as the endpoint to try next.
+	if r.URL.Query().Get("transport") == "sse" {
+		sim, fallback = fallback, sim
+	}
+	if r.URL.Query().Get("role") == "observer" {
+		if g.observerKey == "" || r.URL.Query().Get("key") != g.observerKey {
+			http.Error(w, "observer login not permitted", http.StatusForbidden)
+			return
+		}
+		role = "observer"
+		sim = simEndpoint{Address: g.simURL("ws", "/observe"), Protocol: "ws-json"}
+	}
 	tok := randomToken()
 	s := session{
 		PlayerID: randomToken()[:8],
 		Name:     name,
 		Token:    tok,
+		Role:     role,
 		LastSeen: time.Now(),
 	}
 	g.mu.Lock()
 	g.sessions[tok] = s
 	g.mu.Unlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
+	resp := map[string]any{
 		"token":     tok,
 		"player_id": s.PlayerID,
-		"sim": map[string]any{
-			"address":  "ws://" + g.simAddress + "/ws",
-			"protocol": "ws-json",
-			"version":  "1",
-		},
-	})
+		"role":      s.Role,
+		"sim":       sim.withVersion(),
+	}
+	if role == "player" {
+		resp["fallback"] = fallback.withVersion()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// simEndpoint is where and how a client connects to the sim.
+type simEndpoint struct {
+	Address  string
+	Protocol string // "ws-json" (WebSocket) or "sse-json" (SSE/long-poll down, POST up)
+}
+
+func (e simEndpoint) withVersion() map[string]any {
+	return map[string]any{"address": e.Address, "protocol": e.Protocol, "version": "1"}
 }
 
 func (g *gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +123,7 @@ func (g *gateway) handleValidate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]any{
 		"player_id": s.PlayerID,
 		"name":      s.Name,
+		"role":      s.Role,
 	})
 }
 
@@ -96,17 +140,31 @@ func randomToken() string {
 func main() {
 	var port = flag.String("port", "8080", "gateway port")
 	var simAddr = flag.String("sim", "localhost:8081", "sim service address")
+	var observerKey = flag.String("observer-key", "", "shared key required for observer logins (/login?role=observer&key=...); empty disables them")
+	var tlsCert = flag.String("tls-cert", "", "TLS certificate file; with -tls-key, serves HTTPS")
+	var tlsKey = flag.String("tls-key", "", "TLS private key file")
+	var simTLS = flag.Bool("sim-tls", false, "advertise wss:// and https:// sim addresses (implied by -tls-cert, since TLS pages cannot open plain sockets)")
 	flag.Parse()
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalf("gateway: -tls-cert and -tls-key must be set together")
+	}
+	useTLS := *tlsCert != ""
 
-	g := newGateway(*simAddr)
+	g := newGateway(*simAddr, *observerKey, *simTLS || useTLS)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", g.handleHealth)
 	mux.HandleFunc("/login", g.handleLogin)
 	mux.HandleFunc("/validate", g.handleValidate)
 
-	log.Printf("gateway: listening on :%s (sim=%s)", *port, *simAddr)
-	if err := http.ListenAndServe(":"+*port, mux); err != nil {
+	log.Printf("gateway: listening on :%s (sim=%s tls=%v)", *port, *simAddr, useTLS)
+	var err error
+	if useTLS {
+		err = http.ListenAndServeTLS(":"+*port, *tlsCert, *tlsKey, mux)
+	} else {
+		err = http.ListenAndServe(":"+*port, mux)
+	}
+	if err != nil {
 		log.Fatalf("gateway: %v", err)
 	}
 }
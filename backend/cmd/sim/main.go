@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,6 +20,9 @@ import (
 	"prototype-game/backend/internal/sim"
 	"prototype-game/backend/internal/spatial"
 	"prototype-game/backend/internal/state"
+	"prototype-game/backend/internal/transport/session"
+	transportsse "prototype-game/backend/internal/transport/sse"
+	"prototype-game/backend/internal/transport/udp"
 	transportws "prototype-game/backend/internal/transport/ws"
 )
 
@@ -44,6 +48,34 @@ func main() {
 		maxBots    = flag.Int("max-bots", 100, "maximum total bots across all cells")
 		storeFile  = flag.String("store-file", "", "file path for persistent player state store (default: in-memory)")
 		devMode    = flag.Bool("dev", false, "enable development mode (relaxed WebSocket origin checks)")
+		origins    = flag.String("origins", "", "comma-separated WebSocket origin patterns allowed outside dev mode, e.g. game.example.com,*.example.com (default: localhost)")
+		compress   = flag.String("ws-compression", "off", "WebSocket permessage-deflate: off, context-takeover or no-context-takeover")
+		compressAt = flag.Int("ws-compression-threshold", 0, "smallest WebSocket message compressed, in bytes (default: 128 with context takeover, 512 without)")
+		tlsCert    = flag.String("tls-cert", "", "TLS certificate file; with -tls-key, serves HTTPS/WSS")
+		tlsKey     = flag.String("tls-key", "", "TLS private key file")
+		resumeFile = flag.String("resume-file", "", "file path for durable session resume tokens (default: in-memory)")
+		resumeDSN  = flag.String("resume-dsn", "", "PostgreSQL DSN for resume tokens shared across sim instances (default: in-memory)")
+		resumeTTL  = flag.Duration("resume-ttl", 60*time.Second, "lifetime of session resume tokens")
+		udpAddr    = flag.String("udp-addr", "", "listen address for the UDP transport, e.g. :8082 (default: disabled)")
+		maxPerIP   = flag.Int("max-conns-per-ip", 0, "concurrent player connections allowed per remote IP across all transports (default: unlimited)")
+		worldFile  = flag.String("world-file", "", "JSON world data with spawn points (default: a single spawn at the origin)")
+		skillsFile = flag.String("skills-file", "", "JSON skill level curves keyed by skill (default: built-in curves)")
+		worldState = flag.String("world-state-file", "", "file path for persistent world state such as ground items (default: in-memory)")
+		invGrids   = flag.String("inventory-grids", "", "comma-separated inventory compartment grids, e.g. backpack=10x5,belt=5x2 (default: unordered compartments)")
+		// death and respawn
+		respawnDelay    = flag.Duration("respawn-delay", 10*time.Second, "how long players stay dead before respawning")
+		corpseTTL       = flag.Duration("corpse-ttl", 5*time.Minute, "how long corpses stay in the world")
+		deathDrop       = flag.Float64("death-drop-chance", 0.25, "chance each backpack item is dropped on the ground on death (0 keeps everything)")
+		deathDurability = flag.Float64("death-durability-loss", 0.1, "durability lost by each equipped item on death (0 disables)")
+		// items dropped on the ground
+		pickupRange   = flag.Float64("pickup-range", 3, "how close players must be to pick up ground items, in meters")
+		lootWindow    = flag.Duration("loot-window", time.Minute, "how long only the dropping player may pick up a ground item")
+		groundDespawn = flag.Duration("ground-despawn", 10*time.Minute, "how long dropped items stay on the ground")
+		// graceful drain on SIGINT/SIGTERM
+		drainTimeout    = flag.Duration("drain-timeout", 10*time.Second, "how long to wait for sessions to persist and close on shutdown")
+		reconnectAfter  = flag.Duration("reconnect-after", 2*time.Second, "reconnect delay suggested to clients on shutdown")
+		reconnectJitter = flag.Duration("reconnect-jitter", 3*time.Second, "random extra reconnect delay per client on shutdown")
+		reconnectAddr   = flag.String("reconnect-addr", "", "alternate websocket address suggested to clients on shutdown")
 	)
 	flag.Parse()
 
@@ -51,6 +83,32 @@ func main() {
 	if err := validateConfig(*cellSize, *aoiRadius, *tickHz, *snapshotHz, *hysteresis); err != nil {
 		log.Fatalf("sim: invalid configuration: %v", err)
 	}
+	if err := validateTLSFlags(*tlsCert, *tlsKey); err != nil {
+		log.Fatalf("sim: invalid configuration: %v", err)
+	}
+	originPatterns := parseOrigins(*origins)
+	compression, err := transportws.ParseCompressionMode(*compress)
+	if err != nil {
+		log.Fatalf("sim: invalid configuration: %v", err)
+	}
+
+	grids, err := parseInventoryGrids(*invGrids)
+	if err != nil {
+		log.Fatalf("sim: invalid configuration: %v", err)
+	}
+
+	var world *sim.World
+	if *worldFile != "" {
+		if world, err = sim.LoadWorld(*worldFile); err != nil {
+			log.Fatalf("sim: invalid configuration: %v", err)
+		}
+	}
+	var skillCurves map[string]sim.SkillCurve
+	if *skillsFile != "" {
+		if skillCurves, err = sim.LoadSkillCurves(*skillsFile); err != nil {
+			log.Fatalf("sim: invalid configuration: %v", err)
+		}
+	}
 
 	// Initialize Prometheus metrics registry and collectors
 	metrics.Init()
@@ -64,13 +122,54 @@ func main() {
 		TargetDensityPerCell: *botDensity,
 		MaxBots:              *maxBots,
 		DebugSnapshot:        *debug,
+		Death: sim.DeathConfig{
+			RespawnDelay:   *respawnDelay,
+			CorpseTTL:      *corpseTTL,
+			DropChance:     deathDrop,
+			DurabilityLoss: deathDurability,
+		},
+		Ground: sim.GroundConfig{
+			PickupRange:  *pickupRange,
+			OwnerWindow:  *lootWindow,
+			DespawnAfter: *groundDespawn,
+		},
+		World:          world,
+		SkillCurves:    skillCurves,
+		InventoryGrids: grids,
 	})
+
+	// Restore items left on the ground before the last shutdown.
+	var worldStore state.WorldStore = state.NewMemWorldStore()
+	if *worldState != "" {
+		fileWorld, err := state.NewFileWorldStore(*worldState)
+		if err != nil {
+			log.Fatalf("sim: failed to create world state store: %v", err)
+		}
+		worldStore = fileWorld
+		log.Printf("sim: using world state file at %s", *worldState)
+	}
+	if ws, ok, err := worldStore.LoadWorld(context.Background()); err != nil {
+		log.Fatalf("sim: failed to load world state: %v", err)
+	} else if ok {
+		if err := eng.RestoreWorldState(ws); err != nil {
+			log.Fatalf("sim: failed to restore world state: %v", err)
+		}
+	}
+	worldStopCh := startWorldSaves(eng, worldStore, 30*time.Second)
 	eng.Start()
 	log.Printf("sim: started. tick=%dHz snap=%dHz cell=%.0fm aoi=%.0fm bot-density=%d max-bots=%d",
 		*tickHz, *snapshotHz, *cellSize, *aoiRadius, *botDensity, *maxBots)
 
+	// Shared by all websocket handlers so one Start drains every session.
+	drainer := transportws.NewDrainer()
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		// Report unhealthy while draining so load balancers stop routing new clients here
+		if drainer.Draining() {
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
@@ -131,7 +230,72 @@ func main() {
 	log.Printf("sim: persistence manager started")
 
 	auth := join.NewHTTPAuth(*gatewayURL)
-	transportws.RegisterWithStoreAndDevMode(mux, "/ws", auth, eng, st, *devMode)
+	resumeStore := state.ResumeStore(state.NewMemResumeStore())
+	if *resumeFile != "" && *resumeDSN != "" {
+		log.Fatalf("sim: -resume-file and -resume-dsn are mutually exclusive")
+	}
+	if *resumeDSN != "" {
+		pgResume, err := state.NewPostgresResumeStore(*resumeDSN)
+		if err != nil {
+			log.Fatalf("sim: failed to create resume token store: %v", err)
+		}
+		defer pgResume.Close()
+		resumeStore = pgResume
+		log.Printf("sim: using PostgreSQL resume token store")
+	}
+	if *resumeFile != "" {
+		fileResume, err := state.NewFileResumeStore(*resumeFile)
+		if err != nil {
+			log.Fatalf("sim: failed to create resume token store: %v", err)
+		}
+		resumeStore = fileResume
+		log.Printf("sim: using file resume token store at %s", *resumeFile)
+	}
+	// Sessions, resume tokens, command dedup, the per-IP cap and the drain are
+	// shared by every player transport, so a player can move between /ws, /sse
+	// and UDP and one shutdown drains all.
+	sessions := transportws.NewSessionRegistry(transportws.TakeoverKickOld)
+	resume := transportws.NewResumeManagerWithStore(resumeStore, *resumeTTL)
+	commands := transportws.NewCommandLog(0, *resumeTTL)
+	connLimits := transportws.NewConnLimiter(*maxPerIP)
+	transportws.RegisterWithOptions(mux, "/ws", auth, eng, st, transportws.WSOptions{
+		DevMode:              *devMode,
+		OriginPatterns:       originPatterns,
+		Compression:          compression,
+		CompressionThreshold: *compressAt,
+		Sessions:             sessions,
+		Resume:               resume,
+		CommandLog:           commands,
+		ConnLimits:           connLimits,
+		Drain:                drainer,
+	})
+	sharedOpts := session.Options{
+		Sessions:   sessions,
+		Resume:     resume,
+		CommandLog: commands,
+		ConnLimits: connLimits,
+		Drain:      drainer,
+	}
+	// HTTP fallback (SSE or long-poll down, POST up) for clients whose proxies break WebSockets
+	transportsse.Register(mux, "/sse", session.NewServer(auth, eng, st, sharedOpts), transportsse.Options{})
+	var udpListener *udp.Listener
+	if *udpAddr != "" {
+		udpSrv := session.NewServer(auth, eng, st, sharedOpts)
+		l, err := udp.Listen(ctx, *udpAddr, udpSrv.ServePlayer, udp.Options{ConnLimits: udpSrv.ConnLimiter()})
+		if err != nil {
+			log.Fatalf("sim: udp listen: %v", err)
+		}
+		udpListener = l
+		log.Printf("sim: udp listening on %s", l.Addr())
+	}
+	// Read-only spectator endpoint for observer-role tokens
+	transportws.RegisterObserver(mux, "/observe", auth, eng, transportws.WSOptions{
+		DevMode:              *devMode,
+		OriginPatterns:       originPatterns,
+		Compression:          compression,
+		CompressionThreshold: *compressAt,
+		Drain:                drainer,
+	})
 	// Dev endpoints to poke the engine without a client transport yet.
 	mux.HandleFunc("/dev/spawn", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
@@ -249,8 +413,12 @@ func main() {
 				"display_name": template.DisplayName,
 				"weight":       template.Weight,
 				"bulk":         template.Bulk,
+				"max_stack":    template.StackSize(),
 				"damage_type":  template.DamageType,
 				"skill_req":    template.SkillReq,
+				"repair":       template.Repair,
+				"category":     template.Category,
+				"effects":      template.Effects,
 			})
 		}
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
@@ -261,8 +429,15 @@ func main() {
 
 	srv := &http.Server{Addr: ":" + *port, Handler: mux}
 	go func() {
-		log.Printf("sim: http listening on :%s", *port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if *tlsCert != "" {
+			log.Printf("sim: https listening on :%s", *port)
+			err = srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("sim: http listening on :%s", *port)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("sim http: %v", err)
 		}
 	}()
@@ -271,7 +446,25 @@ func main() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	<-sigCh
+	log.Printf("sim: draining sessions...")
+
+	// Stop accepting joins, tell clients when and where to reconnect, and wait for
+	// every session to persist its player and close before tearing anything down.
+	drainer.Start(transportws.DrainNotice{
+		Reason:          "shutdown",
+		ReconnectAfter:  *reconnectAfter,
+		ReconnectJitter: *reconnectJitter,
+		Address:         *reconnectAddr,
+	})
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), *drainTimeout)
+	if err := drainer.Wait(drainCtx); err != nil {
+		log.Printf("sim: drain incomplete after %v: %v", *drainTimeout, err)
+	}
+	drainCancel()
 	log.Printf("sim: shutting down...")
+	if udpListener != nil {
+		udpListener.Close()
+	}
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer shutdownCancel()
@@ -281,6 +474,12 @@ func main() {
 		close(storeStopCh)
 	}
 
+	// Stop periodic world saves and save the final world state
+	close(worldStopCh)
+	if err := saveWorld(shutdownCtx, eng, worldStore); err != nil {
+		log.Printf("sim: world state save error: %v", err)
+	}
+
 	// Stop persistence manager to ensure all pending saves complete
 	eng.StopPersistence()
 	log.Printf("sim: persistence manager stopped")
@@ -299,6 +498,35 @@ func main() {
 	log.Printf("sim: stopped")
 }
 
+// startWorldSaves saves the world state every interval until the returned
+// channel is closed.
+func startWorldSaves(eng *sim.Engine, ws state.WorldStore, interval time.Duration) chan<- struct{} {
+	stopCh := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := saveWorld(context.Background(), eng, ws); err != nil {
+					log.Printf("sim: world state save error: %v", err)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return stopCh
+}
+
+func saveWorld(ctx context.Context, eng *sim.Engine, ws state.WorldStore) error {
+	st, err := eng.SerializeWorldState()
+	if err != nil {
+		return err
+	}
+	return ws.SaveWorld(ctx, st)
+}
+
 func shortID() string {
 	// timestamp-based short id (dev only)
 	return fmt.Sprintf("p%x", time.Now().UnixNano()&0xfffffff)
@@ -315,6 +543,47 @@ func parseFloat(s string, def float64) float64 {
 	return v
 }
 
+// parseOrigins splits the -origins flag into patterns, dropping empty entries.
+func parseOrigins(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parseInventoryGrids parses the -inventory-grids flag into grid sizes by
+// compartment.
+func parseInventoryGrids(s string) (map[sim.CompartmentType]sim.GridSize, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	grids := make(map[sim.CompartmentType]sim.GridSize)
+	for _, entry := range strings.Split(s, ",") {
+		name, dims, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		var size sim.GridSize
+		if ok {
+			_, err := fmt.Sscanf(dims, "%dx%d", &size.W, &size.H)
+			ok = err == nil
+		}
+		if !ok || name == "" || size.W <= 0 || size.H <= 0 {
+			return nil, fmt.Errorf("inventory grid %q must look like compartment=WxH", entry)
+		}
+		grids[sim.CompartmentType(name)] = size
+	}
+	return grids, nil
+}
+
+// validateTLSFlags requires the certificate and key to be given together.
+func validateTLSFlags(certFile, keyFile string) error {
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("-tls-cert and -tls-key must be set together")
+	}
+	return nil
+}
+
 // validateConfig validates configuration parameters to prevent divide-by-zero and other issues
 func validateConfig(cellSize, aoiRadius float64, tickHz, snapshotHz int, hysteresis float64) error {
 	// Sentinel error so callers can detect invalid-config programmatically.
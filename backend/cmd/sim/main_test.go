@@ -4,6 +4,8 @@ import (
 	"math"
 	"strings"
 	"testing"
+
+	"prototype-game/backend/internal/sim"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -149,3 +151,49 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOrigins(t *testing.T) {
+	got := parseOrigins(" game.example.com, ,*.example.com:* ,")
+	want := []string{"game.example.com", "*.example.com:*"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	if parseOrigins("") != nil {
+		t.Fatal("empty flag should leave the default policy in place")
+	}
+}
+
+func TestParseInventoryGrids(t *testing.T) {
+	grids, err := parseInventoryGrids("backpack=10x5, belt=5x2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grids) != 2 || grids[sim.CompartmentBackpack] != (sim.GridSize{W: 10, H: 5}) || grids[sim.CompartmentBelt] != (sim.GridSize{W: 5, H: 2}) {
+		t.Fatalf("got %+v", grids)
+	}
+	if grids, err := parseInventoryGrids(""); err != nil || grids != nil {
+		t.Fatalf("empty flag: got %+v, %v", grids, err)
+	}
+	for _, bad := range []string{"backpack", "backpack=10", "backpack=0x5", "=3x3"} {
+		if _, err := parseInventoryGrids(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestValidateTLSFlags(t *testing.T) {
+	if err := validateTLSFlags("", ""); err != nil {
+		t.Fatalf("TLS disabled: %v", err)
+	}
+	if err := validateTLSFlags("cert.pem", "key.pem"); err != nil {
+		t.Fatalf("TLS enabled: %v", err)
+	}
+	if validateTLSFlags("cert.pem", "") == nil || validateTLSFlags("", "key.pem") == nil {
+		t.Fatal("expected error when only one of cert/key is set")
+	}
+}
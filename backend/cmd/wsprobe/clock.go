@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// maxClockSamples bounds how many time_sync round trips are kept; older samples
+// describe network conditions that no longer apply.
+const maxClockSamples = 16
+
+type clockSample struct {
+	offset float64 // server clock minus client clock, ms
+	rtt    float64 // round trip, ms
+}
+
+// clockEstimator estimates the offset between the client clock and the server's
+// monotonic clock from time_sync round trips, NTP style: each sample assumes the
+// server stamped the probe halfway through the round trip, and the sample with the
+// lowest RTT (least queueing) is trusted most.
+type clockEstimator struct {
+	samples []clockSample
+}
+
+// add records one round trip. sent and recv are client times in ms; server is the
+// server_time_ms from the reply.
+func (ce *clockEstimator) add(sent, recv, server float64) clockSample {
+	s := clockSample{offset: server - (sent+recv)/2, rtt: recv - sent}
+	ce.samples = append(ce.samples, s)
+	if len(ce.samples) > maxClockSamples {
+		ce.samples = ce.samples[len(ce.samples)-maxClockSamples:]
+	}
+	return s
+}
+
+// estimate returns the offset of the best (lowest RTT) sample, its RTT, and the
+// jitter: the standard deviation of offsets across all samples. ok is false
+// until at least one sample exists.
+func (ce *clockEstimator) estimate() (offset, rtt, jitter float64, ok bool) {
+	if len(ce.samples) == 0 {
+		return 0, 0, 0, false
+	}
+	sorted := append([]clockSample(nil), ce.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].rtt < sorted[j].rtt })
+	best := sorted[0]
+
+	mean := 0.0
+	for _, s := range ce.samples {
+		mean += s.offset
+	}
+	mean /= float64(len(ce.samples))
+	variance := 0.0
+	for _, s := range ce.samples {
+		variance += (s.offset - mean) * (s.offset - mean)
+	}
+	jitter = math.Sqrt(variance / float64(len(ce.samples)))
+	return best.offset, best.rtt, jitter, true
+}
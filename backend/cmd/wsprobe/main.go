@@ -25,6 +25,11 @@ type GameClient struct {
 	equipment   map[string]interface{}
 	skills      map[string]interface{}
 	encumbrance map[string]interface{}
+	// clock sync against the server's monotonic time (see time_sync)
+	start         time.Time
+	clock         clockEstimator
+	syncID        int64
+	interpDelayMs float64
 }
 
 func main() {
@@ -35,6 +40,7 @@ func main() {
 		moveZ       = flag.Float64("move_z", 0, "movement intent z (-1..1)")
 		demo        = flag.Bool("demo", false, "run equipment demo")
 		interactive = flag.Bool("interactive", false, "interactive equipment management mode")
+		timeSync    = flag.Int("timesync", 5, "time_sync round trips used to estimate clock offset in basic probe mode")
 	)
 	flag.Parse()
 	if *token == "" {
@@ -44,7 +50,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	client := &GameClient{ctx: ctx, seq: 1}
+	client := &GameClient{ctx: ctx, seq: 1, start: time.Now()}
 
 	if err := client.connect(*url, *token); err != nil {
 		log.Fatal(err)
@@ -56,7 +62,7 @@ func main() {
 	} else if *interactive {
 		client.runInteractiveMode()
 	} else {
-		client.runBasicProbe(*moveX, *moveZ)
+		client.runBasicProbe(*moveX, *moveZ, *timeSync)
 	}
 }
 
@@ -89,6 +95,9 @@ func (c *GameClient) connect(url, token string) error {
 	c.equipment = data["equipment"].(map[string]interface{})
 	c.skills = data["skills"].(map[string]interface{})
 	c.encumbrance = data["encumbrance"].(map[string]interface{})
+	if cfg, ok := data["config"].(map[string]interface{}); ok {
+		c.interpDelayMs, _ = cfg["interp_delay_ms"].(float64)
+	}
 
 	fmt.Printf("Connected as player: %s\n", c.playerID)
 	c.printPlayerStatus()
@@ -162,9 +171,14 @@ func (c *GameClient) printPlayerStatus() {
 	fmt.Println("=====================")
 }
 
-func (c *GameClient) runBasicProbe(moveX, moveZ float64) {
+func (c *GameClient) runBasicProbe(moveX, moveZ float64, timeSync int) {
 	fmt.Println("Running basic probe...")
 
+	if timeSync > 0 {
+		c.syncClock(timeSync)
+		c.printClockEstimate()
+	}
+
 	// Optionally send movement and read one state
 	if moveX != 0 || moveZ != 0 {
 		c.sendMovement(moveX, moveZ)
@@ -200,7 +214,7 @@ func (c *GameClient) runEquipmentDemo() {
 
 func (c *GameClient) runInteractiveMode() {
 	fmt.Println("🎮 Interactive Equipment Management Mode")
-	fmt.Println("Commands: equip <item_id> <slot> | unequip <slot> [compartment] | status | move <x> <z> | quit")
+	fmt.Println("Commands: equip <item_id> <slot> | unequip <slot> [compartment] | status | move <x> <z> | sync | quit")
 	fmt.Println("Example: equip sword_001 main_hand")
 	fmt.Println("Example: unequip main_hand backpack")
 
@@ -259,12 +273,15 @@ func (c *GameClient) runInteractiveMode() {
 				continue
 			}
 			c.sendMovement(x, z)
+		case "sync":
+			c.sendTimeSync()
 		case "help", "h":
 			fmt.Println("Commands:")
 			fmt.Println("  equip <item_id> <slot>    - Equip item to slot")
 			fmt.Println("  unequip <slot> [compartment] - Unequip item to compartment")
 			fmt.Println("  move <x> <z>              - Send movement intent")
 			fmt.Println("  status                    - Show player status")
+			fmt.Println("  sync                      - Probe server clock offset")
 			fmt.Println("  quit                      - Exit")
 		default:
 			fmt.Printf("Unknown command: %s (try 'help')\n", parts[0])
@@ -319,6 +336,52 @@ func (c *GameClient) sendMovement(x, z float64) {
 	}
 }
 
+// clientTimeMs is the client's monotonic clock in ms, echoed back by time_sync.
+func (c *GameClient) clientTimeMs() float64 {
+	return float64(time.Since(c.start)) / float64(time.Millisecond)
+}
+
+func (c *GameClient) sendTimeSync() {
+	c.syncID++
+	probe := map[string]interface{}{
+		"type":        "time_sync",
+		"id":          c.syncID,
+		"client_time": c.clientTimeMs(),
+	}
+	if err := wsjson.Write(c.ctx, c.conn, probe); err != nil {
+		fmt.Printf("Failed to send time_sync: %v\n", err)
+	}
+}
+
+// syncClock performs n time_sync round trips one at a time, processing any
+// other messages that arrive in between.
+func (c *GameClient) syncClock(n int) {
+	for i := 0; i < n; i++ {
+		before := len(c.clock.samples)
+		c.sendTimeSync()
+		deadline := time.Now().Add(2 * time.Second)
+		for len(c.clock.samples) == before && time.Now().Before(deadline) {
+			c.readNextMessage()
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (c *GameClient) printClockEstimate() {
+	offset, rtt, jitter, ok := c.clock.estimate()
+	if !ok {
+		fmt.Println("🕒 Clock offset: no time_sync replies")
+		return
+	}
+	fmt.Printf("🕒 Clock offset: %+.1fms (jitter %.1fms, best RTT %.1fms, %d samples)\n",
+		offset, jitter, rtt, len(c.clock.samples))
+	if c.interpDelayMs > 0 {
+		// remote entities are drawn interpDelayMs behind the estimated server clock
+		fmt.Printf("🕒 Interpolation delay %.0fms (render at server time %.1fms)\n",
+			c.interpDelayMs, c.clientTimeMs()+offset-c.interpDelayMs)
+	}
+}
+
 func (c *GameClient) readNextMessage() {
 	var raw json.RawMessage
 	readCtx, cancel := context.WithTimeout(c.ctx, 2*time.Second)
@@ -388,6 +451,10 @@ func (c *GameClient) processMessage(raw json.RawMessage) {
 		c.handleTelemetry(msg)
 	case "handover":
 		c.handleHandover(msg)
+	case "time_sync":
+		c.handleTimeSync(msg)
+	case "server_shutdown":
+		c.handleServerShutdown(msg)
 	default:
 		fmt.Printf("Unknown message type '%s': %v\n", msgType, msg)
 	}
@@ -471,6 +538,22 @@ func (c *GameClient) handleTelemetry(msg map[string]interface{}) {
 	fmt.Printf("📊 RTT: %.1fms, Tick Rate: %.0fHz\n", rtt, tickRate)
 }
 
+func (c *GameClient) handleTimeSync(msg map[string]interface{}) {
+	data, ok := msg["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sent, ok1 := data["client_time"].(float64)
+	server, ok2 := data["server_time_ms"].(float64)
+	if !ok1 || !ok2 {
+		return
+	}
+	s := c.clock.add(sent, c.clientTimeMs(), server)
+	_, _, jitter, _ := c.clock.estimate()
+	fmt.Printf("🕒 time_sync: offset %+.1fms, RTT %.1fms, jitter %.1fms\n", s.offset, s.rtt, jitter)
+}
+
 func (c *GameClient) handleHandover(msg map[string]interface{}) {
 	data, ok := msg["data"].(map[string]interface{})
 	if !ok {
@@ -481,3 +564,17 @@ func (c *GameClient) handleHandover(msg map[string]interface{}) {
 	to := data["to"]
 	fmt.Printf("🔄 Handover: %v -> %v\n", from, to)
 }
+
+func (c *GameClient) handleServerShutdown(msg map[string]interface{}) {
+	data, ok := msg["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	delay, _ := data["reconnect_after_ms"].(float64)
+	fmt.Printf("🛑 Server shutting down (%v); reconnect in %.0fms", data["reason"], delay)
+	if addr, ok := data["address"].(string); ok {
+		fmt.Printf(" at %s", addr)
+	}
+	fmt.Println()
+}
@@ -19,22 +19,33 @@ func NewHTTPAuth(base string) *HTTPAuth {
 }
 
 func (h *HTTPAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	pid, name, _, ok := h.ValidateRole(ctx, token)
+	return pid, name, ok
+}
+
+// ValidateRole is like Validate but also returns the role granted to the token.
+// Gateways that do not report a role grant RolePlayer.
+func (h *HTTPAuth) ValidateRole(ctx context.Context, token string) (string, string, string, bool) {
 	u := h.BaseURL + "/validate?token=" + url.QueryEscape(token)
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	resp, err := h.Client.Do(req)
 	if err != nil {
-		return "", "", false
+		return "", "", "", false
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", "", false
+		return "", "", "", false
 	}
 	var out struct {
 		PlayerID string `json:"player_id"`
 		Name     string `json:"name"`
+		Role     string `json:"role"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", "", false
+		return "", "", "", false
+	}
+	if out.Role == "" {
+		out.Role = RolePlayer
 	}
-	return out.PlayerID, out.Name, true
+	return out.PlayerID, out.Name, out.Role, true
 }
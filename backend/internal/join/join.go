@@ -17,28 +17,52 @@ type AuthService interface {
 
 // Hello represents the minimal client hello payload.
 type Hello struct {
-	Token   string `json:"token"`
-	Resume  string `json:"resume,omitempty"`
-	LastSeq int    `json:"last_seq,omitempty"`
+	Token  string `json:"token"`
+	Resume string `json:"resume,omitempty"`
+	// Deprecated: the last acked sequence is now kept server-side with the
+	// resume token; the client-reported value is ignored.
+	LastSeq int `json:"last_seq,omitempty"`
+}
+
+// WorldConfig is the subset of engine configuration clients need.
+type WorldConfig struct {
+	TickHz              int     `json:"tick_hz"`
+	SnapshotHz          int     `json:"snapshot_hz"`
+	AOIRadius           float64 `json:"aoi_radius"`
+	CellSize            float64 `json:"cell_size"`
+	HandoverHysteresisM float64 `json:"handover_hysteresis"`
+	// InterpDelayMs is the recommended render delay for remote entities: two
+	// snapshot intervals, so a client always has a pair of states to interpolate.
+	InterpDelayMs float64 `json:"interp_delay_ms"`
+}
+
+func worldConfig(eng *sim.Engine) WorldConfig {
+	cfg := eng.GetConfig()
+	return WorldConfig{
+		TickHz:              cfg.TickHz,
+		SnapshotHz:          cfg.SnapshotHz,
+		AOIRadius:           cfg.AOIRadius,
+		CellSize:            cfg.CellSize,
+		HandoverHysteresisM: cfg.HandoverHysteresisM,
+		InterpDelayMs:       2 * 1000.0 / float64(max(1, cfg.SnapshotHz)),
+	}
 }
 
 // JoinAck is sent on successful join.
 type JoinAck struct {
-	PlayerID string          `json:"player_id"`
-	Pos      spatial.Vec2    `json:"pos"`
-	Cell     spatial.CellKey `json:"cell"`
-	Config   struct {
-		TickHz              int     `json:"tick_hz"`
-		SnapshotHz          int     `json:"snapshot_hz"`
-		AOIRadius           float64 `json:"aoi_radius"`
-		CellSize            float64 `json:"cell_size"`
-		HandoverHysteresisM float64 `json:"handover_hysteresis"`
-	} `json:"config"`
+	PlayerID    string               `json:"player_id"`
+	Pos         spatial.Vec2         `json:"pos"`
+	Cell        spatial.CellKey      `json:"cell"`
+	Config      WorldConfig          `json:"config"`
 	Inventory   *sim.Inventory       `json:"inventory"`
 	Equipment   *sim.Equipment       `json:"equipment"`
 	Skills      map[string]int       `json:"skills"`
+	SkillXP     map[string]float64   `json:"skill_xp"`
 	Encumbrance sim.EncumbranceState `json:"encumbrance"`
 	ResumeToken string               `json:"resume,omitempty"`
+	// Set when the player logged out dead; they respawn after RespawnInMs.
+	Dead        bool  `json:"dead,omitempty"`
+	RespawnInMs int64 `json:"respawn_in_ms,omitempty"`
 }
 
 // ErrorMsg is a structured error for transport.
@@ -50,14 +74,33 @@ type ErrorMsg struct {
 // HandleJoin performs auth, spawns/attaches the player, and builds a JoinAck.
 // It is transport-agnostic so we can test without websockets.
 func HandleJoin(ctx context.Context, auth AuthService, eng *sim.Engine, hello Hello) (JoinAck, *ErrorMsg) {
+	pid, name, em := Authenticate(ctx, auth, hello)
+	if em != nil {
+		return JoinAck{}, em
+	}
+	return JoinAuthenticated(ctx, eng, pid, name), nil
+}
+
+// Authenticate validates the hello token and returns the player's identity.
+// Transports that need to act on the identity before the player is attached
+// (e.g. to enforce one session per player) call this and JoinAuthenticated
+// instead of HandleJoin.
+func Authenticate(ctx context.Context, auth AuthService, hello Hello) (playerID, name string, em *ErrorMsg) {
 	if hello.Token == "" {
-		return JoinAck{}, &ErrorMsg{Code: "bad_request", Message: "missing token"}
+		return "", "", &ErrorMsg{Code: "bad_request", Message: "missing token"}
 	}
-	pid, name, ok := auth.Validate(ctx, hello.Token)
+	pid, name, role, ok := validateRole(ctx, auth, hello.Token)
 	if !ok || pid == "" {
-		return JoinAck{}, &ErrorMsg{Code: "auth", Message: "invalid token"}
+		return "", "", &ErrorMsg{Code: "auth", Message: "invalid token"}
 	}
+	if role == RoleObserver {
+		return "", "", &ErrorMsg{Code: "forbidden", Message: "observer tokens cannot join as a player"}
+	}
+	return pid, name, nil
+}
 
+// JoinAuthenticated spawns/attaches an already authenticated player and builds a JoinAck.
+func JoinAuthenticated(ctx context.Context, eng *sim.Engine, pid, name string) JoinAck {
 	playerMgr := eng.GetPlayerManager()
 	templates := playerMgr.GetAllItemTemplates()
 
@@ -105,26 +148,27 @@ func HandleJoin(ctx context.Context, auth AuthService, eng *sim.Engine, hello He
 		// Note: For new players, AddOrUpdatePlayer creates the record, but full initialization of components is performed by InitializePlayer
 	}
 
-	cfg := eng.GetConfig()
 	ack := JoinAck{
 		PlayerID: snap.ID,
 		Pos:      snap.Pos,
 		Cell:     snap.OwnedCell,
+		Config:   worldConfig(eng),
 	}
-	ack.Config.TickHz = cfg.TickHz
-	ack.Config.SnapshotHz = cfg.SnapshotHz
-	ack.Config.AOIRadius = cfg.AOIRadius
-	ack.Config.CellSize = cfg.CellSize
-	ack.Config.HandoverHysteresisM = cfg.HandoverHysteresisM
 
 	// Include inventory and equipment data in join response
 	ack.Inventory = snap.Inventory
 	ack.Equipment = snap.Equipment
 	ack.Skills = snap.Skills
+	ack.SkillXP = snap.SkillXP
 
 	// Calculate current encumbrance
 	ack.Encumbrance = playerMgr.GetPlayerEncumbrance(&snap)
 
+	if snap.Dead {
+		ack.Dead = true
+		ack.RespawnInMs = max(0, time.Until(snap.RespawnAt).Milliseconds())
+	}
+
 	// Persist updated state immediately (best-effort) for login tracking
 	if playerStore != nil {
 		if persistedState != nil {
@@ -140,7 +184,7 @@ func HandleJoin(ctx context.Context, auth AuthService, eng *sim.Engine, hello He
 		}
 	}
 
-	return ack, nil
+	return ack
 }
 
 // Pluggable store for player persistence; set by the service (e.g., sim main).
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
 	"prototype-game/backend/internal/testutil"
 )
 
@@ -49,6 +50,34 @@ func TestHandleJoin_Success(t *testing.T) {
 	}
 }
 
+func TestAuthenticate_DoesNotSpawn(t *testing.T) {
+	eng := newTestEngine()
+	auth := fakeAuth{"tok123": {"p1", "Alice"}}
+
+	pid, name, errMsg := Authenticate(context.Background(), auth, Hello{Token: "tok123"})
+	if errMsg != nil {
+		t.Fatalf("unexpected error: %+v", errMsg)
+	}
+	if pid != "p1" || name != "Alice" {
+		t.Fatalf("unexpected identity: %q %q", pid, name)
+	}
+	if _, ok := eng.GetPlayer("p1"); ok {
+		t.Fatal("Authenticate must not attach the player to the engine")
+	}
+
+	ack := JoinAuthenticated(context.Background(), eng, pid, name)
+	if ack.PlayerID != "p1" {
+		t.Fatalf("bad ack: %#v", ack)
+	}
+	if _, ok := eng.GetPlayer("p1"); !ok {
+		t.Fatal("expected player to be attached after JoinAuthenticated")
+	}
+
+	if _, _, errMsg := Authenticate(context.Background(), auth, Hello{}); errMsg == nil || errMsg.Code != "bad_request" {
+		t.Fatalf("expected bad_request for missing token, got %#v", errMsg)
+	}
+}
+
 func TestHandleJoin_AuthFailure(t *testing.T) {
 	eng := newTestEngine()
 	auth := fakeAuth{}
@@ -117,3 +146,60 @@ func TestHTTPAuth_ClientTimeout(t *testing.T) {
 		t.Fatalf("HTTPAuth validation took too long (%v), client timeout not working", elapsed)
 	}
 }
+
+// roleAuth grants each token a role: token -> [playerID, name, role].
+type roleAuth map[string][3]string
+
+func (r roleAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	pid, name, _, ok := r.ValidateRole(ctx, token)
+	return pid, name, ok
+}
+
+func (r roleAuth) ValidateRole(ctx context.Context, token string) (string, string, string, bool) {
+	if v, ok := r[token]; ok {
+		return v[0], v[1], v[2], true
+	}
+	return "", "", "", false
+}
+
+func TestHandleObserve_RequiresObserverRole(t *testing.T) {
+	eng := newTestEngine()
+	auth := roleAuth{"ptok": {"p1", "Alice", RolePlayer}, "otok": {"qa1", "QA", RoleObserver}}
+
+	if _, em := HandleObserve(context.Background(), auth, eng, ObserveHello{Token: "ptok"}); em == nil || em.Code != "forbidden" {
+		t.Fatalf("expected forbidden for player token, got %+v", em)
+	}
+	// Plain AuthService implementations only grant the player role.
+	if _, em := HandleObserve(context.Background(), fakeAuth{"tok": {"p1", "Alice"}}, eng, ObserveHello{Token: "tok"}); em == nil || em.Code != "forbidden" {
+		t.Fatalf("expected forbidden without role support, got %+v", em)
+	}
+	// Observer tokens cannot spawn a player.
+	if _, em := HandleJoin(context.Background(), auth, eng, Hello{Token: "otok"}); em == nil || em.Code != "forbidden" {
+		t.Fatalf("expected observer token to be refused for join, got %+v", em)
+	}
+}
+
+func TestHandleObserve_DoesNotSpawn(t *testing.T) {
+	eng := newTestEngine()
+	auth := roleAuth{"otok": {"qa1", "QA", RoleObserver}}
+
+	ack, em := HandleObserve(context.Background(), auth, eng, ObserveHello{Token: "otok", Pos: &spatial.Vec2{X: 25, Z: -5}})
+	if em != nil {
+		t.Fatalf("unexpected error: %+v", em)
+	}
+	if ack.ObserverID != "qa1" || ack.Cell.Cx != 2 || ack.Cell.Cz != -1 {
+		t.Fatalf("bad observe ack: %#v", ack)
+	}
+	if _, ok := eng.GetPlayer("qa1"); ok {
+		t.Fatal("observer must not spawn a player")
+	}
+	if _, em := HandleObserve(context.Background(), auth, eng, ObserveHello{Token: "otok", Follow: "nobody"}); em == nil || em.Code != "target_not_found" {
+		t.Fatalf("expected target_not_found, got %+v", em)
+	}
+
+	eng.AddOrUpdatePlayer("p1", "Alice", spatial.Vec2{X: 3, Z: 4}, spatial.Vec2{})
+	ack, em = HandleObserve(context.Background(), auth, eng, ObserveHello{Token: "otok", Follow: "p1"})
+	if em != nil || ack.Follow != "p1" || ack.Pos.X != 3 {
+		t.Fatalf("expected to follow p1, got ack=%#v err=%+v", ack, em)
+	}
+}
@@ -0,0 +1,91 @@
+package join
+
+import (
+	"context"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+// Roles a token can be granted.
+const (
+	RolePlayer   = "player"
+	RoleObserver = "observer"
+)
+
+// RoleAuthService is implemented by auth services that also report the role
+// granted to a token. Services that only implement AuthService grant RolePlayer.
+type RoleAuthService interface {
+	AuthService
+	ValidateRole(ctx context.Context, token string) (playerID, name, role string, ok bool)
+}
+
+func validateRole(ctx context.Context, auth AuthService, token string) (playerID, name, role string, ok bool) {
+	if ra, isRole := auth.(RoleAuthService); isRole {
+		return ra.ValidateRole(ctx, token)
+	}
+	pid, name, ok := auth.Validate(ctx, token)
+	return pid, name, RolePlayer, ok
+}
+
+// ObserveHello is the hello payload for a read-only observer session. The
+// observer either follows a player (Follow) or watches a fixed position (Pos).
+type ObserveHello struct {
+	Token  string        `json:"token"`
+	Follow string        `json:"follow,omitempty"`
+	Pos    *spatial.Vec2 `json:"pos,omitempty"`
+}
+
+// ObserveAck is sent when an observer session is accepted.
+type ObserveAck struct {
+	ObserverID string          `json:"observer_id"`
+	Follow     string          `json:"follow,omitempty"`
+	Pos        spatial.Vec2    `json:"pos"`
+	Cell       spatial.CellKey `json:"cell"`
+	Config     WorldConfig     `json:"config"`
+}
+
+// HandleObserve authenticates an observer and resolves its initial target. No
+// player is spawned and nothing is persisted, so observers never affect the
+// simulation (bot density, AOI of real players, or stored state).
+func HandleObserve(ctx context.Context, auth AuthService, eng *sim.Engine, hello ObserveHello) (ObserveAck, *ErrorMsg) {
+	if hello.Token == "" {
+		return ObserveAck{}, &ErrorMsg{Code: "bad_request", Message: "missing token"}
+	}
+	id, _, role, ok := validateRole(ctx, auth, hello.Token)
+	if !ok || id == "" {
+		return ObserveAck{}, &ErrorMsg{Code: "auth", Message: "invalid token"}
+	}
+	if role != RoleObserver {
+		return ObserveAck{}, &ErrorMsg{Code: "forbidden", Message: "observer role required"}
+	}
+	var at spatial.Vec2
+	if hello.Pos != nil {
+		at = *hello.Pos
+	}
+	pos, cell, ok := ObserveTarget(eng, hello.Follow, at)
+	if !ok {
+		return ObserveAck{}, &ErrorMsg{Code: "target_not_found", Message: "followed player not found"}
+	}
+	return ObserveAck{
+		ObserverID: id,
+		Follow:     hello.Follow,
+		Pos:        pos,
+		Cell:       cell,
+		Config:     worldConfig(eng),
+	}, nil
+}
+
+// ObserveTarget resolves where an observer is looking: the followed player's
+// position and owned cell, or the fixed position and the cell containing it.
+func ObserveTarget(eng *sim.Engine, follow string, pos spatial.Vec2) (spatial.Vec2, spatial.CellKey, bool) {
+	if follow != "" {
+		p, ok := eng.GetPlayer(follow)
+		if !ok {
+			return spatial.Vec2{}, spatial.CellKey{}, false
+		}
+		return p.Pos, p.OwnedCell, true
+	}
+	cx, cz := spatial.WorldToCell(pos.X, pos.Z, eng.GetConfig().CellSize)
+	return pos, spatial.CellKey{Cx: cx, Cz: cz}, true
+}
@@ -20,7 +20,19 @@ var (
 	wsConnectedGauge       prometheus.Gauge
 	handoversTotalCounter  prometheus.Counter
 	equipOperationsCounter *prometheus.CounterVec
+	inventoryOpsCounter    *prometheus.CounterVec
 	equipCooldownCounter   prometheus.Counter
+	sendQueueDepthHist     prometheus.Histogram
+	sendQueueCoalesced     *prometheus.CounterVec
+	slowConsumerCounter    prometheus.Counter
+	rateLimitedCounter     *prometheus.CounterVec
+	connRejectedCounter    *prometheus.CounterVec
+	sessionTakeoverCounter *prometheus.CounterVec
+	duplicateCmdCounter    *prometheus.CounterVec
+	observersGauge         prometheus.Gauge
+	deferredEntityCounter  prometheus.Counter
+	snapshotWireBytesHist  *prometheus.HistogramVec
+	attacksCounter         *prometheus.CounterVec
 
 	initOnce sync.Once
 )
@@ -79,12 +91,107 @@ func ensureInit() {
 			[]string{"operation", "result"}, // operation: equip/unequip, result: success/failed
 		)
 
+		inventoryOpsCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "sim",
+				Name:      "inventory_operations_total",
+				Help:      "Total inventory operations processed.",
+			},
+			[]string{"operation", "result"}, // operation: inventory command, result: success/failed
+		)
+
 		equipCooldownCounter = prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: "sim",
 			Name:      "equip_cooldown_blocks_total",
 			Help:      "Total equipment operations blocked by cooldown.",
 		})
 
+		sendQueueDepthHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "ws",
+			Name:      "send_queue_depth",
+			Help:      "Pending outbound messages per session, sampled on enqueue.",
+			Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64, 128},
+		})
+
+		sendQueueCoalesced = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ws",
+				Name:      "send_queue_coalesced_total",
+				Help:      "Outbound messages superseded by a newer message of the same type before being written.",
+			},
+			[]string{"type"},
+		)
+
+		slowConsumerCounter = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "ws",
+			Name:      "slow_consumer_disconnects_total",
+			Help:      "Total WebSocket clients disconnected for falling behind on outbound messages.",
+		})
+
+		rateLimitedCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ws",
+				Name:      "rate_limited_total",
+				Help:      "Client messages rejected by per-session rate limits.",
+			},
+			[]string{"class", "action"}, // class: movement/inventory/chat/other, action: drop/warn/disconnect
+		)
+
+		connRejectedCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ws",
+				Name:      "connections_rejected_total",
+				Help:      "WebSocket connection attempts rejected before upgrade.",
+			},
+			[]string{"reason"},
+		)
+
+		sessionTakeoverCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ws",
+				Name:      "session_takeovers_total",
+				Help:      "Duplicate logins for a player with an active session.",
+			},
+			[]string{"result"}, // result: replaced/rejected
+		)
+
+		duplicateCmdCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "ws",
+				Name:      "duplicate_commands_total",
+				Help:      "Mutating commands not executed because their seq was already seen.",
+			},
+			[]string{"result"}, // result: cached/stale
+		)
+
+		observersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "ws",
+			Name:      "observers_connected",
+			Help:      "Number of connected read-only observer sessions.",
+		})
+
+		deferredEntityCounter = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "ws",
+			Name:      "snapshot_entities_deferred_total",
+			Help:      "Entity updates left out of a state message to stay within the snapshot byte budget.",
+		})
+
+		snapshotWireBytesHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "ws",
+			Name:      "snapshot_wire_bytes",
+			Help:      "Bytes a WS state message took on the wire, by negotiated compression; compare with ws_snapshot_bytes.",
+			Buckets:   []float64{256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536},
+		}, []string{"compression"})
+
+		attacksCounter = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "sim",
+				Name:      "attacks_total",
+				Help:      "Attack commands by outcome: hit, miss or the reason they were rejected.",
+			},
+			[]string{"result"},
+		)
+
 		registry.MustRegister(
 			tickTimeMsHist,
 			snapshotBytesHist,
@@ -93,7 +200,19 @@ func ensureInit() {
 			wsConnectedGauge,
 			handoversTotalCounter,
 			equipOperationsCounter,
+			inventoryOpsCounter,
 			equipCooldownCounter,
+			sendQueueDepthHist,
+			sendQueueCoalesced,
+			slowConsumerCounter,
+			rateLimitedCounter,
+			connRejectedCounter,
+			sessionTakeoverCounter,
+			duplicateCmdCounter,
+			observersGauge,
+			deferredEntityCounter,
+			snapshotWireBytesHist,
+			attacksCounter,
 		)
 	})
 }
@@ -144,6 +263,18 @@ func DecWSConnected() {
 	wsConnectedGauge.Dec()
 }
 
+// IncObserversConnected increments the observer session gauge.
+func IncObserversConnected() {
+	ensureInit()
+	observersGauge.Inc()
+}
+
+// DecObserversConnected decrements the observer session gauge.
+func DecObserversConnected() {
+	ensureInit()
+	observersGauge.Dec()
+}
+
 // IncHandovers increments handover counter.
 func IncHandovers() {
 	ensureInit()
@@ -160,8 +291,81 @@ func ObserveEquipOperation(operation string, success bool) {
 	equipOperationsCounter.WithLabelValues(operation, result).Inc()
 }
 
+// ObserveInventoryOperation records an inventory command (repair, ...) and its
+// result.
+func ObserveInventoryOperation(operation string, success bool) {
+	ensureInit()
+	result := "success"
+	if !success {
+		result = "failed"
+	}
+	inventoryOpsCounter.WithLabelValues(operation, result).Inc()
+}
+
 // IncEquipCooldownBlocks increments the counter for operations blocked by cooldown.
 func IncEquipCooldownBlocks() {
 	ensureInit()
 	equipCooldownCounter.Inc()
 }
+
+// ObserveSendQueueDepth records the depth of a session's outbound queue.
+func ObserveSendQueueDepth(n int) {
+	ensureInit()
+	sendQueueDepthHist.Observe(float64(n))
+}
+
+// IncSendQueueCoalesced counts an outbound message superseded before being written.
+func IncSendQueueCoalesced(msgType string) {
+	ensureInit()
+	sendQueueCoalesced.WithLabelValues(msgType).Inc()
+}
+
+// IncSlowConsumerDisconnects counts a client disconnected for falling behind.
+func IncSlowConsumerDisconnects() {
+	ensureInit()
+	slowConsumerCounter.Inc()
+}
+
+// IncRateLimited counts a client message rejected by a rate limit and the action taken.
+func IncRateLimited(class, action string) {
+	ensureInit()
+	rateLimitedCounter.WithLabelValues(class, action).Inc()
+}
+
+// IncConnectionsRejected counts a connection attempt rejected before upgrade.
+func IncConnectionsRejected(reason string) {
+	ensureInit()
+	connRejectedCounter.WithLabelValues(reason).Inc()
+}
+
+// IncSessionTakeovers counts a duplicate login and how it was resolved.
+func IncSessionTakeovers(result string) {
+	ensureInit()
+	sessionTakeoverCounter.WithLabelValues(result).Inc()
+}
+
+// IncDuplicateCommands counts a command deduplicated by seq: answered from the
+// cache or refused as older than the window.
+func IncDuplicateCommands(result string) {
+	ensureInit()
+	duplicateCmdCounter.WithLabelValues(result).Inc()
+}
+
+// AddDeferredEntities counts entity updates deferred to a later snapshot by the byte budget.
+func AddDeferredEntities(n int) {
+	ensureInit()
+	deferredEntityCounter.Add(float64(n))
+}
+
+// ObserveSnapshotWireBytes records the on-the-wire size of a WS state message.
+// compression is "deflate" when permessage-deflate was negotiated, else "none".
+func ObserveSnapshotWireBytes(n int, compression string) {
+	ensureInit()
+	snapshotWireBytesHist.WithLabelValues(compression).Observe(float64(n))
+}
+
+// IncAttacks counts an attack command by result ("hit", "miss" or a rejection code).
+func IncAttacks(result string) {
+	ensureInit()
+	attacksCounter.WithLabelValues(result).Inc()
+}
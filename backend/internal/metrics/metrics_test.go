@@ -202,6 +202,26 @@ func TestObserveEquipOperation(t *testing.T) {
 	}
 }
 
+// TestObserveInventoryOperation verifies inventory operations are counted apart from equipment
+func TestObserveInventoryOperation(t *testing.T) {
+	ObserveInventoryOperation("repair", true)
+	ObserveInventoryOperation("repair", false)
+
+	metrics := scrapeMetrics(t)
+
+	successPattern := regexp.MustCompile(`sim_inventory_operations_total{[^}]*operation="repair"[^}]*result="success"[^}]*}\s+([1-9]\d*|1)`)
+	if !successPattern.MatchString(metrics) {
+		t.Fatal("Expected sim_inventory_operations_total with operation=repair,result=success")
+	}
+	failPattern := regexp.MustCompile(`sim_inventory_operations_total{[^}]*operation="repair"[^}]*result="failed"[^}]*}\s+([1-9]\d*|1)`)
+	if !failPattern.MatchString(metrics) {
+		t.Fatal("Expected sim_inventory_operations_total with operation=repair,result=failed")
+	}
+	if strings.Contains(metrics, `sim_equip_operations_total{operation="repair"`) {
+		t.Fatal("inventory operations must not be counted as equipment operations")
+	}
+}
+
 // TestIncEquipCooldownBlocks verifies equip cooldown counter is updated
 func TestIncEquipCooldownBlocks(t *testing.T) {
 	// Increment cooldown blocks counter
@@ -223,6 +243,120 @@ func TestIncEquipCooldownBlocks(t *testing.T) {
 	}
 }
 
+// TestSendQueueMetrics verifies outbound queue depth, coalescing and slow consumer metrics are updated
+func TestSendQueueMetrics(t *testing.T) {
+	ObserveSendQueueDepth(3)
+	IncSendQueueCoalesced("state")
+	IncSlowConsumerDisconnects()
+
+	metrics := scrapeMetrics(t)
+
+	bucketPattern := regexp.MustCompile(`ws_send_queue_depth_bucket{[^}]*le="[^"]*"}\s+([1-9]\d*|1)`)
+	if !bucketPattern.MatchString(metrics) {
+		t.Fatal("Expected at least one sample in ws_send_queue_depth histogram buckets")
+	}
+
+	coalescedPattern := regexp.MustCompile(`ws_send_queue_coalesced_total{[^}]*type="state"[^}]*}\s+([1-9]\d*|1)`)
+	if !coalescedPattern.MatchString(metrics) {
+		t.Fatal("Expected ws_send_queue_coalesced_total with type=state")
+	}
+
+	re := regexp.MustCompile(`(?m)^ws_slow_consumer_disconnects_total\s+([1-9]\d*|1)`)
+	if !re.MatchString(metrics) {
+		t.Fatal("Expected ws_slow_consumer_disconnects_total > 0")
+	}
+}
+
+// TestRateLimitMetrics verifies rate limit and connection rejection counters are updated
+func TestRateLimitMetrics(t *testing.T) {
+	IncRateLimited("movement", "drop")
+	IncConnectionsRejected("ip_cap")
+
+	metrics := scrapeMetrics(t)
+
+	limitedPattern := regexp.MustCompile(`ws_rate_limited_total{[^}]*action="drop"[^}]*class="movement"[^}]*}\s+([1-9]\d*|1)`)
+	if !limitedPattern.MatchString(metrics) {
+		t.Fatal("Expected ws_rate_limited_total with class=movement,action=drop")
+	}
+
+	rejectedPattern := regexp.MustCompile(`ws_connections_rejected_total{[^}]*reason="ip_cap"[^}]*}\s+([1-9]\d*|1)`)
+	if !rejectedPattern.MatchString(metrics) {
+		t.Fatal("Expected ws_connections_rejected_total with reason=ip_cap")
+	}
+}
+
+// TestIncSessionTakeovers verifies duplicate login counter is updated
+func TestIncSessionTakeovers(t *testing.T) {
+	IncSessionTakeovers("replaced")
+
+	metrics := scrapeMetrics(t)
+
+	pattern := regexp.MustCompile(`ws_session_takeovers_total{[^}]*result="replaced"[^}]*}\s+([1-9]\d*|1)`)
+	if !pattern.MatchString(metrics) {
+		t.Fatal("Expected ws_session_takeovers_total with result=replaced")
+	}
+}
+
+func TestIncDuplicateCommands(t *testing.T) {
+	IncDuplicateCommands("cached")
+
+	metrics := scrapeMetrics(t)
+
+	pattern := regexp.MustCompile(`ws_duplicate_commands_total{[^}]*result="cached"[^}]*}\s+([1-9]\d*|1)`)
+	if !pattern.MatchString(metrics) {
+		t.Fatal("Expected ws_duplicate_commands_total with result=cached")
+	}
+}
+
+func TestObserversConnectedGauge(t *testing.T) {
+	IncObserversConnected()
+	IncObserversConnected()
+	DecObserversConnected()
+
+	metrics := scrapeMetrics(t)
+
+	pattern := regexp.MustCompile(`ws_observers_connected\s+1`)
+	if !pattern.MatchString(metrics) {
+		t.Fatal("Expected ws_observers_connected to be 1")
+	}
+}
+
+func TestAddDeferredEntities(t *testing.T) {
+	AddDeferredEntities(3)
+
+	metrics := scrapeMetrics(t)
+
+	pattern := regexp.MustCompile(`ws_snapshot_entities_deferred_total\s+([3-9]|\d{2,})`)
+	if !pattern.MatchString(metrics) {
+		t.Fatal("Expected ws_snapshot_entities_deferred_total to be at least 3")
+	}
+}
+
+func TestObserveSnapshotWireBytes(t *testing.T) {
+	ObserveSnapshotWireBytes(300, "deflate")
+	ObserveSnapshotWireBytes(1200, "none")
+
+	metrics := scrapeMetrics(t)
+
+	for _, label := range []string{"deflate", "none"} {
+		pattern := regexp.MustCompile(`ws_snapshot_wire_bytes_count\{compression="` + label + `"\}\s+[1-9]`)
+		if !pattern.MatchString(metrics) {
+			t.Fatalf("Expected ws_snapshot_wire_bytes observations for compression=%s", label)
+		}
+	}
+}
+
+func TestIncAttacks(t *testing.T) {
+	IncAttacks("hit")
+
+	metrics := scrapeMetrics(t)
+
+	pattern := regexp.MustCompile(`sim_attacks_total{[^}]*result="hit"[^}]*}\s+([1-9]\d*|1)`)
+	if !pattern.MatchString(metrics) {
+		t.Fatal("Expected sim_attacks_total with result=hit")
+	}
+}
+
 // TestMetricsEndpointFormat verifies the metrics endpoint returns valid Prometheus format
 func TestMetricsEndpointFormat(t *testing.T) {
 	// Generate some sample data first
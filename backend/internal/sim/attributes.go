@@ -0,0 +1,170 @@
+package sim
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"time"
+)
+
+var ErrExhausted = errors.New("not enough stamina")
+
+// Base attributes of an unequipped, unskilled player and of every bot.
+const (
+	baseMaxHealth    = 100.0
+	baseMaxStamina   = 100.0
+	baseHealthRegen  = 1.0  // health per second
+	baseStaminaRegen = 10.0 // stamina per second
+	botMaxHealth     = 50.0
+)
+
+// StatBonus is what an equipped item, or one level of a skill, adds to a
+// player's derived stats.
+type StatBonus struct {
+	MaxHealth    float64 `json:"max_health,omitempty"`
+	MaxStamina   float64 `json:"max_stamina,omitempty"`
+	HealthRegen  float64 `json:"health_regen,omitempty"`
+	StaminaRegen float64 `json:"stamina_regen,omitempty"`
+}
+
+// skillStatBonuses is the bonus per level of each skill.
+var skillStatBonuses = map[string]StatBonus{
+	"defense": {MaxHealth: 2, HealthRegen: 0.05},
+	"melee":   {MaxStamina: 1, StaminaRegen: 0.1},
+}
+
+// Stats are a player's stats derived from base values, equipped items and
+// skills. They are recomputed whenever equipment or skills change and never
+// persisted. A Stats value is replaced rather than modified, so copies may
+// share Armor.
+type Stats struct {
+	MaxHealth    float64                `json:"max_health"`
+	MaxStamina   float64                `json:"max_stamina"`
+	HealthRegen  float64                `json:"health_regen"`
+	StaminaRegen float64                `json:"stamina_regen"`
+	Armor        map[DamageType]float64 `json:"armor"` // absorbed fraction per damage type, capped at maxArmor
+}
+
+func (s Stats) equal(o Stats) bool {
+	return s.MaxHealth == o.MaxHealth && s.MaxStamina == o.MaxStamina &&
+		s.HealthRegen == o.HealthRegen && s.StaminaRegen == o.StaminaRegen &&
+		maps.Equal(s.Armor, o.Armor)
+}
+
+func (s *Stats) add(b StatBonus, times float64) {
+	s.MaxHealth += b.MaxHealth * times
+	s.MaxStamina += b.MaxStamina * times
+	s.HealthRegen += b.HealthRegen * times
+	s.StaminaRegen += b.StaminaRegen * times
+}
+
+// statsBasis records the equipment, skills and buffs versions Stats were
+// derived from.
+type statsBasis struct {
+	equipment, skills, buffs int64
+	valid                    bool
+}
+
+// DeriveStats computes a player's stats from their equipment, skills and buffs.
+func (pm *PlayerManager) DeriveStats(player *Player) Stats {
+	s := Stats{
+		MaxHealth:    baseMaxHealth,
+		MaxStamina:   baseMaxStamina,
+		HealthRegen:  baseHealthRegen,
+		StaminaRegen: baseStaminaRegen,
+		Armor:        make(map[DamageType]float64),
+	}
+	for skill, level := range player.Skills {
+		s.add(skillStatBonuses[skill], float64(level))
+	}
+	if player.Equipment != nil {
+		for _, item := range player.Equipment.Slots {
+			if item == nil || item.Instance.Broken() {
+				continue
+			}
+			tmpl, ok := pm.GetItemTemplate(item.Instance.TemplateID)
+			if !ok {
+				continue
+			}
+			if tmpl.Stats != nil {
+				s.add(*tmpl.Stats, 1)
+			}
+			for dt, a := range tmpl.Armor {
+				s.Armor[dt] = math.Min(s.Armor[dt]+a, maxArmor)
+			}
+		}
+	}
+	for _, b := range player.Buffs {
+		s.add(b.Bonus, 1)
+	}
+	return s
+}
+
+// RefreshStats re-derives the player's stats if equipment or skills changed
+// since they were last derived, clamping health and stamina to the new
+// maximums. It reports whether the stats changed; skill XP gains that do not
+// level anything up leave them as they were.
+func (pm *PlayerManager) RefreshStats(player *Player) bool {
+	basis := statsBasis{equipment: player.EquipmentVersion, skills: player.SkillsVersion, buffs: player.buffsVersion, valid: true}
+	if player.statsBasis == basis {
+		return false
+	}
+	wasValid := player.statsBasis.valid
+	player.statsBasis = basis
+	stats := pm.DeriveStats(player)
+	if wasValid && stats.equal(player.Stats) {
+		return false
+	}
+	player.Stats = stats
+	player.MaxHealth = player.Stats.MaxHealth
+	player.Health = math.Min(player.Health, player.MaxHealth)
+	player.Stamina = math.Min(player.Stamina, player.Stats.MaxStamina)
+	player.AttributesVersion++
+	return true
+}
+
+// regenerate advances a player's health and stamina by dt. AttributesVersion
+// only changes when a whole point is gained, so regeneration does not force
+// an attributes delta into every state message.
+func (p *Player) regenerate(dt time.Duration) {
+	health := math.Min(p.Health+p.Stats.HealthRegen*dt.Seconds(), p.MaxHealth)
+	stamina := math.Min(p.Stamina+p.Stats.StaminaRegen*dt.Seconds(), p.Stats.MaxStamina)
+	if math.Floor(health) != math.Floor(p.Health) || math.Floor(stamina) != math.Floor(p.Stamina) {
+		p.AttributesVersion++
+	}
+	p.Health, p.Stamina = health, stamina
+}
+
+// regenerateBot advances a bot's health by dt at the base rate.
+func regenerateBot(ent *Entity, dt time.Duration) {
+	ent.Health = math.Min(ent.Health+baseHealthRegen*dt.Seconds(), ent.MaxHealth)
+}
+
+// applyDamageLocked takes damage off an entity's health, never below zero,
+// and kills it when its health runs out. It reports whether the entity died.
+// e.mu must be held by caller.
+func (e *Engine) applyDamageLocked(ent *Entity, damage float64, now time.Time) bool {
+	if damage <= 0 || ent.Health <= 0 {
+		return false
+	}
+	ent.Health = math.Max(0, ent.Health-damage)
+	if p, ok := e.players[ent.ID]; ok {
+		p.AttributesVersion++
+		e.playerMgr.GainSkillXP(p, "defense", damage*xpDamageTaken)
+	}
+	if ent.Health > 0 {
+		return false
+	}
+	e.killLocked(ent, now)
+	return true
+}
+
+// spendStamina deducts cost from the player's stamina, never below zero.
+// Callers check for ErrExhausted before committing to the action.
+func (p *Player) spendStamina(cost float64) {
+	if cost <= 0 {
+		return
+	}
+	p.Stamina = math.Max(0, p.Stamina-cost)
+	p.AttributesVersion++
+}
@@ -0,0 +1,138 @@
+package sim
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func TestNewPlayerStartsWithFullPools(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	p, _ := e.GetPlayer("p1")
+	if p.Health != baseMaxHealth || p.MaxHealth != baseMaxHealth || p.Stamina != baseMaxStamina {
+		t.Fatalf("expected full base pools, got health=%v/%v stamina=%v", p.Health, p.MaxHealth, p.Stamina)
+	}
+}
+
+func TestDeriveStatsFromEquipmentAndSkills(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	equip(t, e, "p1", "armor_leather", SlotChest)
+	equip(t, e, "p1", "shield_wood", SlotOffHand)
+	e.Step(time.Millisecond)
+
+	p, _ := e.GetPlayer("p1")
+	// equip gives melee 10 and defense 5
+	wantMaxHealth := baseMaxHealth + 15 + 5 + 5*2
+	if p.Stats.MaxHealth != wantMaxHealth || p.MaxHealth != wantMaxHealth {
+		t.Fatalf("max health = %v (entity %v), want %v", p.Stats.MaxHealth, p.MaxHealth, wantMaxHealth)
+	}
+	if p.Stats.MaxStamina != baseMaxStamina+10 {
+		t.Fatalf("max stamina = %v, want %v", p.Stats.MaxStamina, baseMaxStamina+10)
+	}
+	if got := p.Stats.Armor[DamageSlash]; math.Abs(got-0.4) > 1e-9 {
+		t.Fatalf("slash armor = %v, want 0.4", got)
+	}
+
+	// Taking the armor off lowers the maximum and clamps health to it
+	if err := e.UnequipItem("p1", SlotChest, CompartmentBackpack, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	e.Step(time.Millisecond)
+	p, _ = e.GetPlayer("p1")
+	if p.MaxHealth != wantMaxHealth-15 || p.Health > p.MaxHealth {
+		t.Fatalf("after unequip health=%v/%v, want max %v", p.Health, p.MaxHealth, wantMaxHealth-15)
+	}
+	if p.Stats.Armor[DamageSlash] != 0.15 {
+		t.Fatalf("slash armor after unequip = %v, want 0.15", p.Stats.Armor[DamageSlash])
+	}
+}
+
+func TestRegenerationBumpsVersionPerWholePoint(t *testing.T) {
+	p := &Player{Entity: Entity{Health: 50, MaxHealth: 100}, Stamina: 100}
+	p.Stats = Stats{MaxHealth: 100, MaxStamina: 100, HealthRegen: 1, StaminaRegen: 10}
+
+	p.regenerate(500 * time.Millisecond)
+	if p.Health != 50.5 || p.AttributesVersion != 0 {
+		t.Fatalf("half a point: health=%v version=%d", p.Health, p.AttributesVersion)
+	}
+	p.regenerate(500 * time.Millisecond)
+	if p.Health != 51 || p.AttributesVersion != 1 {
+		t.Fatalf("whole point: health=%v version=%d", p.Health, p.AttributesVersion)
+	}
+	p.regenerate(time.Minute)
+	if p.Health != 100 || p.Stamina != 100 {
+		t.Fatalf("regeneration must stop at the maximum, got health=%v stamina=%v", p.Health, p.Stamina)
+	}
+	v := p.AttributesVersion
+	p.regenerate(time.Second)
+	if p.AttributesVersion != v {
+		t.Fatal("full pools must not bump the version")
+	}
+}
+
+func TestAttackDamagesHealthAndSpendsStamina(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 2})
+	before, _ := e.GetPlayer("p2")
+
+	now := time.Now()
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2"}, now)
+	if err != nil {
+		t.Fatalf("attack: %v", err)
+	}
+	after, _ := e.GetPlayer("p2")
+	if after.Health != before.Health-unarmedProfile.Damage || ev.TargetHealth != after.Health {
+		t.Fatalf("health %v -> %v (event %v), want %v damage", before.Health, after.Health, ev.TargetHealth, unarmedProfile.Damage)
+	}
+	if after.AttributesVersion == before.AttributesVersion {
+		t.Fatal("taking damage must bump the target's AttributesVersion")
+	}
+	attacker, _ := e.GetPlayer("p1")
+	if attacker.Stamina != baseMaxStamina-unarmedProfile.Stamina {
+		t.Fatalf("attacker stamina = %v, want %v", attacker.Stamina, baseMaxStamina-unarmedProfile.Stamina)
+	}
+
+	// Out of stamina: the attack is refused and nothing changes
+	e.mu.Lock()
+	e.players["p1"].Stamina = unarmedProfile.Stamina / 2
+	e.mu.Unlock()
+	if _, err := e.Attack("p1", AttackRequest{TargetID: "p2"}, now.Add(time.Minute)); err != ErrExhausted {
+		t.Fatalf("expected ErrExhausted, got %v", err)
+	}
+	if p2, _ := e.GetPlayer("p2"); p2.Health != after.Health {
+		t.Fatal("a refused attack must not deal damage")
+	}
+}
+
+func TestAttributesPersistAcrossSerialization(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	equip(t, e, "p1", "armor_leather", SlotChest)
+	e.mu.Lock()
+	p := e.players["p1"]
+	p.Health, p.Stamina = 42, 17
+	st, err := SerializePlayerData(p)
+	e.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := newTestEngine()
+	e2.DevSpawn("p1", "Alice", spatial.Vec2{})
+	if err := e2.RestorePlayerState("p1", st, e2.GetPlayerManager().GetAllItemTemplates()); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := e2.GetPlayer("p1")
+	if got.Health != 42 || got.Stamina != 17 {
+		t.Fatalf("restored health=%v stamina=%v, want 42 and 17", got.Health, got.Stamina)
+	}
+	if got.MaxHealth != baseMaxHealth+15+5*2 {
+		t.Fatalf("restored max health = %v; stats must be derived from restored equipment and skills", got.MaxHealth)
+	}
+}
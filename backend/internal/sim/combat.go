@@ -0,0 +1,354 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+var (
+	ErrAttackCooldown = errors.New("attack is on cooldown")
+	ErrTargetNotFound = errors.New("target not found")
+	ErrInvalidTarget  = errors.New("cannot attack this target")
+	ErrNoTarget       = errors.New("no target within reach")
+	ErrOutOfRange     = errors.New("target is out of range")
+	ErrOutOfArc       = errors.New("target is outside the attack arc")
+)
+
+// AttackProfile describes how an attack of one damage type reaches and hurts
+// its target.
+type AttackProfile struct {
+	Range    float64       // meters from attacker to target
+	Arc      float64       // full width of the cone around the aim direction, in degrees
+	Damage   float64       // base damage before armor
+	Cooldown time.Duration // minimum time between attacks
+	Stamina  float64       // stamina spent per attack, hit or miss
+}
+
+// attackProfiles maps a main hand weapon's damage type to its attack. Slashes
+// sweep wide, thrusts reach further along a narrow line, blunt weapons hit
+// hardest but slowest, and elemental attacks are short ranged projectiles.
+var attackProfiles = map[DamageType]AttackProfile{
+	DamageSlash:     {Range: 2.0, Arc: 120, Damage: 12, Cooldown: 800 * time.Millisecond, Stamina: 8},
+	DamagePierce:    {Range: 2.5, Arc: 45, Damage: 10, Cooldown: 700 * time.Millisecond, Stamina: 6},
+	DamageBlunt:     {Range: 1.8, Arc: 90, Damage: 14, Cooldown: time.Second, Stamina: 12},
+	DamageElemental: {Range: 8.0, Arc: 30, Damage: 9, Cooldown: 1200 * time.Millisecond, Stamina: 10},
+}
+
+// unarmedProfile applies when the main hand is empty or holds no weapon.
+var unarmedProfile = AttackProfile{Range: 1.5, Arc: 90, Damage: 3, Cooldown: 600 * time.Millisecond, Stamina: 4}
+
+const (
+	// maxArmor caps the damage fraction absorbed by all equipped pieces together.
+	maxArmor = 0.8
+	// Hit chance falls off linearly from hitChanceNear at point blank to
+	// hitChanceFar at the edge of the weapon's range.
+	hitChanceNear = 0.95
+	hitChanceFar  = 0.7
+	// combatLogSize is how many recent events the engine keeps for snapshots.
+	// Sessions that miss more than this between two snapshots drop the oldest.
+	combatLogSize = 256
+)
+
+// AttackInput is everything needed to resolve one attack. It holds no engine
+// state, so ResolveAttack can be tested in isolation.
+type AttackInput struct {
+	AttackerPos spatial.Vec2
+	Aim         spatial.Vec2 // direction the attack is aimed; need not be normalized
+	TargetPos   spatial.Vec2
+	Profile     AttackProfile
+	Armor       float64 // target's resistance to the attack's damage type
+}
+
+// AttackOutcome is the result of a resolved attack.
+type AttackOutcome struct {
+	Hit      bool
+	Damage   float64
+	Distance float64
+}
+
+// ResolveAttack checks that the target is within reach and decides whether it
+// is hit and for how much. roll is the attack's random draw in [0, 1): the
+// same input and roll always produce the same outcome.
+func ResolveAttack(in AttackInput, roll float64) (AttackOutcome, error) {
+	dist, err := checkReach(in.AttackerPos, in.Aim, in.TargetPos, in.Profile)
+	if err != nil {
+		return AttackOutcome{}, err
+	}
+	out := AttackOutcome{Distance: dist}
+	if roll >= hitChance(dist, in.Profile.Range) {
+		return out, nil
+	}
+	out.Hit = true
+	out.Damage = mitigate(in.Profile.Damage, in.Armor)
+	return out, nil
+}
+
+// checkReach returns the distance to target if it lies within the profile's
+// range and arc around aim. A zero aim, or a target on top of the attacker,
+// passes the arc check.
+func checkReach(from, aim, target spatial.Vec2, p AttackProfile) (float64, error) {
+	d2 := spatial.Dist2(from, target)
+	const eps = 1e-9 // same boundary tolerance as QueryAOI
+	if d2 > p.Range*p.Range+eps {
+		return 0, ErrOutOfRange
+	}
+	dist := math.Sqrt(d2)
+	aimLen := math.Hypot(aim.X, aim.Z)
+	if dist == 0 || aimLen == 0 {
+		return dist, nil
+	}
+	cos := ((target.X-from.X)*aim.X + (target.Z-from.Z)*aim.Z) / (dist * aimLen)
+	if cos < math.Cos(p.Arc/2*math.Pi/180)-eps {
+		return 0, ErrOutOfArc
+	}
+	return dist, nil
+}
+
+// hitChance is the probability of hitting a target dist meters away with a
+// weapon of the given range.
+func hitChance(dist, rng float64) float64 {
+	if rng <= 0 {
+		return hitChanceNear
+	}
+	f := math.Min(dist/rng, 1)
+	return hitChanceNear - (hitChanceNear-hitChanceFar)*f
+}
+
+// mitigate reduces base damage by armor, clamped to [0, maxArmor].
+func mitigate(base, armor float64) float64 {
+	armor = math.Max(0, math.Min(armor, maxArmor))
+	return base * (1 - armor)
+}
+
+// facing returns the unit vector a yaw points along.
+func facing(yaw float64) spatial.Vec2 {
+	return spatial.Vec2{X: math.Sin(yaw), Z: math.Cos(yaw)}
+}
+
+// AttackRequest is a player's attack command.
+type AttackRequest struct {
+	// TargetID picks the target; if empty, the nearest entity within reach is attacked.
+	TargetID string
+	// Aim is the attack direction; if zero, the attacker's facing is used.
+	Aim spatial.Vec2
+}
+
+// CombatEvent records one resolved attack. Events are numbered by Seq in the
+// order they happened and published with each snapshot for sessions to
+// broadcast to players in range.
+type CombatEvent struct {
+	Seq          uint64       `json:"seq"`
+	Tick         uint64       `json:"tick"`
+	AttackerID   string       `json:"attacker_id"`
+	TargetID     string       `json:"target_id"`
+	AttackerPos  spatial.Vec2 `json:"attacker_pos"`
+	TargetPos    spatial.Vec2 `json:"target_pos"`
+	DamageType   DamageType   `json:"damage_type"`
+	Hit          bool         `json:"hit"`
+	Damage       float64      `json:"damage"`
+	TargetHealth float64      `json:"target_health"` // after the attack
+	Killed       bool         `json:"killed,omitempty"`
+}
+
+// Attack resolves an attack by a player with their main hand weapon. A
+// request rejected before resolution (cooldown, too little stamina, no valid
+// target, out of reach) changes nothing; a resolved attack, hit or miss,
+// spends stamina, starts the weapon's cooldown and is recorded as a
+// CombatEvent. Hits take the damage off the target's health.
+func (e *Engine) Attack(playerID string, req AttackRequest, now time.Time) (CombatEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok := e.players[playerID]
+	if !ok {
+		return CombatEvent{}, fmt.Errorf("player %s not found", playerID)
+	}
+	if p.Dead {
+		return CombatEvent{}, ErrDead
+	}
+	if now.Before(p.AttackReadyAt) {
+		return CombatEvent{}, ErrAttackCooldown
+	}
+	dmgType, profile := e.attackProfileLocked(p)
+	if p.Stamina < profile.Stamina {
+		return CombatEvent{}, ErrExhausted
+	}
+	aim := req.Aim
+	if aim == (spatial.Vec2{}) {
+		aim = facing(p.Yaw)
+	}
+
+	var target *Entity
+	switch {
+	case req.TargetID == playerID:
+		return CombatEvent{}, ErrInvalidTarget
+	case req.TargetID != "":
+		if target = e.nearbyEntityLocked(p.Pos, req.TargetID); target == nil {
+			return CombatEvent{}, ErrTargetNotFound
+		}
+		if !alive(target) {
+			return CombatEvent{}, ErrInvalidTarget
+		}
+	default:
+		if target = e.pickTargetLocked(p, aim, profile); target == nil {
+			return CombatEvent{}, ErrNoTarget
+		}
+	}
+
+	out, err := ResolveAttack(AttackInput{
+		AttackerPos: p.Pos,
+		Aim:         aim,
+		TargetPos:   target.Pos,
+		Profile:     profile,
+		Armor:       e.armorLocked(target.ID, dmgType),
+	}, e.rng.Float64())
+	if err != nil {
+		return CombatEvent{}, err
+	}
+	p.spendStamina(profile.Stamina)
+	p.AttackReadyAt = now.Add(profile.Cooldown)
+	xp := xpAttackMiss
+	if out.Hit {
+		xp = xpAttackHit
+	}
+	e.playerMgr.GainSkillXP(p, damageTypeSkills[dmgType], xp)
+	e.wearWeaponLocked(p)
+	killed := false
+	if out.Hit {
+		if tp, ok := e.players[target.ID]; ok {
+			e.wearArmorLocked(tp)
+		}
+		killed = e.applyDamageLocked(target, out.Damage, now)
+	}
+	ev := CombatEvent{
+		Tick:         e.tickCount.Load(),
+		AttackerID:   p.ID,
+		TargetID:     target.ID,
+		AttackerPos:  p.Pos,
+		TargetPos:    target.Pos,
+		DamageType:   dmgType,
+		Hit:          out.Hit,
+		Damage:       out.Damage,
+		TargetHealth: target.Health,
+		Killed:       killed,
+	}
+	e.recordCombatEventLocked(&ev)
+	return ev, nil
+}
+
+// attackProfileLocked returns the damage type and profile of the player's main
+// hand weapon, or the unarmed profile if it is empty or broken. e.mu must be held by caller.
+func (e *Engine) attackProfileLocked(p *Player) (DamageType, AttackProfile) {
+	if p.Equipment != nil {
+		if item := p.Equipment.GetSlot(SlotMainHand); item != nil && !item.Instance.Broken() {
+			if tmpl, ok := e.playerMgr.GetItemTemplate(item.Instance.TemplateID); ok {
+				if profile, ok := attackProfiles[tmpl.DamageType]; ok {
+					return tmpl.DamageType, profile
+				}
+			}
+		}
+	}
+	return DamageBlunt, unarmedProfile
+}
+
+// armorLocked returns the target's resistance to dmgType from its derived
+// stats. Bots wear nothing. e.mu must be held by caller.
+func (e *Engine) armorLocked(targetID string, dmgType DamageType) float64 {
+	p, ok := e.players[targetID]
+	if !ok {
+		return 0
+	}
+	e.playerMgr.RefreshStats(p) // equipment may have changed since the last tick
+	return p.Stats.Armor[dmgType]
+}
+
+// nearbyEntityLocked finds an entity in the 3x3 cell neighborhood of pos.
+// Attack ranges are far below the cell size, so anything further away is out
+// of reach anyway. e.mu must be held by caller.
+func (e *Engine) nearbyEntityLocked(pos spatial.Vec2, id string) *Entity {
+	cx, cz := spatial.WorldToCell(pos.X, pos.Z, e.cfg.CellSize)
+	for _, k := range spatial.Neighbors3x3(spatial.CellKey{Cx: cx, Cz: cz}) {
+		if cell, ok := e.cells[k]; ok {
+			if ent, ok := cell.Entities[id]; ok {
+				return ent
+			}
+		}
+	}
+	return nil
+}
+
+// pickTargetLocked returns the nearest entity within the profile's reach
+// around aim, breaking ties by id so the choice is deterministic. e.mu must be
+// held by caller.
+func (e *Engine) pickTargetLocked(p *Player, aim spatial.Vec2, profile AttackProfile) *Entity {
+	cx, cz := spatial.WorldToCell(p.Pos.X, p.Pos.Z, e.cfg.CellSize)
+	var (
+		best     *Entity
+		bestDist float64
+	)
+	for _, k := range spatial.Neighbors3x3(spatial.CellKey{Cx: cx, Cz: cz}) {
+		cell, ok := e.cells[k]
+		if !ok {
+			continue
+		}
+		for id, ent := range cell.Entities {
+			if id == p.ID || !alive(ent) {
+				continue
+			}
+			dist, err := checkReach(p.Pos, aim, ent.Pos, profile)
+			if err != nil {
+				continue
+			}
+			if best == nil || dist < bestDist || (dist == bestDist && id < best.ID) {
+				best, bestDist = ent, dist
+			}
+		}
+	}
+	return best
+}
+
+// alive reports whether ent can be attacked: not a corpse and not dead.
+func alive(ent *Entity) bool {
+	return ent.Kind != KindCorpse && ent.Health > 0
+}
+
+// recordCombatEventLocked numbers ev and appends it to the log published with
+// snapshots. Published snapshots keep referencing the old backing array, so
+// entries are never modified in place. e.mu must be held by caller.
+func (e *Engine) recordCombatEventLocked(ev *CombatEvent) {
+	e.combatSeq++
+	ev.Seq = e.combatSeq
+	if len(e.combatLog) >= 2*combatLogSize {
+		e.combatLog = append([]CombatEvent(nil), e.combatLog[len(e.combatLog)-combatLogSize:]...)
+	}
+	e.combatLog = append(e.combatLog, *ev)
+}
+
+// recentCombatLocked returns up to combatLogSize of the latest events, oldest
+// first. e.mu must be held (read or write) by caller.
+func (e *Engine) recentCombatLocked() []CombatEvent {
+	n := len(e.combatLog)
+	return e.combatLog[max(0, n-combatLogSize):n:n]
+}
+
+// CombatSeq returns the Seq of the latest combat event, or 0 if there was none.
+func (e *Engine) CombatSeq() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.combatSeq
+}
+
+// CombatSince returns the snapshot's combat events with Seq greater than seq,
+// oldest first. It is safe to call on a nil snapshot.
+func (s *WorldSnapshot) CombatSince(seq uint64) []CombatEvent {
+	if s == nil {
+		return nil
+	}
+	i := sort.Search(len(s.combat), func(i int) bool { return s.combat[i].Seq > seq })
+	return s.combat[i:]
+}
@@ -0,0 +1,188 @@
+package sim
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+// fixedSource makes every rand.Float64 draw return roughly the same value.
+type fixedSource float64
+
+func (f fixedSource) Int63() int64 { return int64(float64(f) * (1 << 63)) }
+func (fixedSource) Seed(int64)     {}
+
+func withRolls(e *Engine, roll float64) { e.rng = rand.New(fixedSource(roll)) }
+
+func TestResolveAttack(t *testing.T) {
+	sword := attackProfiles[DamageSlash]
+	origin := spatial.Vec2{}
+	north := spatial.Vec2{Z: 1}
+	cases := []struct {
+		name    string
+		in      AttackInput
+		roll    float64
+		wantErr error
+		wantHit bool
+		wantDmg float64
+	}{
+		{"point blank hit", AttackInput{origin, north, spatial.Vec2{Z: 1}, sword, 0}, 0.5, nil, true, 12},
+		{"armor mitigates", AttackInput{origin, north, spatial.Vec2{Z: 1}, sword, 0.25}, 0.5, nil, true, 9},
+		{"armor is capped", AttackInput{origin, north, spatial.Vec2{Z: 1}, sword, 2}, 0.5, nil, true, 12 * (1 - maxArmor)},
+		{"high roll misses", AttackInput{origin, north, spatial.Vec2{Z: 1}, sword, 0}, 0.99, nil, false, 0},
+		{"at max range", AttackInput{origin, north, spatial.Vec2{Z: 2}, sword, 0}, 0.5, nil, true, 12},
+		{"beyond range", AttackInput{origin, north, spatial.Vec2{Z: 2.01}, sword, 0}, 0, ErrOutOfRange, false, 0},
+		{"inside arc", AttackInput{origin, north, spatial.Vec2{X: 1, Z: 0.6}, sword, 0}, 0.5, nil, true, 12},
+		{"outside arc", AttackInput{origin, north, spatial.Vec2{X: 1, Z: -0.6}, sword, 0}, 0, ErrOutOfArc, false, 0},
+		{"behind", AttackInput{origin, north, spatial.Vec2{Z: -1}, sword, 0}, 0, ErrOutOfArc, false, 0},
+		{"narrow thrust", AttackInput{origin, north, spatial.Vec2{X: 1, Z: 1}, attackProfiles[DamagePierce], 0}, 0, ErrOutOfArc, false, 0},
+		{"no aim ignores arc", AttackInput{origin, spatial.Vec2{}, spatial.Vec2{Z: -1}, sword, 0}, 0.5, nil, true, 12},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ResolveAttack(tc.in, tc.roll)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if out.Hit != tc.wantHit || math.Abs(out.Damage-tc.wantDmg) > 1e-9 {
+				t.Fatalf("outcome = %+v, want hit=%v damage=%v", out, tc.wantHit, tc.wantDmg)
+			}
+		})
+	}
+}
+
+func TestHitChanceFallsOffWithDistance(t *testing.T) {
+	if got := hitChance(0, 2); got != hitChanceNear {
+		t.Fatalf("point blank chance = %v, want %v", got, hitChanceNear)
+	}
+	if got := hitChance(2, 2); math.Abs(got-hitChanceFar) > 1e-9 {
+		t.Fatalf("max range chance = %v, want %v", got, hitChanceFar)
+	}
+	if near, mid := hitChance(0.5, 2), hitChance(1.5, 2); near <= mid {
+		t.Fatalf("expected chance to fall with distance: %v <= %v", near, mid)
+	}
+}
+
+// equip gives a player an item from the test templates and equips it.
+func equip(t *testing.T, e *Engine, playerID string, tmpl ItemTemplateID, slot SlotID) {
+	t.Helper()
+	if err := e.DevGivePlayerSkill(playerID, "melee", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DevGivePlayerSkill(playerID, "defense", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DevAddItemToPlayer(playerID, tmpl, 1, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := e.GetPlayer(playerID)
+	for _, item := range p.Inventory.Items {
+		if item.Instance.TemplateID == tmpl {
+			if err := e.EquipItem(playerID, item.Instance.InstanceID, slot, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("%s not in inventory", tmpl)
+}
+
+func TestEngineAttack_WeaponAgainstArmor(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 2.5})
+	equip(t, e, "p1", "sword_iron", SlotMainHand)
+	equip(t, e, "p2", "armor_leather", SlotChest)
+
+	now := time.Now()
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now)
+	if err != nil {
+		t.Fatalf("attack: %v", err)
+	}
+	if !ev.Hit || ev.DamageType != DamageSlash || math.Abs(ev.Damage-9) > 1e-9 {
+		t.Fatalf("expected a 9 damage slash hit through leather, got %+v", ev)
+	}
+	if ev.Seq != 1 || ev.AttackerID != "p1" || ev.TargetID != "p2" {
+		t.Fatalf("unexpected event identity: %+v", ev)
+	}
+
+	if _, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now.Add(100*time.Millisecond)); !errors.Is(err, ErrAttackCooldown) {
+		t.Fatalf("expected cooldown, got %v", err)
+	}
+	withRolls(e, 0.99)
+	ev, err = e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now.Add(attackProfiles[DamageSlash].Cooldown))
+	if err != nil || ev.Hit || ev.Damage != 0 || ev.Seq != 2 {
+		t.Fatalf("expected a recorded miss after the cooldown, got %+v, %v", ev, err)
+	}
+
+	snap := e.PublishSnapshot()
+	if got := snap.CombatSince(0); len(got) != 2 || got[0].Seq != 1 || got[1].Seq != 2 {
+		t.Fatalf("snapshot combat events = %+v", got)
+	}
+	if got := snap.CombatSince(e.CombatSeq()); len(got) != 0 {
+		t.Fatalf("expected no events after the latest seq, got %+v", got)
+	}
+}
+
+func TestEngineAttack_RejectionsKeepWeaponReady(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 4})
+	now := time.Now()
+
+	cases := []struct {
+		req  AttackRequest
+		want error
+	}{
+		{AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, ErrOutOfRange},
+		{AttackRequest{TargetID: "p1"}, ErrInvalidTarget},
+		{AttackRequest{TargetID: "ghost"}, ErrTargetNotFound},
+		{AttackRequest{Aim: spatial.Vec2{Z: 1}}, ErrNoTarget},
+	}
+	for _, tc := range cases {
+		if _, err := e.Attack("p1", tc.req, now); !errors.Is(err, tc.want) {
+			t.Fatalf("Attack(%+v) = %v, want %v", tc.req, err, tc.want)
+		}
+	}
+
+	e.AddOrUpdatePlayer("p2", "Bob", spatial.Vec2{X: 1, Z: 2}, spatial.Vec2{})
+	if _, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{X: 1}}, now); !errors.Is(err, ErrOutOfArc) {
+		t.Fatalf("expected out of arc, got %v", err)
+	}
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now)
+	if err != nil {
+		t.Fatalf("attack after rejections: %v", err)
+	}
+	if ev.DamageType != DamageBlunt || ev.Damage != unarmedProfile.Damage {
+		t.Fatalf("expected an unarmed blunt hit, got %+v", ev)
+	}
+}
+
+func TestEngineAttack_PicksNearestTargetAlongFacing(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 5})
+	e.DevSpawn("ahead", "Bob", spatial.Vec2{X: 6.2, Z: 5})
+	e.DevSpawn("closer_behind", "Carol", spatial.Vec2{X: 4, Z: 5})
+	e.DevSpawn("further_ahead", "Dave", spatial.Vec2{X: 6.4, Z: 5})
+
+	// Walking towards +X turns the player to face it.
+	e.DevSetVelocity("p1", spatial.Vec2{X: 1})
+	e.Step(time.Millisecond)
+	e.DevSetVelocity("p1", spatial.Vec2{})
+	e.Step(time.Millisecond)
+
+	ev, err := e.Attack("p1", AttackRequest{}, time.Now())
+	if err != nil {
+		t.Fatalf("attack: %v", err)
+	}
+	if ev.TargetID != "ahead" {
+		t.Fatalf("expected the nearest entity ahead to be picked, got %q", ev.TargetID)
+	}
+}
@@ -0,0 +1,202 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"time"
+)
+
+var (
+	ErrNotUsable     = errors.New("item cannot be used")
+	ErrUseCooldown   = errors.New("item category is on cooldown")
+	ErrUnknownSpawn  = errors.New("teleport destination does not exist")
+	ErrUnknownEffect = errors.New("unknown item effect")
+	ErrNothingToHeal = errors.New("already at full health")
+)
+
+// ItemCategory groups usable items that share a use cooldown.
+type ItemCategory string
+
+const (
+	CategoryPotion ItemCategory = "potion"
+	CategoryScroll ItemCategory = "scroll"
+	CategoryFood   ItemCategory = "food"
+)
+
+// categoryCooldowns is how long after using an item no other item of the
+// same category can be used.
+var categoryCooldowns = map[ItemCategory]time.Duration{
+	CategoryPotion: 10 * time.Second,
+	CategoryScroll: 30 * time.Second,
+	CategoryFood:   5 * time.Second,
+}
+
+// defaultUseCooldown applies to categories without an entry above.
+const defaultUseCooldown = time.Second
+
+// EffectKind is what using an item does.
+type EffectKind string
+
+const (
+	EffectHeal     EffectKind = "heal"     // restore Amount health
+	EffectBuff     EffectKind = "buff"     // add Bonus to stats for Duration
+	EffectTeleport EffectKind = "teleport" // move to the spawn point named Destination, or the nearest one
+)
+
+// ItemEffect is one effect of using an item.
+type ItemEffect struct {
+	Kind        EffectKind    `json:"kind"`
+	Amount      float64       `json:"amount,omitempty"`
+	Bonus       *StatBonus    `json:"bonus,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Destination string        `json:"destination,omitempty"`
+}
+
+// Buff is a temporary stat bonus from a used item.
+type Buff struct {
+	Source    ItemTemplateID `json:"source"`
+	Bonus     StatBonus      `json:"bonus"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// UseResult describes a successful use of an item.
+type UseResult struct {
+	TemplateID    ItemTemplateID `json:"template_id"`
+	Remaining     int            `json:"remaining"` // quantity left; 0 means the instance is gone
+	CooldownUntil time.Time      `json:"cooldown_until"`
+}
+
+func useCooldown(category ItemCategory) time.Duration {
+	if d, ok := categoryCooldowns[category]; ok {
+		return d
+	}
+	return defaultUseCooldown
+}
+
+// UseItem uses one unit of a carried item, applying its template's effects,
+// starting its category's cooldown and removing the instance when none are
+// left. Nothing is consumed if any effect cannot apply.
+func (e *Engine) UseItem(playerID string, instanceID ItemInstanceID, now time.Time) (UseResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok := e.players[playerID]
+	if !ok {
+		return UseResult{}, fmt.Errorf("player %s not found", playerID)
+	}
+	if p.Dead {
+		return UseResult{}, ErrDead
+	}
+	if p.Inventory == nil {
+		return UseResult{}, ErrItemNotFound
+	}
+	idx := p.Inventory.FindItem(instanceID)
+	if idx < 0 {
+		return UseResult{}, ErrItemNotFound
+	}
+	inst := p.Inventory.Items[idx].Instance
+	tmpl, ok := e.playerMgr.GetItemTemplate(inst.TemplateID)
+	if !ok {
+		return UseResult{}, fmt.Errorf("unknown item template: %s", inst.TemplateID)
+	}
+	if len(tmpl.Effects) == 0 {
+		return UseResult{}, ErrNotUsable
+	}
+	if now.Before(p.UseCooldowns[tmpl.Category]) {
+		return UseResult{}, ErrUseCooldown
+	}
+	for _, eff := range tmpl.Effects {
+		if err := e.checkEffectLocked(p, eff); err != nil {
+			return UseResult{}, err
+		}
+	}
+
+	for _, eff := range tmpl.Effects {
+		e.applyEffectLocked(p, tmpl.ID, eff, now)
+	}
+	// replaced rather than modified, like Skills (see GainSkillXP)
+	cooldowns := maps.Clone(p.UseCooldowns)
+	if cooldowns == nil {
+		cooldowns = make(map[ItemCategory]time.Time)
+	}
+	cooldowns[tmpl.Category] = now.Add(useCooldown(tmpl.Category))
+	p.UseCooldowns = cooldowns
+
+	remaining := inst.Quantity - 1
+	if remaining > 0 {
+		p.Inventory.Items[idx].Instance.Quantity = remaining
+	} else {
+		_ = p.Inventory.RemoveItem(instanceID)
+	}
+	p.InventoryVersion++
+	return UseResult{TemplateID: tmpl.ID, Remaining: remaining, CooldownUntil: cooldowns[tmpl.Category]}, nil
+}
+
+// checkEffectLocked reports why an effect cannot apply to the player, if it
+// cannot. e.mu must be held by caller.
+func (e *Engine) checkEffectLocked(p *Player, eff ItemEffect) error {
+	switch eff.Kind {
+	case EffectHeal:
+		if p.Health >= p.MaxHealth {
+			return ErrNothingToHeal
+		}
+	case EffectBuff:
+		if eff.Bonus == nil || eff.Duration <= 0 {
+			return fmt.Errorf("%w: buff needs a bonus and a duration", ErrUnknownEffect)
+		}
+	case EffectTeleport:
+		if eff.Destination != "" {
+			if _, ok := e.world.SpawnPoint(eff.Destination); !ok {
+				return ErrUnknownSpawn
+			}
+		}
+	default:
+		return ErrUnknownEffect
+	}
+	return nil
+}
+
+// applyEffectLocked applies a checked effect. e.mu must be held by caller.
+func (e *Engine) applyEffectLocked(p *Player, source ItemTemplateID, eff ItemEffect, now time.Time) {
+	switch eff.Kind {
+	case EffectHeal:
+		p.Health = math.Min(p.Health+eff.Amount, p.MaxHealth)
+		p.AttributesVersion++
+	case EffectBuff:
+		buffs := make([]Buff, 0, len(p.Buffs)+1)
+		for _, b := range p.Buffs {
+			// using the same item again refreshes its buff rather than stacking it
+			if b.Source != source {
+				buffs = append(buffs, b)
+			}
+		}
+		p.Buffs = append(buffs, Buff{Source: source, Bonus: *eff.Bonus, ExpiresAt: now.Add(eff.Duration)})
+		p.buffsVersion++
+	case EffectTeleport:
+		sp, ok := e.world.SpawnPoint(eff.Destination)
+		if !ok {
+			sp = e.world.NearestSpawn(p.Pos)
+		}
+		e.relocatePlayerLocked(p, sp.Pos, now)
+	}
+}
+
+// expireBuffs drops buffs that ran out; stats are re-derived on the next
+// refresh.
+func (p *Player) expireBuffs(now time.Time) {
+	if len(p.Buffs) == 0 {
+		return
+	}
+	active := make([]Buff, 0, len(p.Buffs))
+	for _, b := range p.Buffs {
+		if now.Before(b.ExpiresAt) {
+			active = append(active, b)
+		}
+	}
+	if len(active) != len(p.Buffs) {
+		p.Buffs = active
+		p.buffsVersion++
+	}
+}
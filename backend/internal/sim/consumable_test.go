@@ -0,0 +1,111 @@
+package sim
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+// carried returns the instance id of the first carried item of a template.
+func carried(t *testing.T, e *Engine, playerID string, tmpl ItemTemplateID) ItemInstanceID {
+	t.Helper()
+	p, _ := e.GetPlayer(playerID)
+	for _, item := range p.Inventory.Items {
+		if item.Instance.TemplateID == tmpl {
+			return item.Instance.InstanceID
+		}
+	}
+	t.Fatalf("%s not in inventory", tmpl)
+	return ""
+}
+
+func TestUseItem_HealConsumesAndSharesCategoryCooldown(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	if err := e.DevAddItemToPlayer("p1", "potion_health", 2, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DevAddItemToPlayer("p1", "draught_stamina", 1, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	potion := carried(t, e, "p1", "potion_health")
+	now := time.Now()
+
+	if _, err := e.UseItem("p1", potion, now); !errors.Is(err, ErrNothingToHeal) {
+		t.Fatalf("using a potion at full health: got %v, want ErrNothingToHeal", err)
+	}
+	setHealth(e, "p1", 50)
+	res, err := e.UseItem("p1", potion, now)
+	if err != nil || res.Remaining != 1 || !res.CooldownUntil.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("use = %+v, %v", res, err)
+	}
+	p, _ := e.GetPlayer("p1")
+	if p.Health != 80 {
+		t.Fatalf("health after potion = %v, want 80", p.Health)
+	}
+
+	draught := carried(t, e, "p1", "draught_stamina")
+	if _, err := e.UseItem("p1", draught, now.Add(time.Second)); !errors.Is(err, ErrUseCooldown) {
+		t.Fatalf("another potion during the cooldown: got %v, want ErrUseCooldown", err)
+	}
+	res, err = e.UseItem("p1", potion, now.Add(10*time.Second))
+	if err != nil || res.Remaining != 0 {
+		t.Fatalf("last potion: %+v, %v", res, err)
+	}
+	p, _ = e.GetPlayer("p1")
+	if p.Inventory.HasItem(potion) {
+		t.Fatalf("the used up potion should be removed from the inventory")
+	}
+
+	if _, err := e.UseItem("p1", potion, now.Add(time.Minute)); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("using a removed potion: got %v, want ErrItemNotFound", err)
+	}
+	if err := e.DevAddItemToPlayer("p1", "rock_small", 1, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.UseItem("p1", carried(t, e, "p1", "rock_small"), now.Add(time.Minute)); !errors.Is(err, ErrNotUsable) {
+		t.Fatalf("using a rock: got %v, want ErrNotUsable", err)
+	}
+}
+
+func TestUseItem_BuffRaisesStatsUntilItExpires(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	if err := e.DevAddItemToPlayer("p1", "draught_stamina", 1, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.UseItem("p1", carried(t, e, "p1", "draught_stamina"), now); err != nil {
+		t.Fatal(err)
+	}
+	e.Step(time.Millisecond)
+	p, _ := e.GetPlayer("p1")
+	if p.Stats.MaxStamina != baseMaxStamina+20 || len(p.Buffs) != 1 {
+		t.Fatalf("buffed max stamina = %v, buffs %+v", p.Stats.MaxStamina, p.Buffs)
+	}
+
+	now = now.Add(time.Minute)
+	e.Step(time.Millisecond)
+	p, _ = e.GetPlayer("p1")
+	if p.Stats.MaxStamina != baseMaxStamina || len(p.Buffs) != 0 || p.Stamina > baseMaxStamina {
+		t.Fatalf("after expiry max stamina = %v, stamina %v, buffs %+v", p.Stats.MaxStamina, p.Stamina, p.Buffs)
+	}
+}
+
+func TestUseItem_TeleportToNearestSpawn(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 24, Z: 3})
+	if err := e.DevAddItemToPlayer("p1", "scroll_recall", 1, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.UseItem("p1", carried(t, e, "p1", "scroll_recall"), now); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := e.GetPlayer("p1")
+	if p.Pos != (spatial.Vec2{X: 30}) || p.OwnedCell != (spatial.CellKey{Cx: 3}) {
+		t.Fatalf("after recall pos=%v cell=%v, want the east spawn", p.Pos, p.OwnedCell)
+	}
+}
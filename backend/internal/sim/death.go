@@ -0,0 +1,196 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+var ErrDead = errors.New("player is dead")
+
+// DeathConfig controls what a player loses on death and when they come back.
+// Zero fields take the defaults noted on each.
+type DeathConfig struct {
+	// RespawnDelay is how long a player stays dead; defaults to 10 seconds.
+	RespawnDelay time.Duration
+	// CorpseTTL is how long a corpse stays in the world; defaults to 5
+	// minutes.
+	CorpseTTL time.Duration
+	// DropChance is the chance each backpack item is dropped on the ground;
+	// nil defaults to 0.25, 0 or negative keeps everything. Belt and craft
+	// bag items and equipment are always kept.
+	DropChance *float64
+	// DurabilityLoss is taken from every equipped item's durability; nil
+	// defaults to 0.1, 0 or negative disables wear.
+	DurabilityLoss *float64
+}
+
+func (c DeathConfig) withDefaults() DeathConfig {
+	if c.RespawnDelay <= 0 {
+		c.RespawnDelay = 10 * time.Second
+	}
+	if c.CorpseTTL <= 0 {
+		c.CorpseTTL = 5 * time.Minute
+	}
+	if c.DropChance == nil {
+		c.DropChance = ptr(0.25)
+	}
+	if c.DurabilityLoss == nil {
+		c.DurabilityLoss = ptr(0.1)
+	}
+	return c
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// corpse is what an entity leaves where it died. It is an entity of
+// KindCorpse in its cell, so it shows up in AOI until it expires.
+type corpse struct {
+	ent       *Entity
+	cell      spatial.CellKey
+	ownerID   string // player who died; empty for bots
+	expiresAt time.Time
+}
+
+// Corpse is a copy of a corpse's state.
+type Corpse struct {
+	ID        string
+	OwnerID   string
+	Pos       spatial.Vec2
+	ExpiresAt time.Time
+}
+
+// Corpse returns the corpse with the given entity id.
+func (e *Engine) Corpse(id string) (Corpse, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	c, ok := e.corpses[id]
+	if !ok {
+		return Corpse{}, false
+	}
+	return Corpse{
+		ID:        c.ent.ID,
+		OwnerID:   c.ownerID,
+		Pos:       c.ent.Pos,
+		ExpiresAt: c.expiresAt,
+	}, true
+}
+
+// killLocked handles an entity whose health reached zero. Players stay in the
+// world, dead, until they respawn; bots are removed and replaced by density
+// maintenance. Both leave a corpse. e.mu must be held by caller.
+func (e *Engine) killLocked(ent *Entity, now time.Time) {
+	if p, ok := e.players[ent.ID]; ok {
+		e.killPlayerLocked(p, now)
+		return
+	}
+	if st, ok := e.bots[ent.ID]; ok {
+		if c, ok := e.cells[st.OwnedCell]; ok {
+			delete(c.Entities, ent.ID)
+		}
+		delete(e.bots, ent.ID)
+		e.spawnCorpseLocked(ent, "", now)
+	}
+}
+
+// killPlayerLocked puts a player into the death state, drops part of their
+// backpack on the ground next to their corpse and wears their equipment. The
+// dropped items keep the player's looting rights until the ground owner window
+// has passed after respawning. e.mu must be held by caller.
+func (e *Engine) killPlayerLocked(p *Player, now time.Time) {
+	p.Dead = true
+	p.Health = 0
+	p.Vel = spatial.Vec2{}
+	p.RespawnAt = now.Add(e.death.RespawnDelay)
+	p.AttributesVersion++
+	ownerUntil := p.RespawnAt.Add(e.ground.OwnerWindow)
+	for _, item := range e.dropOnDeathLocked(p) {
+		e.spawnGroundItemLocked(item, p.Pos, p.ID, ownerUntil, now.Add(e.ground.DespawnAfter))
+	}
+	wearOnDeath(p, *e.death.DurabilityLoss)
+	e.spawnCorpseLocked(&p.Entity, p.ID, now)
+}
+
+// dropOnDeathLocked removes a random share of the player's backpack items and
+// returns them. e.mu must be held by caller.
+func (e *Engine) dropOnDeathLocked(p *Player) []ItemInstance {
+	if p.Inventory == nil || *e.death.DropChance <= 0 {
+		return nil
+	}
+	var dropped []ItemInstance
+	for _, item := range p.Inventory.GetCompartmentContents(CompartmentBackpack) {
+		if e.rng.Float64() >= *e.death.DropChance {
+			continue
+		}
+		if err := p.Inventory.RemoveItem(item.Instance.InstanceID); err == nil {
+			dropped = append(dropped, item.Instance)
+		}
+	}
+	if len(dropped) > 0 {
+		p.InventoryVersion++
+	}
+	return dropped
+}
+
+// wearOnDeath takes loss from the durability of every equipped item.
+func wearOnDeath(p *Player, loss float64) {
+	if p.Equipment == nil || loss <= 0 {
+		return
+	}
+	worn := false
+	for _, item := range p.Equipment.Slots {
+		worn = item.wear(loss) || worn
+	}
+	if worn {
+		p.EquipmentVersion++
+	}
+}
+
+// spawnCorpseLocked places a corpse of ent at its position. e.mu must be held by caller.
+func (e *Engine) spawnCorpseLocked(ent *Entity, ownerID string, now time.Time) {
+	e.corpseSeq++
+	id := fmt.Sprintf("corpse-%d", e.corpseSeq)
+	cx, cz := spatial.WorldToCell(ent.Pos.X, ent.Pos.Z, e.cfg.CellSize)
+	key := spatial.CellKey{Cx: cx, Cz: cz}
+	c := &corpse{
+		ent:       &Entity{ID: id, Kind: KindCorpse, Pos: ent.Pos, Yaw: ent.Yaw, Name: ent.Name},
+		cell:      key,
+		ownerID:   ownerID,
+		expiresAt: now.Add(e.death.CorpseTTL),
+	}
+	e.getOrCreateCellLocked(key).Entities[id] = c.ent
+	e.corpses[id] = c
+}
+
+// updateDeathsLocked respawns players whose delay is over and removes expired
+// corpses. e.mu must be held by caller.
+func (e *Engine) updateDeathsLocked(now time.Time) {
+	for _, p := range e.players {
+		if p.Dead && !now.Before(p.RespawnAt) {
+			e.respawnLocked(p, now)
+		}
+	}
+	for id, c := range e.corpses {
+		if now.Before(c.expiresAt) {
+			continue
+		}
+		if cell, ok := e.cells[c.cell]; ok {
+			delete(cell.Entities, id)
+		}
+		delete(e.corpses, id)
+	}
+}
+
+// respawnLocked brings a dead player back at the spawn point nearest to where
+// they died, with full health and stamina. e.mu must be held by caller.
+func (e *Engine) respawnLocked(p *Player, now time.Time) {
+	e.relocatePlayerLocked(p, e.world.NearestSpawn(p.Pos).Pos, now)
+	p.Dead = false
+	p.RespawnAt = time.Time{}
+	e.playerMgr.RefreshStats(p)
+	p.Health = p.MaxHealth
+	p.Stamina = p.Stats.MaxStamina
+	p.AttributesVersion++
+}
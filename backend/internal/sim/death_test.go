@@ -0,0 +1,295 @@
+package sim
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func newDeathTestEngine(now *time.Time) *Engine {
+	e := NewEngine(Config{
+		CellSize:            10,
+		AOIRadius:           5,
+		TickHz:              20,
+		SnapshotHz:          10,
+		HandoverHysteresisM: 2,
+		World: &World{SpawnPoints: []SpawnPoint{
+			{Name: "origin"},
+			{Name: "east", Pos: spatial.Vec2{X: 30}},
+		}},
+	})
+	e.clock = func() time.Time { return *now }
+	withRolls(e, 0) // every attack hits, every backpack item drops
+	return e
+}
+
+func setHealth(e *Engine, id string, health float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.players[id].Health = health
+}
+
+func TestPlayerDeathLeavesCorpseAndRespawnsAtNearestSpawn(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 28, Z: 0})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 28, Z: 1})
+	equip(t, e, "p2", "armor_leather", SlotChest)
+	if err := e.DevAddItemToPlayer("p2", "rock_small", 3, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DevAddItemToPlayer("p2", "potion_health", 1, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	rocks := carried(t, e, "p2", "rock_small")
+	setHealth(e, "p2", 1)
+
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now)
+	if err != nil || !ev.Killed || ev.TargetHealth != 0 {
+		t.Fatalf("expected a killing blow, got %+v, %v", ev, err)
+	}
+	p2, _ := e.GetPlayer("p2")
+	if !p2.Dead || !p2.RespawnAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("dead=%v respawnAt=%v, want dead until %v", p2.Dead, p2.RespawnAt, now.Add(10*time.Second))
+	}
+	// worn once by the hit, then by the death
+	if got := p2.Equipment.GetSlot(SlotChest).Instance.Durability; math.Abs(got-(1-armorWearPerHit-0.1)) > 1e-9 {
+		t.Fatalf("armor durability = %v, want %v", got, 1-armorWearPerHit-0.1)
+	}
+	if n := len(p2.Inventory.GetCompartmentContents(CompartmentBackpack)); n != 0 {
+		t.Fatalf("expected the backpack to be dropped, %d items left", n)
+	}
+	if n := len(p2.Inventory.GetCompartmentContents(CompartmentBelt)); n != 1 {
+		t.Fatalf("expected belt items to be kept, got %d", n)
+	}
+
+	c, ok := e.Corpse("corpse-1")
+	if !ok || c.OwnerID != "p2" || c.Pos != (spatial.Vec2{X: 28, Z: 1}) {
+		t.Fatalf("corpse = %+v, %v", c, ok)
+	}
+	g, ok := e.GroundItem("ground-" + string(rocks))
+	if !ok || g.Item.Quantity != 3 || g.Pos != c.Pos || g.OwnerID != "p2" {
+		t.Fatalf("ground item = %+v, %v, want the dropped rocks", g, ok)
+	}
+	// Looting rights outlast the respawn delay.
+	if !g.OwnerUntil.Equal(now.Add(10*time.Second + time.Minute)) {
+		t.Fatalf("looting rights until %v", g.OwnerUntil)
+	}
+
+	if _, err := e.Attack("p2", AttackRequest{TargetID: "p1"}, now); !errors.Is(err, ErrDead) {
+		t.Fatalf("dead attacker: got %v, want ErrDead", err)
+	}
+	later := now.Add(time.Second)
+	for _, id := range []string{"p2", "corpse-1"} {
+		if _, err := e.Attack("p1", AttackRequest{TargetID: id, Aim: spatial.Vec2{Z: 1}}, later); !errors.Is(err, ErrInvalidTarget) {
+			t.Fatalf("attacking %s: got %v, want ErrInvalidTarget", id, err)
+		}
+	}
+
+	// Dead players do not move or regenerate.
+	e.DevSetVelocity("p2", spatial.Vec2{X: 5})
+	now = now.Add(9 * time.Second)
+	e.Step(time.Second)
+	p2, _ = e.GetPlayer("p2")
+	if !p2.Dead || p2.Pos != (spatial.Vec2{X: 28, Z: 1}) || p2.Health != 0 {
+		t.Fatalf("before the delay: dead=%v pos=%v health=%v", p2.Dead, p2.Pos, p2.Health)
+	}
+
+	now = now.Add(time.Second)
+	e.Step(time.Millisecond)
+	p2, _ = e.GetPlayer("p2")
+	if p2.Dead || p2.Pos != (spatial.Vec2{X: 30}) || p2.OwnedCell != (spatial.CellKey{Cx: 3}) {
+		t.Fatalf("after the delay: dead=%v pos=%v cell=%v, want alive at the east spawn", p2.Dead, p2.Pos, p2.OwnedCell)
+	}
+	if p2.Health != p2.MaxHealth || p2.Stamina != p2.Stats.MaxStamina {
+		t.Fatalf("respawned with health=%v stamina=%v, want full pools", p2.Health, p2.Stamina)
+	}
+
+	now = now.Add(5 * time.Minute)
+	e.Step(time.Millisecond)
+	if _, ok := e.Corpse("corpse-1"); ok {
+		t.Fatalf("expected the corpse to despawn after its TTL")
+	}
+	for _, ent := range e.DevListAllEntities() {
+		if ent.Kind == KindCorpse {
+			t.Fatalf("corpse entity %s still in the world", ent.ID)
+		}
+	}
+	// The dropped items outlast the corpse and can be picked back up.
+	if _, err := e.PickUpItem("p2", g.ID, CompartmentBackpack, now); err != nil {
+		t.Fatalf("picking up the dropped rocks: %v", err)
+	}
+}
+
+func TestDeadPlayersCannotManageItems(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	if err := e.DevAddItemToPlayer("p1", "potion_health", 2, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	potion := carried(t, e, "p1", "potion_health")
+	e.mu.Lock()
+	e.killPlayerLocked(e.players["p1"], now)
+	e.mu.Unlock()
+
+	errs := map[string]error{
+		"equip":   e.EquipItem("p1", potion, SlotMainHand, now),
+		"unequip": e.UnequipItem("p1", SlotMainHand, CompartmentBackpack, now),
+		"move":    e.MoveItem("p1", potion, CompartmentBackpack, 0),
+		"place":   e.PlaceItem("p1", potion, GridPos{}),
+		"sort":    e.SortInventory("p1", CompartmentBelt),
+	}
+	_, errs["repair"] = e.RepairItem("p1", potion)
+	_, errs["split"] = e.SplitStack("p1", potion, 1)
+	_, errs["merge"] = e.MergeStack("p1", potion, potion)
+	for op, err := range errs {
+		if !errors.Is(err, ErrDead) {
+			t.Errorf("%s while dead: got %v, want ErrDead", op, err)
+		}
+	}
+}
+
+func TestZeroDeathConfigKeepsEverything(t *testing.T) {
+	c := DeathConfig{DropChance: ptr(0.0), DurabilityLoss: ptr(0.0)}.withDefaults()
+	if *c.DropChance != 0 || *c.DurabilityLoss != 0 {
+		t.Fatalf("explicit zeros became drop=%v loss=%v", *c.DropChance, *c.DurabilityLoss)
+	}
+	if d := (DeathConfig{}).withDefaults(); *d.DropChance != 0.25 || *d.DurabilityLoss != 0.1 {
+		t.Fatalf("defaults drop=%v loss=%v", *d.DropChance, *d.DurabilityLoss)
+	}
+
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.death = c
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	equip(t, e, "p1", "armor_leather", SlotChest)
+	if err := e.DevAddItemToPlayer("p1", "rock_small", 3, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	e.mu.Lock()
+	e.killPlayerLocked(e.players["p1"], now)
+	e.mu.Unlock()
+	p1, _ := e.GetPlayer("p1")
+	if n := len(p1.Inventory.GetCompartmentContents(CompartmentBackpack)); n != 1 {
+		t.Fatalf("backpack has %d items after death, want the rocks kept", n)
+	}
+	if got := p1.Equipment.GetSlot(SlotChest).Instance.Durability; got != 1 {
+		t.Fatalf("armor durability = %v, want no wear", got)
+	}
+}
+
+func TestBotDeathRemovesBotAndLeavesCorpse(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 5})
+	e.mu.Lock()
+	e.spawnBotInCellLocked(spatial.CellKey{})
+	var bot *Entity
+	for _, ent := range e.cells[spatial.CellKey{}].Entities {
+		if ent.Kind == KindBot {
+			bot = ent
+		}
+	}
+	bot.Pos, bot.Health = spatial.Vec2{X: 5, Z: 6}, 1
+	e.mu.Unlock()
+
+	ev, err := e.Attack("p1", AttackRequest{TargetID: bot.ID, Aim: spatial.Vec2{Z: 1}}, now)
+	if err != nil || !ev.Killed {
+		t.Fatalf("expected the bot to die, got %+v, %v", ev, err)
+	}
+	e.mu.RLock()
+	_, tracked := e.bots[bot.ID]
+	_, inCell := e.cells[spatial.CellKey{}].Entities[bot.ID]
+	e.mu.RUnlock()
+	if tracked || inCell {
+		t.Fatalf("dead bot still in the world (tracked=%v inCell=%v)", tracked, inCell)
+	}
+	if c, ok := e.Corpse("corpse-1"); !ok || c.OwnerID != "" {
+		t.Fatalf("bot corpse = %+v, %v", c, ok)
+	}
+}
+
+func TestDeathRestoredAfterRelog(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 28, Z: 0})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 28, Z: 1})
+	setHealth(e, "p2", 1)
+	if _, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, now); err != nil {
+		t.Fatal(err)
+	}
+	e.mu.Lock()
+	st, err := SerializePlayerData(e.players["p2"])
+	e.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Logging back in on a fresh engine at the persisted death position.
+	e2 := newDeathTestEngine(&now)
+	e2.DevSpawn("p2", "Bob", spatial.Vec2{X: 28, Z: 1})
+	if err := e2.RestorePlayerState("p2", st, e2.GetPlayerManager().GetAllItemTemplates()); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(5 * time.Second)
+	e2.Step(time.Millisecond)
+	p2, _ := e2.GetPlayer("p2")
+	if !p2.Dead || p2.Health != 0 {
+		t.Fatalf("expected the player to still be dead, got dead=%v health=%v", p2.Dead, p2.Health)
+	}
+	now = now.Add(5 * time.Second)
+	e2.Step(time.Millisecond)
+	p2, _ = e2.GetPlayer("p2")
+	if p2.Dead || p2.Pos != (spatial.Vec2{X: 30}) || p2.Health != p2.MaxHealth {
+		t.Fatalf("expected a respawn at the east spawn, got dead=%v pos=%v health=%v", p2.Dead, p2.Pos, p2.Health)
+	}
+}
+
+func TestNearestSpawn(t *testing.T) {
+	w := &World{SpawnPoints: []SpawnPoint{
+		{Name: "a", Pos: spatial.Vec2{X: -10}},
+		{Name: "b", Pos: spatial.Vec2{X: 10}},
+	}}
+	cases := []struct {
+		pos  spatial.Vec2
+		want string
+	}{
+		{spatial.Vec2{X: -3}, "a"},
+		{spatial.Vec2{X: 3, Z: 50}, "b"},
+		{spatial.Vec2{}, "a"}, // tie goes to the first listed
+	}
+	for _, tc := range cases {
+		if got := w.NearestSpawn(tc.pos).Name; got != tc.want {
+			t.Fatalf("NearestSpawn(%v) = %s, want %s", tc.pos, got, tc.want)
+		}
+	}
+}
+
+func TestLoadWorld(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "world.json")
+	if err := os.WriteFile(good, []byte(`{"spawn_points": [{"name": "village", "pos": {"x": 12, "z": -4}}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := LoadWorld(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.SpawnPoints) != 1 || w.SpawnPoints[0] != (SpawnPoint{Name: "village", Pos: spatial.Vec2{X: 12, Z: -4}}) {
+		t.Fatalf("spawn points = %+v", w.SpawnPoints)
+	}
+
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte(`{"spawn_points": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWorld(empty); err == nil {
+		t.Fatalf("expected a world without spawn points to be rejected")
+	}
+}
@@ -0,0 +1,182 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrItemBroken       = errors.New("item is broken")
+	ErrNotRepairable    = errors.New("item cannot be repaired")
+	ErrNotDamaged       = errors.New("item is not damaged")
+	ErrMissingMaterials = errors.New("not enough repair materials")
+)
+
+// Durability lost per use.
+const (
+	weaponWearPerAttack = 0.01 // main hand weapon, per resolved attack
+	armorWearPerHit     = 0.01 // each equipped armor piece, per hit taken
+)
+
+// RepairCost is the material a template is repaired with. Quantity units
+// restore an item from broken to full; partial repairs cost proportionally
+// less, but never nothing.
+type RepairCost struct {
+	Material ItemTemplateID `json:"material"`
+	Quantity int            `json:"quantity"`
+}
+
+// For returns the material needed to restore an item at durability to full.
+func (c RepairCost) For(durability float64) int {
+	return max(1, int(math.Ceil((1-durability)*float64(c.Quantity)-1e-9)))
+}
+
+// Broken reports whether the item has worn out. Broken items neither
+// contribute stats nor can be equipped until repaired.
+func (i ItemInstance) Broken() bool { return i.Durability <= 0 }
+
+// wear takes amount off an equipped item's durability and reports whether it
+// changed. Broken items wear no further.
+func (item *EquippedItem) wear(amount float64) bool {
+	if item == nil || item.Instance.InstanceID == "" || item.Instance.Broken() {
+		return false
+	}
+	item.Instance.Durability = math.Max(0, item.Instance.Durability-amount)
+	return true
+}
+
+// wearWeaponLocked wears the player's main hand weapon after an attack.
+// e.mu must be held by caller.
+func (e *Engine) wearWeaponLocked(p *Player) {
+	if p.Equipment != nil && p.Equipment.GetSlot(SlotMainHand).wear(weaponWearPerAttack) {
+		p.EquipmentVersion++
+	}
+}
+
+// wearArmorLocked wears every equipped item that provides armor after the
+// player is hit. e.mu must be held by caller.
+func (e *Engine) wearArmorLocked(p *Player) {
+	if p.Equipment == nil {
+		return
+	}
+	worn := false
+	for _, item := range p.Equipment.Slots {
+		if item == nil {
+			continue
+		}
+		if tmpl, ok := e.playerMgr.GetItemTemplate(item.Instance.TemplateID); ok && len(tmpl.Armor) > 0 {
+			worn = item.wear(armorWearPerHit) || worn
+		}
+	}
+	if worn {
+		p.EquipmentVersion++
+	}
+}
+
+// RepairItem restores an equipped or carried item to full durability,
+// consuming the template's repair material from the player's inventory. It
+// returns the amount of material used.
+func (pm *PlayerManager) RepairItem(player *Player, instanceID ItemInstanceID) (int, error) {
+	var instance *ItemInstance
+	equipped := false
+	if player.Equipment != nil {
+		for _, item := range player.Equipment.Slots {
+			if item != nil && item.Instance.InstanceID == instanceID {
+				instance, equipped = &item.Instance, true
+				break
+			}
+		}
+	}
+	if instance == nil && player.Inventory != nil {
+		if idx := player.Inventory.FindItem(instanceID); idx >= 0 {
+			instance = &player.Inventory.Items[idx].Instance
+		}
+	}
+	if instance == nil {
+		return 0, ErrItemNotFound
+	}
+
+	template, exists := pm.GetItemTemplate(instance.TemplateID)
+	if !exists {
+		return 0, fmt.Errorf("unknown item template: %s", instance.TemplateID)
+	}
+	if template.Repair == nil {
+		return 0, ErrNotRepairable
+	}
+	if instance.Durability >= 1 {
+		return 0, ErrNotDamaged
+	}
+	cost := template.Repair.For(instance.Durability)
+	if pm.countMaterial(player, template.Repair.Material) < cost {
+		return 0, ErrMissingMaterials
+	}
+
+	// Taking material out may move inventory items, so repair first.
+	instance.Durability = 1
+	pm.consumeMaterial(player, template.Repair.Material, cost)
+	player.InventoryVersion++
+	if equipped {
+		player.EquipmentVersion++
+	}
+	return cost, nil
+}
+
+// countMaterial returns how many units of a template the player carries.
+func (pm *PlayerManager) countMaterial(player *Player, material ItemTemplateID) int {
+	if player.Inventory == nil {
+		return 0
+	}
+	n := 0
+	for _, item := range player.Inventory.Items {
+		if item.Instance.TemplateID == material {
+			n += item.Instance.Quantity
+		}
+	}
+	return n
+}
+
+// consumeMaterial takes quantity units of a template out of the player's
+// inventory, removing stacks that run out. Callers check countMaterial first.
+func (pm *PlayerManager) consumeMaterial(player *Player, material ItemTemplateID, quantity int) {
+	var emptied []ItemInstanceID
+	for i := range player.Inventory.Items {
+		if quantity == 0 {
+			break
+		}
+		inst := &player.Inventory.Items[i].Instance
+		if inst.TemplateID != material {
+			continue
+		}
+		take := min(quantity, inst.Quantity)
+		inst.Quantity -= take
+		quantity -= take
+		if inst.Quantity == 0 {
+			emptied = append(emptied, inst.InstanceID)
+		}
+	}
+	for _, id := range emptied {
+		_ = player.Inventory.RemoveItem(id)
+	}
+}
+
+// RepairItem repairs a player's item; see PlayerManager.RepairItem. Repairs
+// train crafting by the materials they consume.
+func (e *Engine) RepairItem(playerID string, instanceID ItemInstanceID) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return 0, fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return 0, ErrDead
+	}
+	used, err := e.playerMgr.RepairItem(player, instanceID)
+	if err != nil {
+		return 0, err
+	}
+	e.playerMgr.GainSkillXP(player, "crafting", float64(used)*xpRepairMaterial)
+	return used, nil
+}
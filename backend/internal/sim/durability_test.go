@@ -0,0 +1,142 @@
+package sim
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func setDurability(e *Engine, id string, slot SlotID, durability float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.players[id].Equipment.GetSlot(slot).Instance.Durability = durability
+}
+
+func TestRepairCostFor(t *testing.T) {
+	c := RepairCost{Material: "ingot_iron", Quantity: 4}
+	for _, tc := range []struct {
+		durability float64
+		want       int
+	}{{0, 4}, {0.5, 2}, {0.74, 2}, {0.99, 1}} {
+		if got := c.For(tc.durability); got != tc.want {
+			t.Fatalf("For(%v) = %d, want %d", tc.durability, got, tc.want)
+		}
+	}
+}
+
+func TestAttacksWearWeaponAndArmor(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 2})
+	equip(t, e, "p1", "sword_iron", SlotMainHand)
+	equip(t, e, "p2", "armor_leather", SlotChest)
+	p1, _ := e.GetPlayer("p1")
+	p2, _ := e.GetPlayer("p2")
+	v1, v2 := p1.EquipmentVersion, p2.EquipmentVersion
+
+	if _, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	p1, _ = e.GetPlayer("p1")
+	p2, _ = e.GetPlayer("p2")
+	if got := p1.Equipment.GetSlot(SlotMainHand).Instance.Durability; math.Abs(got-(1-weaponWearPerAttack)) > 1e-9 || p1.EquipmentVersion == v1 {
+		t.Fatalf("sword durability = %v (version %d -> %d)", got, v1, p1.EquipmentVersion)
+	}
+	if got := p2.Equipment.GetSlot(SlotChest).Instance.Durability; math.Abs(got-(1-armorWearPerHit)) > 1e-9 || p2.EquipmentVersion == v2 {
+		t.Fatalf("armor durability = %v (version %d -> %d)", got, v2, p2.EquipmentVersion)
+	}
+}
+
+func TestBrokenItemsStopWorking(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 2})
+	equip(t, e, "p1", "sword_iron", SlotMainHand)
+	equip(t, e, "p2", "armor_leather", SlotChest)
+	setDurability(e, "p1", SlotMainHand, 0)
+	setDurability(e, "p2", SlotChest, 0)
+	e.mu.Lock()
+	e.players["p1"].EquipmentVersion++
+	e.players["p2"].EquipmentVersion++
+	e.mu.Unlock()
+
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.DamageType != DamageBlunt || ev.Damage != unarmedProfile.Damage {
+		t.Fatalf("a broken sword should attack as unarmed through broken armor, got %+v", ev)
+	}
+	p2, _ := e.GetPlayer("p2")
+	if p2.MaxHealth != baseMaxHealth+5*2 || len(p2.Stats.Armor) != 0 {
+		t.Fatalf("broken armor still counts: max health %v, armor %v", p2.MaxHealth, p2.Stats.Armor)
+	}
+
+	if err := e.UnequipItem("p2", SlotChest, CompartmentBackpack, time.Now().Add(EquipCooldown)); err != nil {
+		t.Fatal(err)
+	}
+	p2, _ = e.GetPlayer("p2")
+	armor := p2.Inventory.GetCompartmentContents(CompartmentBackpack)[0].Instance
+	if err := e.EquipItem("p2", armor.InstanceID, SlotChest, time.Now().Add(2*EquipCooldown)); !errors.Is(err, ErrItemBroken) {
+		t.Fatalf("equipping broken armor: got %v, want ErrItemBroken", err)
+	}
+}
+
+func TestRepairItem(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	equip(t, e, "p1", "sword_iron", SlotMainHand)
+	p, _ := e.GetPlayer("p1")
+	sword := p.Equipment.GetSlot(SlotMainHand).Instance.InstanceID
+
+	if _, err := e.RepairItem("p1", sword); !errors.Is(err, ErrNotDamaged) {
+		t.Fatalf("repairing an undamaged sword: got %v, want ErrNotDamaged", err)
+	}
+	setDurability(e, "p1", SlotMainHand, 0.5)
+	if _, err := e.RepairItem("p1", sword); !errors.Is(err, ErrMissingMaterials) {
+		t.Fatalf("repairing without ingots: got %v, want ErrMissingMaterials", err)
+	}
+	if err := e.DevAddItemToPlayer("p1", "ingot_iron", 1, CompartmentCraftBag); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DevAddItemToPlayer("p1", "ingot_iron", 2, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RepairItem("p1", "ghost"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("repairing a missing item: got %v, want ErrItemNotFound", err)
+	}
+	p, _ = e.GetPlayer("p1")
+	if _, err := e.RepairItem("p1", p.Inventory.Items[0].Instance.InstanceID); !errors.Is(err, ErrNotRepairable) {
+		t.Fatalf("repairing an ingot: got %v, want ErrNotRepairable", err)
+	}
+
+	inv, eq := p.InventoryVersion, p.EquipmentVersion
+	used, err := e.RepairItem("p1", sword)
+	if err != nil || used != 2 {
+		t.Fatalf("repair used %d, %v; want 2 ingots", used, err)
+	}
+	p, _ = e.GetPlayer("p1")
+	if got := p.Equipment.GetSlot(SlotMainHand).Instance.Durability; got != 1 {
+		t.Fatalf("durability after repair = %v", got)
+	}
+	if p.InventoryVersion == inv || p.EquipmentVersion == eq {
+		t.Fatalf("repair must bump inventory and equipment versions")
+	}
+	if p.SkillXP["crafting"] != 2*xpRepairMaterial {
+		t.Fatalf("repairing should train crafting, xp = %v", p.SkillXP)
+	}
+	left := 0
+	for _, item := range p.Inventory.Items {
+		if item.Instance.TemplateID == "ingot_iron" {
+			left += item.Instance.Quantity
+		}
+	}
+	if left != 1 || len(p.Inventory.Items) != 1 {
+		t.Fatalf("expected 1 ingot left in a single stack, got %d in %+v", left, p.Inventory.Items)
+	}
+}
@@ -38,6 +38,23 @@ type Engine struct {
 	densityAcc time.Duration
 	// ids
 	botSeq int64
+	// latest published snapshot shared by all sessions (see snapshot.go)
+	latestSnap atomic.Pointer[WorldSnapshot]
+	// combat events, numbered by combatSeq and published with snapshots (see combat.go)
+	combatSeq uint64
+	combatLog []CombatEvent
+	// death and respawn (see death.go)
+	world     *World
+	death     DeathConfig
+	corpses   map[string]*corpse
+	corpseSeq int64
+	clock     func() time.Time // wall clock for respawn and corpse timers; replaced in tests
+	// items lying in the world (see ground.go)
+	ground      GroundConfig
+	groundItems map[string]*groundItem
+	// server clock: ticks simulated so far and the monotonic origin of server time
+	tickCount atomic.Uint64
+	epoch     time.Time
 	// metrics (atomic)
 	met struct {
 		handovers   int64 // count of player handovers
@@ -49,16 +66,35 @@ type Engine struct {
 func NewEngine(cfg Config) *Engine {
 	playerMgr := NewPlayerManager()
 	playerMgr.CreateTestItemTemplates() // Initialize with test items
+	skillCurves := cfg.SkillCurves
+	if skillCurves == nil {
+		skillCurves = DefaultSkillCurves()
+	}
+	for skill, curve := range skillCurves {
+		playerMgr.RegisterSkillCurve(skill, curve)
+	}
+	playerMgr.inventoryGrids = cfg.InventoryGrids
+	world := cfg.World
+	if world == nil {
+		world = DefaultWorld()
+	}
 
 	return &Engine{
-		cfg:       cfg,
-		cells:     make(map[spatial.CellKey]*CellInstance),
-		players:   make(map[string]*Player),
-		bots:      make(map[string]*botState),
-		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
-		stopCh:    make(chan struct{}),
-		stoppedCh: make(chan struct{}),
-		playerMgr: playerMgr,
+		cfg:         cfg,
+		cells:       make(map[spatial.CellKey]*CellInstance),
+		players:     make(map[string]*Player),
+		bots:        make(map[string]*botState),
+		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		stopCh:      make(chan struct{}),
+		stoppedCh:   make(chan struct{}),
+		playerMgr:   playerMgr,
+		world:       world,
+		death:       cfg.Death.withDefaults(),
+		corpses:     make(map[string]*corpse),
+		ground:      cfg.Ground.withDefaults(),
+		groundItems: make(map[string]*groundItem),
+		clock:       time.Now,
+		epoch:       time.Now(),
 	}
 }
 
@@ -109,10 +145,24 @@ func (e *Engine) loop() {
 func (e *Engine) tick(dt time.Duration) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	e.tickCount.Add(1)
 	// Integrate very simple kinematics for players.
+	now := e.clock()
+	e.updateDeathsLocked(now)
+	e.updateGroundItemsLocked(now)
 	for _, p := range e.players {
+		if p.Dead {
+			continue
+		}
 		p.Pos.X += p.Vel.X * dt.Seconds()
 		p.Pos.Z += p.Vel.Z * dt.Seconds()
+		// players face where they move and keep their facing when they stop
+		if p.Vel != (spatial.Vec2{}) {
+			p.Yaw = math.Atan2(p.Vel.X, p.Vel.Z)
+		}
+		p.expireBuffs(now)
+		e.playerMgr.RefreshStats(p)
+		p.regenerate(dt)
 	}
 	// Update bots using two-phase approach: compute velocities from neighbor snapshot, then integrate.
 	for ck, cell := range e.cells {
@@ -145,6 +195,7 @@ func (e *Engine) tick(dt time.Duration) {
 			}
 			ent.Pos.X += ent.Vel.X * dt.Seconds()
 			ent.Pos.Z += ent.Vel.Z * dt.Seconds()
+			regenerateBot(ent, dt)
 			if st, ok := e.bots[ent.ID]; ok {
 				e.constrainBotWithinCell(ent, st)
 			}
@@ -163,9 +214,10 @@ func (e *Engine) tick(dt time.Duration) {
 }
 
 func (e *Engine) snapshot() {
-	// For MVP skeleton, just log entity counts per cell.
 	e.mu.RLock()
 	defer e.mu.RUnlock()
+	// Publish one immutable snapshot per tick for all sessions to share.
+	e.publishSnapshotLocked(time.Now())
 	total := len(e.players)
 	if total == 0 {
 		return
@@ -254,7 +306,7 @@ func (e *Engine) spawnBotInCellLocked(k spatial.CellKey) bool {
 	x0 := float64(k.Cx) * e.cfg.CellSize
 	z0 := float64(k.Cz) * e.cfg.CellSize
 	pos := spatial.Vec2{X: x0 + e.rng.Float64()*e.cfg.CellSize, Z: z0 + e.rng.Float64()*e.cfg.CellSize}
-	ent := &Entity{ID: id, Kind: KindBot, Pos: pos, Name: id}
+	ent := &Entity{ID: id, Kind: KindBot, Pos: pos, Name: id, Health: botMaxHealth, MaxHealth: botMaxHealth}
 	c.Entities[id] = ent
 	// initial state
 	st := &botState{OwnedCell: k}
@@ -368,6 +420,19 @@ func (e *Engine) moveEntityLocked(p *Player, from, to spatial.CellKey) {
 	nc.Entities[p.ID] = &p.Entity
 }
 
+// relocatePlayerLocked moves a player to pos at once, e.g. on respawn or
+// teleport, switching cells without handover hysteresis. e.mu must be held by caller.
+func (e *Engine) relocatePlayerLocked(p *Player, pos spatial.Vec2, now time.Time) {
+	p.Pos, p.Vel = pos, spatial.Vec2{}
+	cx, cz := spatial.WorldToCell(pos.X, pos.Z, e.cfg.CellSize)
+	if key := (spatial.CellKey{Cx: cx, Cz: cz}); key != p.OwnedCell {
+		e.moveEntityLocked(p, p.OwnedCell, key)
+		p.PrevCell = p.OwnedCell
+		p.OwnedCell = key
+		p.HandoverAt = now
+	}
+}
+
 // Step advances the simulation by dt. Exposed for tests and headless driving.
 func (e *Engine) Step(dt time.Duration) {
 	e.tick(dt)
@@ -404,11 +469,8 @@ func (e *Engine) DevAddItemToPlayer(playerID string, templateID ItemTemplateID,
 		return fmt.Errorf("player %s not found", playerID)
 	}
 
-	// Generate unique instance ID
-	instanceID := ItemInstanceID(fmt.Sprintf("%s_%d_%d", templateID, quantity, time.Now().UnixNano()))
-
 	instance := ItemInstance{
-		InstanceID: instanceID,
+		InstanceID: NewItemInstanceID(templateID),
 		TemplateID: templateID,
 		Quantity:   quantity,
 		Durability: 1.0,
@@ -456,8 +518,15 @@ func (e *Engine) EquipItem(playerID string, instanceID ItemInstanceID, slot Slot
 	if !ok {
 		return fmt.Errorf("player %s not found", playerID)
 	}
+	if player.Dead {
+		return ErrDead
+	}
 
-	return e.playerMgr.EquipItem(player, instanceID, slot, now)
+	if err := e.playerMgr.EquipItem(player, instanceID, slot, now); err != nil {
+		return err
+	}
+	e.trainEquipLocked(player, player.Equipment.GetSlot(slot).Instance.TemplateID)
+	return nil
 }
 
 // UnequipItem unequips an item for a player
@@ -469,10 +538,61 @@ func (e *Engine) UnequipItem(playerID string, slot SlotID, compartment Compartme
 	if !ok {
 		return fmt.Errorf("player %s not found", playerID)
 	}
+	if player.Dead {
+		return ErrDead
+	}
 
 	return e.playerMgr.UnequipItem(player, slot, compartment, now)
 }
 
+// MoveItem moves an item between a player's inventory compartments
+func (e *Engine) MoveItem(playerID string, instanceID ItemInstanceID, compartment CompartmentType, quantity int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return ErrDead
+	}
+
+	return e.playerMgr.MoveItem(player, instanceID, compartment, quantity)
+}
+
+// PlaceItem moves an item within a player's inventory grid
+func (e *Engine) PlaceItem(playerID string, instanceID ItemInstanceID, pos GridPos) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return ErrDead
+	}
+
+	return e.playerMgr.PlaceItem(player, instanceID, pos)
+}
+
+// SortInventory auto-sorts a grid compartment of a player's inventory
+func (e *Engine) SortInventory(playerID string, compartment CompartmentType) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return ErrDead
+	}
+
+	return e.playerMgr.SortInventory(player, compartment)
+}
+
 // DevList returns a snapshot list of current players (dev-only helper).
 func (e *Engine) DevList() []Player {
 	e.mu.RLock()
@@ -500,6 +620,13 @@ func (e *Engine) DevListAllEntities() []Entity {
 // GetConfig returns a copy of the engine's config.
 func (e *Engine) GetConfig() Config { return e.cfg }
 
+// CurrentTick returns the number of simulation ticks executed so far.
+func (e *Engine) CurrentTick() uint64 { return e.tickCount.Load() }
+
+// ServerTime returns monotonic time elapsed since the engine was created. It is
+// unaffected by wall clock adjustments, so clients can use it for clock sync.
+func (e *Engine) ServerTime() time.Duration { return time.Since(e.epoch) }
+
 // QueryAOI returns a snapshot list of entities within radius of the given position.
 // The result excludes the entity with id == excludeID (typically the querying player).
 func (e *Engine) QueryAOI(pos spatial.Vec2, radius float64, excludeID string) []Entity {
@@ -588,6 +715,15 @@ func (e *Engine) RequestPlayerDisconnectPersist(ctx context.Context, playerID st
 	}
 }
 
+// PersistPlayerNow synchronously saves a player's data, bypassing the persistence queues.
+// It returns false if no store is configured or the save failed.
+func (e *Engine) PersistPlayerNow(ctx context.Context, playerID string) bool {
+	if e.persistMgr == nil {
+		return false
+	}
+	return e.persistMgr.persistPlayerSync(ctx, playerID)
+}
+
 // RestorePlayerState applies persistent state to an existing player record
 func (e *Engine) RestorePlayerState(playerID string, persistedState state.PlayerState, templates map[ItemTemplateID]*ItemTemplate) error {
 	e.mu.Lock()
@@ -599,7 +735,14 @@ func (e *Engine) RestorePlayerState(playerID string, persistedState state.Player
 	}
 
 	// Apply persistent state to the authoritative player record
-	return DeserializePlayerData(persistedState, player, templates)
+	if err := DeserializePlayerData(persistedState, player, templates); err != nil {
+		return err
+	}
+	if player.Inventory != nil {
+		e.playerMgr.applyInventoryGrids(player.Inventory)
+	}
+	e.playerMgr.RefreshStats(player)
+	return nil
 }
 
 // GetAllConnectedPlayerIDs returns IDs of all currently connected players
@@ -0,0 +1,259 @@
+package sim
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+var (
+	ErrNoGrid           = errors.New("compartment has no grid")
+	ErrInvalidPlacement = errors.New("item does not fit at that position")
+)
+
+// GridSize is the width and height of a compartment grid in cells.
+type GridSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// GridPos is where an item sits in its compartment's grid: the cell of its
+// top-left corner, and whether its footprint is turned on its side.
+type GridPos struct {
+	X       int  `json:"x"`
+	Y       int  `json:"y"`
+	Rotated bool `json:"rotated,omitempty"`
+}
+
+// Footprint is the number of grid cells an item covers.
+type Footprint struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Footprint derives the grid cells an item covers from its Bulk: as close to
+// square as possible, taller than wide. A stack covers the footprint of one
+// unit.
+func (t *ItemTemplate) Footprint() Footprint {
+	bulk := max(1, t.Bulk)
+	w := int(math.Sqrt(float64(bulk)))
+	return Footprint{W: w, H: (bulk + w - 1) / w}
+}
+
+// at returns the footprint as placed, swapping its sides when rotated.
+func (f Footprint) at(pos GridPos) Footprint {
+	if pos.Rotated {
+		return Footprint{W: f.H, H: f.W}
+	}
+	return f
+}
+
+// gridOccupancy marks the cells of one compartment grid taken by items.
+type gridOccupancy struct {
+	size  GridSize
+	taken []bool
+}
+
+func (g *gridOccupancy) fits(fp Footprint, pos GridPos) bool {
+	fp = fp.at(pos)
+	if pos.X < 0 || pos.Y < 0 || pos.X+fp.W > g.size.W || pos.Y+fp.H > g.size.H {
+		return false
+	}
+	for y := pos.Y; y < pos.Y+fp.H; y++ {
+		for x := pos.X; x < pos.X+fp.W; x++ {
+			if g.taken[y*g.size.W+x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g *gridOccupancy) mark(fp Footprint, pos GridPos) {
+	fp = fp.at(pos)
+	for y := pos.Y; y < pos.Y+fp.H; y++ {
+		for x := pos.X; x < pos.X+fp.W; x++ {
+			g.taken[y*g.size.W+x] = true
+		}
+	}
+}
+
+// firstFit returns the first free position scanning rows top to bottom,
+// trying each cell upright before rotated.
+func (g *gridOccupancy) firstFit(fp Footprint) (GridPos, bool) {
+	for y := 0; y < g.size.H; y++ {
+		for x := 0; x < g.size.W; x++ {
+			for _, rotated := range []bool{false, true} {
+				if rotated && fp.W == fp.H {
+					continue
+				}
+				if pos := (GridPos{X: x, Y: y, Rotated: rotated}); g.fits(fp, pos) {
+					return pos, true
+				}
+			}
+		}
+	}
+	return GridPos{}, false
+}
+
+// HasGrid reports whether items in the compartment have grid positions.
+func (inv *Inventory) HasGrid(compartment CompartmentType) bool {
+	_, ok := inv.Grids[compartment]
+	return ok
+}
+
+// occupancy returns the cells of a compartment's grid taken by its placed
+// items other than ignore.
+func (inv *Inventory) occupancy(compartment CompartmentType, ignore ItemInstanceID) *gridOccupancy {
+	size := inv.Grids[compartment]
+	g := &gridOccupancy{size: size, taken: make([]bool, size.W*size.H)}
+	for i := range inv.Items {
+		item := &inv.Items[i]
+		if item.Compartment != compartment || item.Slot == nil || item.Instance.InstanceID == ignore {
+			continue
+		}
+		if template := inv.resolveTemplate(item, nil); template != nil {
+			g.mark(template.Footprint(), *item.Slot)
+		}
+	}
+	return g
+}
+
+// hasRoomFor reports whether n new items of the footprint can be placed in
+// the compartment. Compartments without a grid always have room.
+func (inv *Inventory) hasRoomFor(compartment CompartmentType, fp Footprint, n int) bool {
+	if !inv.HasGrid(compartment) {
+		return true
+	}
+	g := inv.occupancy(compartment, "")
+	for range n {
+		pos, ok := g.firstFit(fp)
+		if !ok {
+			return false
+		}
+		g.mark(fp, pos)
+	}
+	return true
+}
+
+// freeSlot returns the first free position for a new item of the template in
+// the compartment's grid, or nil if the compartment has no grid. It fails with
+// ErrInsufficientSpace when no position fits, so no item in a grid is ever
+// left without one.
+func (inv *Inventory) freeSlot(compartment CompartmentType, template *ItemTemplate) (*GridPos, error) {
+	if !inv.HasGrid(compartment) {
+		return nil, nil
+	}
+	if template == nil {
+		return nil, errors.New("item template required for grid placement")
+	}
+	pos, ok := inv.occupancy(compartment, "").firstFit(template.Footprint())
+	if !ok {
+		return nil, ErrInsufficientSpace
+	}
+	return &pos, nil
+}
+
+// EnableGrid lays a compartment out as a grid of the given size, placing the
+// items already in it. Nothing changes if they do not all fit.
+func (inv *Inventory) EnableGrid(compartment CompartmentType, size GridSize) error {
+	if _, known := inv.CompartmentCaps[compartment]; !known || size.W <= 0 || size.H <= 0 {
+		return ErrBadCompartment
+	}
+	grids := maps.Clone(inv.Grids)
+	if grids == nil {
+		grids = make(map[CompartmentType]GridSize)
+	}
+	grids[compartment] = size
+	old := inv.Grids
+	inv.Grids = grids
+	if err := inv.SortCompartment(compartment); err != nil {
+		inv.Grids = old
+		return err
+	}
+	return nil
+}
+
+// DisableGrid makes a grid compartment unordered again, dropping the
+// positions of the items in it.
+func (inv *Inventory) DisableGrid(compartment CompartmentType) {
+	if !inv.HasGrid(compartment) {
+		return
+	}
+	grids := maps.Clone(inv.Grids)
+	delete(grids, compartment)
+	inv.Grids = grids
+	for i := range inv.Items {
+		if inv.Items[i].Compartment == compartment {
+			inv.Items[i].Slot = nil
+		}
+	}
+}
+
+// PlaceItem moves an item to a position in its compartment's grid.
+func (inv *Inventory) PlaceItem(instanceID ItemInstanceID, pos GridPos) error {
+	idx := inv.FindItem(instanceID)
+	if idx < 0 {
+		return ErrItemNotFound
+	}
+	item := &inv.Items[idx]
+	if !inv.HasGrid(item.Compartment) {
+		return ErrNoGrid
+	}
+	template := inv.resolveTemplate(item, nil)
+	if template == nil {
+		return fmt.Errorf("unknown item template: %s", item.Instance.TemplateID)
+	}
+	if !inv.occupancy(item.Compartment, instanceID).fits(template.Footprint(), pos) {
+		return ErrInvalidPlacement
+	}
+	item.Slot = &pos
+	return nil
+}
+
+// SortCompartment re-packs a grid compartment: largest footprints first, then
+// by template and instance ID, each at the first free position. The layout is
+// kept if the items would not all fit.
+func (inv *Inventory) SortCompartment(compartment CompartmentType) error {
+	if !inv.HasGrid(compartment) {
+		return ErrNoGrid
+	}
+	var order []int
+	for i := range inv.Items {
+		if inv.Items[i].Compartment == compartment {
+			if inv.resolveTemplate(&inv.Items[i], nil) == nil {
+				return fmt.Errorf("unknown item template: %s", inv.Items[i].Instance.TemplateID)
+			}
+			order = append(order, i)
+		}
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		ia, ib := &inv.Items[a], &inv.Items[b]
+		fa, fb := ia.template.Footprint(), ib.template.Footprint()
+		return cmp.Or(
+			cmp.Compare(fb.W*fb.H, fa.W*fa.H),
+			cmp.Compare(ia.Instance.TemplateID, ib.Instance.TemplateID),
+			cmp.Compare(ia.Instance.InstanceID, ib.Instance.InstanceID),
+		)
+	})
+
+	size := inv.Grids[compartment]
+	g := &gridOccupancy{size: size, taken: make([]bool, size.W*size.H)}
+	layout := make([]GridPos, len(order))
+	for n, i := range order {
+		fp := inv.Items[i].template.Footprint()
+		pos, ok := g.firstFit(fp)
+		if !ok {
+			return ErrInsufficientSpace
+		}
+		g.mark(fp, pos)
+		layout[n] = pos
+	}
+	for n, i := range order {
+		inv.Items[i].Slot = &layout[n]
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package sim
+
+import (
+	"errors"
+	"testing"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func newGridPlayer(t *testing.T, size GridSize) (*PlayerManager, *Player) {
+	t.Helper()
+	pm := NewPlayerManager()
+	pm.CreateTestItemTemplates()
+	pm.inventoryGrids = map[CompartmentType]GridSize{CompartmentBackpack: size}
+	p := &Player{}
+	pm.InitializePlayer(p)
+	return pm, p
+}
+
+func addTo(t *testing.T, pm *PlayerManager, p *Player, id ItemInstanceID, tmpl ItemTemplateID, qty int) {
+	t.Helper()
+	if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: id, TemplateID: tmpl, Quantity: qty, Durability: 1}, CompartmentBackpack); err != nil {
+		t.Fatalf("add %s: %v", id, err)
+	}
+}
+
+func slotOf(p *Player, id ItemInstanceID) GridPos {
+	if idx := p.Inventory.FindItem(id); idx >= 0 && p.Inventory.Items[idx].Slot != nil {
+		return *p.Inventory.Items[idx].Slot
+	}
+	return GridPos{X: -1, Y: -1}
+}
+
+func TestFootprintFromBulk(t *testing.T) {
+	cases := map[int]Footprint{0: {1, 1}, 1: {1, 1}, 2: {1, 2}, 3: {1, 3}, 4: {2, 2}, 5: {2, 3}, 25: {5, 5}}
+	for bulk, want := range cases {
+		if got := (&ItemTemplate{Bulk: bulk}).Footprint(); got != want {
+			t.Errorf("Footprint(bulk %d) = %+v, want %+v", bulk, got, want)
+		}
+	}
+}
+
+func TestGridPlacesNewItemsAndRejectsOverflow(t *testing.T) {
+	pm, p := newGridPlayer(t, GridSize{W: 3, H: 3})
+
+	addTo(t, pm, p, "armor", "armor_leather", 1) // 2x2
+	addTo(t, pm, p, "sword", "sword_iron", 1)    // 1x2, right of the armor
+	addTo(t, pm, p, "rocks", "rock_small", 1)    // 1x1, below the armor
+	if got := slotOf(p, "armor"); got != (GridPos{}) {
+		t.Fatalf("armor at %+v, want the corner", got)
+	}
+	if got := slotOf(p, "sword"); got != (GridPos{X: 2}) {
+		t.Fatalf("sword at %+v, want x=2", got)
+	}
+	if got := slotOf(p, "rocks"); got != (GridPos{Y: 2}) {
+		t.Fatalf("rocks at %+v, want y=2", got)
+	}
+
+	// Merging into a placed stack needs no room; a new 1x3 stack does not fit.
+	addTo(t, pm, p, "more_rocks", "rock_small", 2)
+	if p.Inventory.HasItem("more_rocks") || p.Inventory.Items[p.Inventory.FindItem("rocks")].Instance.Quantity != 3 {
+		t.Fatalf("expected the rocks to merge, items=%+v", p.Inventory.Items)
+	}
+	shield := ItemInstance{InstanceID: "shield", TemplateID: "shield_wood", Quantity: 1, Durability: 1}
+	if err := pm.AddItemToInventory(p, shield, CompartmentBackpack); !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("adding a shield to a full grid: got %v, want ErrInsufficientSpace", err)
+	}
+	// An empty shield skips the stack count check but still needs a cell.
+	empty := ItemInstance{InstanceID: "empty", TemplateID: "shield_wood", Durability: 1}
+	if err := pm.AddItemToInventory(p, empty, CompartmentBackpack); !errors.Is(err, ErrInsufficientSpace) || p.Inventory.HasItem("empty") {
+		t.Fatalf("adding an empty shield to a full grid: got %v, want ErrInsufficientSpace", err)
+	}
+	// Unordered compartments are unaffected.
+	if err := pm.AddItemToInventory(p, shield, CompartmentCraftBag); err != nil {
+		t.Fatal(err)
+	}
+	if p.Inventory.Items[p.Inventory.FindItem("shield")].Slot != nil {
+		t.Fatalf("craft bag items should have no position")
+	}
+}
+
+func TestPlaceItemValidatesBoundsOverlapAndRotation(t *testing.T) {
+	pm, p := newGridPlayer(t, GridSize{W: 4, H: 2})
+	addTo(t, pm, p, "sword", "sword_iron", 1) // 1x2 at the corner
+	addTo(t, pm, p, "rocks", "rock_small", 1) // 1x1 at x=1
+
+	cases := []struct {
+		name string
+		pos  GridPos
+		want error
+	}{
+		{"off the grid", GridPos{X: 3, Y: 1}, ErrInvalidPlacement},
+		{"negative", GridPos{X: -1}, ErrInvalidPlacement},
+		{"over the rocks", GridPos{X: 1}, ErrInvalidPlacement},
+		{"rotated past the edge", GridPos{X: 3, Rotated: true}, ErrInvalidPlacement},
+		{"rotated into the free row", GridPos{X: 2, Y: 1, Rotated: true}, nil},
+		{"overlapping itself", GridPos{X: 2, Y: 0}, nil},
+	}
+	for _, tc := range cases {
+		if err := pm.PlaceItem(p, "sword", tc.pos); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	if got := slotOf(p, "sword"); got != (GridPos{X: 2}) {
+		t.Fatalf("sword at %+v", got)
+	}
+	if err := pm.PlaceItem(p, "missing", GridPos{}); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("missing item: got %v", err)
+	}
+	if err := pm.MoveItem(p, "rocks", CompartmentBelt, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.PlaceItem(p, "rocks", GridPos{}); !errors.Is(err, ErrNoGrid) {
+		t.Fatalf("placing in the belt: got %v, want ErrNoGrid", err)
+	}
+}
+
+func TestSortInventoryPacksLargestFirst(t *testing.T) {
+	pm, p := newGridPlayer(t, GridSize{W: 3, H: 3})
+	addTo(t, pm, p, "rocks", "rock_small", 1)
+	addTo(t, pm, p, "sword", "sword_iron", 1)
+	addTo(t, pm, p, "potion", "potion_health", 1)
+	if err := pm.PlaceItem(p, "rocks", GridPos{X: 1, Y: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// a 2x2 armor no longer fits anywhere until the grid is sorted
+	armor := ItemInstance{InstanceID: "armor", TemplateID: "armor_leather", Quantity: 1, Durability: 1}
+	if err := pm.AddItemToInventory(p, armor, CompartmentBackpack); !errors.Is(err, ErrInsufficientSpace) {
+		t.Fatalf("got %v, want ErrInsufficientSpace", err)
+	}
+
+	version := p.InventoryVersion
+	if err := pm.SortInventory(p, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if p.InventoryVersion != version+1 {
+		t.Fatalf("InventoryVersion not bumped")
+	}
+	want := map[ItemInstanceID]GridPos{"sword": {}, "potion": {X: 1}, "rocks": {X: 2}}
+	for id, pos := range want {
+		if got := slotOf(p, id); got != pos {
+			t.Fatalf("%s at %+v, want %+v", id, got, pos)
+		}
+	}
+	if err := pm.AddItemToInventory(p, armor, CompartmentBackpack); err != nil {
+		t.Fatalf("armor after sorting: %v", err)
+	}
+	if got := slotOf(p, "armor"); got != (GridPos{X: 1, Y: 1}) {
+		t.Fatalf("armor at %+v", got)
+	}
+	if err := pm.SortInventory(p, CompartmentBelt); !errors.Is(err, ErrNoGrid) {
+		t.Fatalf("sorting the belt: got %v, want ErrNoGrid", err)
+	}
+}
+
+func TestRemoveItemKeepsOrder(t *testing.T) {
+	pm := NewPlayerManager()
+	pm.CreateTestItemTemplates()
+	p := &Player{}
+	pm.InitializePlayer(p)
+	for _, id := range []ItemInstanceID{"a", "b", "c", "d"} {
+		addTo(t, pm, p, id, "sword_iron", 1)
+	}
+	if err := pm.RemoveItemFromInventory(p, "b"); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []ItemInstanceID{"a", "c", "d"} {
+		if p.Inventory.Items[i].Instance.InstanceID != id || p.Inventory.FindItem(id) != i {
+			t.Fatalf("items = %+v, want a, c, d in order", p.Inventory.Items)
+		}
+	}
+}
+
+func TestGridLayoutSurvivesPersistence(t *testing.T) {
+	pm, p := newGridPlayer(t, GridSize{W: 4, H: 4})
+	addTo(t, pm, p, "sword", "sword_iron", 1)
+	if err := pm.PlaceItem(p, "sword", GridPos{X: 1, Y: 3, Rotated: true}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := SerializePlayerData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &Player{}
+	if err := DeserializePlayerData(st, restored, pm.GetAllItemTemplates()); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Inventory.HasGrid(CompartmentBackpack) || slotOf(restored, "sword") != (GridPos{X: 1, Y: 3, Rotated: true}) {
+		t.Fatalf("restored grids=%+v sword at %+v", restored.Inventory.Grids, slotOf(restored, "sword"))
+	}
+}
+
+func TestRestoredGridsFollowTheConfig(t *testing.T) {
+	pm, p := newGridPlayer(t, GridSize{W: 4, H: 4})
+	addTo(t, pm, p, "sword", "sword_iron", 1)
+	if err := pm.PlaceItem(p, "sword", GridPos{X: 3, Y: 2}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := SerializePlayerData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restore := func(grids map[CompartmentType]GridSize) *Player {
+		e := NewEngine(Config{CellSize: 10, AOIRadius: 5, TickHz: 20, SnapshotHz: 10, InventoryGrids: grids})
+		e.AddOrUpdatePlayer("p1", "Alice", st.Pos, spatial.Vec2{})
+		if err := e.RestorePlayerState("p1", st, e.playerMgr.GetAllItemTemplates()); err != nil {
+			t.Fatal(err)
+		}
+		restored, _ := e.GetPlayer("p1")
+		return &restored
+	}
+
+	// A smaller grid re-sorts the saved layout into it.
+	restored := restore(map[CompartmentType]GridSize{CompartmentBackpack: {W: 2, H: 2}})
+	if restored.Inventory.Grids[CompartmentBackpack] != (GridSize{W: 2, H: 2}) || slotOf(restored, "sword") != (GridPos{}) {
+		t.Fatalf("grids=%+v sword at %+v", restored.Inventory.Grids, slotOf(restored, "sword"))
+	}
+	// One the items no longer fit in leaves the compartment unordered.
+	restored = restore(map[CompartmentType]GridSize{CompartmentBackpack: {W: 1, H: 1}})
+	if restored.Inventory.HasGrid(CompartmentBackpack) || restored.Inventory.Items[0].Slot != nil {
+		t.Fatalf("grids=%+v sword at %+v", restored.Inventory.Grids, restored.Inventory.Items[0].Slot)
+	}
+	// Grids dropped from the config are dropped from the inventory.
+	restored = restore(nil)
+	if restored.Inventory.HasGrid(CompartmentBackpack) || restored.Inventory.Items[0].Slot != nil {
+		t.Fatalf("grids=%+v sword at %+v", restored.Inventory.Grids, restored.Inventory.Items[0].Slot)
+	}
+}
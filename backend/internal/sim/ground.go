@@ -0,0 +1,263 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+	"prototype-game/backend/internal/state"
+)
+
+var ErrNotLootable = errors.New("item belongs to another player")
+
+// GroundConfig controls items lying in the world. Zero fields take the
+// defaults noted on each.
+type GroundConfig struct {
+	// PickupRange is how close a player must be to pick an item up; defaults
+	// to 3 meters.
+	PickupRange float64
+	// OwnerWindow is how long only the player who dropped an item may pick it
+	// up; defaults to 1 minute.
+	OwnerWindow time.Duration
+	// DespawnAfter is how long an item stays on the ground; defaults to 10
+	// minutes.
+	DespawnAfter time.Duration
+}
+
+func (c GroundConfig) withDefaults() GroundConfig {
+	if c.PickupRange <= 0 {
+		c.PickupRange = 3
+	}
+	if c.OwnerWindow <= 0 {
+		c.OwnerWindow = time.Minute
+	}
+	if c.DespawnAfter <= 0 {
+		c.DespawnAfter = 10 * time.Minute
+	}
+	return c
+}
+
+// groundItem is an item lying in the world. It is an entity of KindGroundItem
+// in its cell, so it shows up in AOI until it is picked up or despawns.
+type groundItem struct {
+	ent        *Entity
+	cell       spatial.CellKey
+	item       ItemInstance
+	ownerID    string    // may pick the item up before ownerUntil; empty for anyone
+	ownerUntil time.Time // looting rights end
+	expiresAt  time.Time
+}
+
+// GroundItem is a copy of a ground item's state, as persisted with the world.
+type GroundItem struct {
+	ID         string       `json:"id"`
+	Item       ItemInstance `json:"item"`
+	Pos        spatial.Vec2 `json:"pos"`
+	OwnerID    string       `json:"owner_id,omitempty"`
+	OwnerUntil time.Time    `json:"owner_until"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+}
+
+func (g *groundItem) copy() GroundItem {
+	return GroundItem{
+		ID:         g.ent.ID,
+		Item:       g.item,
+		Pos:        g.ent.Pos,
+		OwnerID:    g.ownerID,
+		OwnerUntil: g.ownerUntil,
+		ExpiresAt:  g.expiresAt,
+	}
+}
+
+// PickUp is the outcome of picking up a ground item.
+type PickUp struct {
+	Item   ItemInstance     // the item as it lay on the ground
+	Stacks []ItemInstanceID // inventory stacks that received its units
+}
+
+// lootableBy reports whether a player has the right to pick the item up.
+func (g *groundItem) lootableBy(playerID string, now time.Time) bool {
+	return g.ownerID == "" || g.ownerID == playerID || !now.Before(g.ownerUntil)
+}
+
+// GroundItem returns the ground item with the given entity id.
+func (e *Engine) GroundItem(id string) (GroundItem, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	g, ok := e.groundItems[id]
+	if !ok {
+		return GroundItem{}, false
+	}
+	return g.copy(), true
+}
+
+// DropItem takes quantity units of a carried item out of the player's
+// inventory and leaves them on the ground at the player's position; 0 drops
+// the whole stack. The player keeps looting rights for the owner window. It
+// returns the ground item's entity id.
+func (e *Engine) DropItem(playerID string, instanceID ItemInstanceID, quantity int, now time.Time) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok := e.players[playerID]
+	if !ok {
+		return "", fmt.Errorf("player %s not found", playerID)
+	}
+	if p.Dead {
+		return "", ErrDead
+	}
+	if p.Inventory == nil {
+		return "", ErrItemNotFound
+	}
+	idx := p.Inventory.FindItem(instanceID)
+	if idx < 0 {
+		return "", ErrItemNotFound
+	}
+	stack := &p.Inventory.Items[idx].Instance
+	if quantity == 0 {
+		quantity = stack.Quantity
+	}
+	if quantity < 0 || quantity > stack.Quantity {
+		return "", ErrInvalidQuantity
+	}
+
+	dropped := *stack
+	dropped.Quantity = quantity
+	if quantity == stack.Quantity {
+		_ = p.Inventory.RemoveItem(instanceID)
+	} else {
+		stack.Quantity -= quantity
+		dropped.InstanceID = NewItemInstanceID(dropped.TemplateID)
+	}
+	p.InventoryVersion++
+	g := e.spawnGroundItemLocked(dropped, p.Pos, p.ID, now.Add(e.ground.OwnerWindow), now.Add(e.ground.DespawnAfter))
+	return g.ent.ID, nil
+}
+
+// PickUpItem moves a ground item within reach into the player's inventory,
+// where its units may join existing stacks. Items another player still has
+// looting rights to cannot be taken.
+func (e *Engine) PickUpItem(playerID, groundID string, compartment CompartmentType, now time.Time) (PickUp, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, ok := e.players[playerID]
+	if !ok {
+		return PickUp{}, fmt.Errorf("player %s not found", playerID)
+	}
+	if p.Dead {
+		return PickUp{}, ErrDead
+	}
+	g, ok := e.groundItems[groundID]
+	if !ok {
+		return PickUp{}, ErrItemNotFound
+	}
+	if spatial.Dist2(p.Pos, g.ent.Pos) > e.ground.PickupRange*e.ground.PickupRange {
+		return PickUp{}, ErrOutOfRange
+	}
+	if !g.lootableBy(playerID, now) {
+		return PickUp{}, ErrNotLootable
+	}
+	if p.Inventory == nil {
+		return PickUp{}, fmt.Errorf("player %s has no inventory", playerID)
+	}
+	before := make(map[ItemInstanceID]int)
+	for _, item := range p.Inventory.GetCompartmentContents(compartment) {
+		before[item.Instance.InstanceID] = item.Instance.Quantity
+	}
+	if err := e.playerMgr.AddItemToInventory(p, g.item, compartment); err != nil {
+		return PickUp{}, err
+	}
+	e.removeGroundItemLocked(groundID)
+
+	res := PickUp{Item: g.item}
+	for _, item := range p.Inventory.GetCompartmentContents(compartment) {
+		if item.Instance.Quantity > before[item.Instance.InstanceID] {
+			res.Stacks = append(res.Stacks, item.Instance.InstanceID)
+		}
+	}
+	return res, nil
+}
+
+// spawnGroundItemLocked places an item on the ground. e.mu must be held by caller.
+func (e *Engine) spawnGroundItemLocked(item ItemInstance, pos spatial.Vec2, ownerID string, ownerUntil, expiresAt time.Time) *groundItem {
+	id := "ground-" + string(item.InstanceID)
+	name := string(item.TemplateID)
+	if tmpl, ok := e.playerMgr.GetItemTemplate(item.TemplateID); ok {
+		name = tmpl.DisplayName
+	}
+	cx, cz := spatial.WorldToCell(pos.X, pos.Z, e.cfg.CellSize)
+	key := spatial.CellKey{Cx: cx, Cz: cz}
+	g := &groundItem{
+		ent:        &Entity{ID: id, Kind: KindGroundItem, Pos: pos, Name: name},
+		cell:       key,
+		item:       item,
+		ownerID:    ownerID,
+		ownerUntil: ownerUntil,
+		expiresAt:  expiresAt,
+	}
+	e.getOrCreateCellLocked(key).Entities[id] = g.ent
+	e.groundItems[id] = g
+	return g
+}
+
+// removeGroundItemLocked takes a ground item out of the world. e.mu must be held by caller.
+func (e *Engine) removeGroundItemLocked(id string) {
+	g, ok := e.groundItems[id]
+	if !ok {
+		return
+	}
+	if cell, ok := e.cells[g.cell]; ok {
+		delete(cell.Entities, id)
+	}
+	delete(e.groundItems, id)
+}
+
+// updateGroundItemsLocked despawns ground items past their timeout. e.mu must
+// be held by caller.
+func (e *Engine) updateGroundItemsLocked(now time.Time) {
+	for id, g := range e.groundItems {
+		if !now.Before(g.expiresAt) {
+			e.removeGroundItemLocked(id)
+		}
+	}
+}
+
+// SerializeWorldState captures the items lying in the world for persistence.
+func (e *Engine) SerializeWorldState() (state.WorldState, error) {
+	e.mu.RLock()
+	items := make([]GroundItem, 0, len(e.groundItems))
+	for _, g := range e.groundItems {
+		items = append(items, g.copy())
+	}
+	e.mu.RUnlock()
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return state.WorldState{}, fmt.Errorf("failed to serialize ground items: %w", err)
+	}
+	return state.WorldState{GroundItems: data, Updated: time.Now()}, nil
+}
+
+// RestoreWorldState puts persisted ground items back into the world, keeping
+// their looting and despawn timers. Items that expired meanwhile are dropped
+// on the next tick.
+func (e *Engine) RestoreWorldState(st state.WorldState) error {
+	if len(st.GroundItems) == 0 {
+		return nil
+	}
+	var items []GroundItem
+	if err := json.Unmarshal(st.GroundItems, &items); err != nil {
+		return fmt.Errorf("failed to deserialize ground items: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, item := range items {
+		e.removeGroundItemLocked("ground-" + string(item.Item.InstanceID))
+		e.spawnGroundItemLocked(item.Item, item.Pos, item.OwnerID, item.OwnerUntil, item.ExpiresAt)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package sim
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func TestDropAndPickUpRespectRangeAndLootingRights(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 5})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 6, Z: 5})
+	e.DevSpawn("p3", "Carol", spatial.Vec2{X: 9, Z: 9})
+	if err := e.DevAddItemToPlayer("p1", "rock_small", 5, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	rocks := carried(t, e, "p1", "rock_small")
+
+	if _, err := e.DropItem("p1", rocks, 6, now); !errors.Is(err, ErrInvalidQuantity) {
+		t.Fatalf("dropping more than carried: got %v, want ErrInvalidQuantity", err)
+	}
+	id, err := e.DropItem("p1", rocks, 2, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := e.GroundItem(id)
+	if !ok || g.Item.Quantity != 2 || g.Item.InstanceID == rocks || g.Pos != (spatial.Vec2{X: 5, Z: 5}) || g.OwnerID != "p1" {
+		t.Fatalf("ground item = %+v, %v", g, ok)
+	}
+	p1, _ := e.GetPlayer("p1")
+	if got := p1.Inventory.Items[p1.Inventory.FindItem(rocks)].Instance.Quantity; got != 3 {
+		t.Fatalf("kept %d rocks, want 3", got)
+	}
+	visible := false
+	for _, ent := range e.DevListAllEntities() {
+		visible = visible || (ent.ID == id && ent.Kind == KindGroundItem && ent.Name == "Small Rock")
+	}
+	if !visible {
+		t.Fatalf("ground item %s is not an entity in the world", id)
+	}
+
+	if _, err := e.PickUpItem("p3", id, CompartmentBackpack, now); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("pick up from afar: got %v, want ErrOutOfRange", err)
+	}
+	if _, err := e.PickUpItem("p2", id, CompartmentBackpack, now); !errors.Is(err, ErrNotLootable) {
+		t.Fatalf("pick up another player's drop: got %v, want ErrNotLootable", err)
+	}
+	res, err := e.PickUpItem("p1", id, CompartmentBackpack, now)
+	if err != nil {
+		t.Fatalf("owner pick up: %v", err)
+	}
+	// The rocks merged back into the carried stack; the dropped ID is gone.
+	if len(res.Stacks) != 1 || res.Stacks[0] != rocks || res.Item.Quantity != 2 {
+		t.Fatalf("pick up = %+v, want 2 rocks into %s", res, rocks)
+	}
+	if _, ok := e.GroundItem(id); ok {
+		t.Fatalf("picked up item still on the ground")
+	}
+	p1, _ = e.GetPlayer("p1")
+	if got := p1.Inventory.Items[p1.Inventory.FindItem(rocks)].Instance.Quantity; got != 5 {
+		t.Fatalf("after pick up %d rocks, want 5 in one stack", got)
+	}
+	if _, err := e.PickUpItem("p1", id, CompartmentBackpack, now); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("second pick up: got %v, want ErrItemNotFound", err)
+	}
+
+	// Looting rights run out.
+	id, err = e.DropItem("p1", rocks, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = e.PickUpItem("p2", id, CompartmentBelt, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("pick up after the owner window: %v", err)
+	}
+	p2, _ := e.GetPlayer("p2")
+	if belt := p2.Inventory.GetCompartmentContents(CompartmentBelt); len(belt) != 1 || belt[0].Instance.Quantity != 5 ||
+		len(res.Stacks) != 1 || res.Stacks[0] != belt[0].Instance.InstanceID {
+		t.Fatalf("p2 belt = %+v, pick up = %+v", belt, res)
+	}
+}
+
+func TestGroundItemsDespawn(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 5})
+	if err := e.DevAddItemToPlayer("p1", "sword_iron", 1, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	id, err := e.DropItem("p1", carried(t, e, "p1", "sword_iron"), 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(10*time.Minute - time.Second)
+	e.Step(time.Millisecond)
+	if _, ok := e.GroundItem(id); !ok {
+		t.Fatalf("item despawned early")
+	}
+	now = now.Add(time.Second)
+	e.Step(time.Millisecond)
+	if _, ok := e.GroundItem(id); ok {
+		t.Fatalf("item still on the ground after the despawn timeout")
+	}
+	for _, ent := range e.DevListAllEntities() {
+		if ent.ID == id {
+			t.Fatalf("despawned item still in its cell")
+		}
+	}
+}
+
+func TestGroundItemsPersistWithWorldState(t *testing.T) {
+	now := time.Now()
+	e := newDeathTestEngine(&now)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 5})
+	if err := e.DevAddItemToPlayer("p1", "potion_health", 3, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	id, err := e.DropItem("p1", carried(t, e, "p1", "potion_health"), 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := e.GroundItem(id)
+	st, err := e.SerializeWorldState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := newDeathTestEngine(&now)
+	if err := e2.RestoreWorldState(st); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := e2.GroundItem(id)
+	if !ok || got.Item != want.Item || got.Pos != want.Pos || got.OwnerID != "p1" ||
+		!got.OwnerUntil.Equal(want.OwnerUntil) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Fatalf("restored %+v, want %+v", got, want)
+	}
+	e2.DevSpawn("p1", "Alice", spatial.Vec2{X: 5, Z: 6})
+	if _, err := e2.PickUpItem("p1", id, CompartmentBelt, now); err != nil {
+		t.Fatalf("pick up restored item: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package sim
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -15,6 +16,7 @@ var (
 	ErrExceedsWeight     = errors.New("item would exceed weight limit")
 	ErrExceedsBulk       = errors.New("item would exceed bulk limit")
 	ErrDuplicateInstance = errors.New("item instance already exists")
+	ErrBadCompartment    = errors.New("invalid target compartment")
 )
 
 // EncumbranceState represents the player's current encumbrance
@@ -75,7 +77,10 @@ type Inventory struct {
 	Items           []InventoryItem         `json:"items"`
 	CompartmentCaps map[CompartmentType]int `json:"compartment_caps"` // Bulk limits per compartment
 	WeightLimit     float64                 `json:"weight_limit"`
-	itemIndex       map[ItemInstanceID]int  // Index for fast lookup
+	// Grids lays out the listed compartments as grids in which every item has
+	// a position (see grid.go); other compartments are unordered.
+	Grids           map[CompartmentType]GridSize `json:"grids,omitempty"`
+	itemIndex       map[ItemInstanceID]int       // Index for fast lookup
 	templateCatalog map[ItemTemplateID]*ItemTemplate
 }
 
@@ -219,27 +224,107 @@ func (inv *Inventory) CanAddItem(instance ItemInstance, compartment CompartmentT
 		}
 	}
 
+	// Check grid space for the stacks AddItem would create
+	if inv.HasGrid(compartment) && instance.Quantity > 0 {
+		limit := template.StackSize()
+		left := max(0, instance.Quantity-inv.stackRoom(instance, compartment, limit))
+		if !inv.hasRoomFor(compartment, template.Footprint(), (left+limit-1)/limit) {
+			return ErrInsufficientSpace
+		}
+	}
+
 	return nil
 }
 
-// AddItem adds an item to the inventory
+// AddItem adds an item to the inventory. Stackable items first fill stacks of
+// the same kind in the compartment; what is left becomes new stacks, the
+// first keeping the instance's ID.
 func (inv *Inventory) AddItem(instance ItemInstance, compartment CompartmentType, template *ItemTemplate) error {
 	if err := inv.CanAddItem(instance, compartment, template); err != nil {
 		return err
 	}
+	if template == nil {
+		template = inv.templateCatalog[instance.TemplateID]
+	}
 
-	invItem := InventoryItem{
+	if instance.Quantity <= 0 {
+		return inv.appendItem(instance, compartment, template)
+	}
+	return inv.transact(func() error {
+		instance.Quantity = inv.fillStacks(instance, compartment, template.StackSize())
+		for instance.Quantity > 0 {
+			stack := instance
+			stack.Quantity = min(instance.Quantity, template.StackSize())
+			if err := inv.appendItem(stack, compartment, template); err != nil {
+				return err
+			}
+			instance.Quantity -= stack.Quantity
+			instance.InstanceID = NewItemInstanceID(instance.TemplateID)
+		}
+		return nil
+	})
+}
+
+// appendItem adds a new item to the compartment, at the first free position
+// of its grid if it has one.
+func (inv *Inventory) appendItem(instance ItemInstance, compartment CompartmentType, template *ItemTemplate) error {
+	slot, err := inv.freeSlot(compartment, template)
+	if err != nil {
+		return err
+	}
+	inv.Items = append(inv.Items, InventoryItem{
 		Instance:    instance,
 		Compartment: compartment,
+		Slot:        slot,
 		template:    template,
-	}
-
-	inv.Items = append(inv.Items, invItem)
+	})
 	inv.itemIndex[instance.InstanceID] = len(inv.Items) - 1
+	return nil
+}
 
+// transact runs fn, restoring the items as they were if it fails part way.
+func (inv *Inventory) transact(fn func() error) error {
+	saved := slices.Clone(inv.Items)
+	if err := fn(); err != nil {
+		inv.Items = saved
+		inv.rebuildIndex()
+		return err
+	}
 	return nil
 }
 
+// stackRoom returns how many units of the instance's kind the compartment's
+// stacks can still take, up to limit units each.
+func (inv *Inventory) stackRoom(instance ItemInstance, compartment CompartmentType, limit int) int {
+	room := 0
+	for _, item := range inv.Items {
+		if item.Compartment == compartment && item.Instance.StacksWith(instance) {
+			room += max(0, limit-item.Instance.Quantity)
+		}
+	}
+	return room
+}
+
+// fillStacks tops up the compartment's stacks of the instance's kind, up to
+// limit units each, and returns the quantity that did not fit.
+func (inv *Inventory) fillStacks(instance ItemInstance, compartment CompartmentType, limit int) int {
+	left := instance.Quantity
+	for i := range inv.Items {
+		if left == 0 {
+			break
+		}
+		stack := &inv.Items[i].Instance
+		if inv.Items[i].Compartment != compartment || !stack.StacksWith(instance) {
+			continue
+		}
+		if n := min(left, limit-stack.Quantity); n > 0 {
+			stack.Quantity += n
+			left -= n
+		}
+	}
+	return left
+}
+
 // RemoveItem removes an item from the inventory
 func (inv *Inventory) RemoveItem(instanceID ItemInstanceID) error {
 	idx := inv.FindItem(instanceID)
@@ -247,12 +332,8 @@ func (inv *Inventory) RemoveItem(instanceID ItemInstanceID) error {
 		return ErrItemNotFound
 	}
 
-	// Remove item by swapping with last and truncating
-	lastIdx := len(inv.Items) - 1
-	if idx != lastIdx {
-		inv.Items[idx] = inv.Items[lastIdx]
-	}
-	inv.Items = inv.Items[:lastIdx]
+	// Remove item keeping the order of the rest
+	inv.Items = slices.Delete(inv.Items, idx, idx+1)
 
 	// Rebuild index after removal
 	inv.rebuildIndex()
@@ -260,6 +341,52 @@ func (inv *Inventory) RemoveItem(instanceID ItemInstanceID) error {
 	return nil
 }
 
+// MoveItem moves quantity units of an item into another compartment; 0 moves
+// the whole stack. Moved units fill the compartment's stacks like AddItem, and
+// units split off a larger stack that do not fit any become a stack with ID
+// newID. Weight is unchanged, so only the target's bulk limit is checked.
+func (inv *Inventory) MoveItem(instanceID ItemInstanceID, to CompartmentType, quantity int, newID ItemInstanceID) error {
+	idx := inv.FindItem(instanceID)
+	if idx < 0 {
+		return ErrItemNotFound
+	}
+	item := inv.Items[idx]
+	if _, known := inv.CompartmentCaps[to]; !known || item.Compartment == to {
+		return ErrBadCompartment
+	}
+	if quantity == 0 {
+		quantity = item.Instance.Quantity
+	}
+	if quantity < 0 || quantity > item.Instance.Quantity {
+		return ErrInvalidQuantity
+	}
+	template := inv.resolveTemplate(&inv.Items[idx], nil)
+	if template == nil {
+		return fmt.Errorf("unknown item template: %s", item.Instance.TemplateID)
+	}
+	if inv.GetCompartmentBulk(to, nil)+template.Bulk*quantity > inv.CompartmentCaps[to] {
+		return ErrExceedsBulk
+	}
+	if quantity > inv.stackRoom(item.Instance, to, template.StackSize()) && !inv.hasRoomFor(to, template.Footprint(), 1) {
+		return ErrInsufficientSpace
+	}
+
+	return inv.transact(func() error {
+		moved := item.Instance
+		moved.Quantity = quantity
+		if quantity == item.Instance.Quantity {
+			_ = inv.RemoveItem(instanceID)
+		} else {
+			inv.Items[idx].Instance.Quantity -= quantity
+			moved.InstanceID = newID
+		}
+		if moved.Quantity = inv.fillStacks(moved, to, template.StackSize()); moved.Quantity > 0 {
+			return inv.appendItem(moved, to, template)
+		}
+		return nil
+	})
+}
+
 // ComputeEncumbrance calculates the current encumbrance state
 func (inv *Inventory) ComputeEncumbrance(templates map[ItemTemplateID]*ItemTemplate) EncumbranceState {
 	totalWeight := inv.GetTotalWeight(templates)
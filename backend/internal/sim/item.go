@@ -53,6 +53,24 @@ type ItemTemplate struct {
 	Bulk        int            `json:"bulk"`        // Inventory space used
 	DamageType  DamageType     `json:"damage_type"` // For combat resolution
 	SkillReq    map[string]int `json:"skill_req"`   // Skill requirements to equip
+	// MaxStack is how many units one instance holds; 0 or 1 means the item
+	// does not stack.
+	MaxStack int `json:"max_stack,omitempty"`
+	// Armor is the fraction of incoming damage of each type absorbed while the
+	// item is equipped; pieces add up to maxArmor (see combat.go).
+	Armor map[DamageType]float64 `json:"armor,omitempty"`
+	// Stats is added to the wearer's derived stats while equipped.
+	Stats *StatBonus `json:"stats,omitempty"`
+	// Repair is the material the item is repaired with; nil if it cannot be.
+	Repair *RepairCost `json:"repair,omitempty"`
+	// Items with Effects can be used; the Category shares a use cooldown.
+	Category ItemCategory `json:"category,omitempty"`
+	Effects  []ItemEffect `json:"effects,omitempty"`
+}
+
+// StackSize returns how many units of this item one instance can hold.
+func (t *ItemTemplate) StackSize() int {
+	return max(1, t.MaxStack)
 }
 
 // Allows checks if this item can be equipped to the given slot
@@ -109,5 +127,7 @@ const (
 type InventoryItem struct {
 	Instance    ItemInstance    `json:"instance"`
 	Compartment CompartmentType `json:"compartment"`
-	template    *ItemTemplate
+	// Slot is the item's position when its compartment is a grid.
+	Slot     *GridPos `json:"slot,omitempty"`
+	template *ItemTemplate
 }
@@ -13,12 +13,44 @@ import (
 type PlayerPersistenceData struct {
 	Inventory         *Inventory           `json:"inventory"`
 	Equipment         *Equipment           `json:"equipment"`
-	Skills            map[string]int       `json:"skills"`
+	Skills            persistedSkills      `json:"skills"`
 	CooldownTimers    map[SlotID]time.Time `json:"cooldown_timers"`
 	EncumbranceConfig struct {
 		WeightLimit     float64                 `json:"weight_limit"`
 		CompartmentCaps map[CompartmentType]int `json:"compartment_caps"`
 	} `json:"encumbrance_config"`
+	Attributes persistedAttributes `json:"attributes"`
+}
+
+// persistedAttributes are the attribute pools that survive a logout, and the
+// death state of a player who logged out while dead. Derived stats are
+// recomputed from equipment and skills on load.
+type persistedAttributes struct {
+	Health    float64    `json:"health"`
+	Stamina   float64    `json:"stamina"`
+	Dead      bool       `json:"dead,omitempty"`
+	RespawnAt *time.Time `json:"respawn_at,omitempty"` // set while dead
+}
+
+// persistedSkills is the SkillsData format: skill levels and the XP toward
+// each next level. Records saved before skill XP existed hold only the levels,
+// as a flat name->level map.
+type persistedSkills struct {
+	Levels map[string]int     `json:"levels"`
+	XP     map[string]float64 `json:"xp"`
+}
+
+// unmarshalSkills decodes SkillsData in either format.
+func unmarshalSkills(data []byte) (persistedSkills, error) {
+	var skills persistedSkills
+	if err := json.Unmarshal(data, &skills); err == nil && skills.Levels != nil {
+		return skills, nil
+	}
+	var levels map[string]int
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return persistedSkills{}, err
+	}
+	return persistedSkills{Levels: levels}, nil
 }
 
 // SerializePlayerData converts player game state to persistent state
@@ -26,8 +58,22 @@ func SerializePlayerData(player *Player) (state.PlayerState, error) {
 	persistData := PlayerPersistenceData{
 		Inventory:      player.Inventory,
 		Equipment:      player.Equipment,
-		Skills:         player.Skills,
+		Skills:         persistedSkills{Levels: player.Skills, XP: player.SkillXP},
 		CooldownTimers: make(map[SlotID]time.Time),
+		Attributes: persistedAttributes{
+			Health:  player.Health,
+			Stamina: player.Stamina,
+			Dead:    player.Dead,
+		},
+	}
+
+	if persistData.Skills.Levels == nil {
+		// a null levels map would read back as a legacy record
+		persistData.Skills.Levels = map[string]int{}
+	}
+	if player.Dead {
+		respawnAt := player.RespawnAt
+		persistData.Attributes.RespawnAt = &respawnAt
 	}
 
 	// Extract cooldown timers from equipment
@@ -71,6 +117,11 @@ func SerializePlayerData(player *Player) (state.PlayerState, error) {
 		return state.PlayerState{}, fmt.Errorf("failed to serialize encumbrance config: %w", err)
 	}
 
+	attributesData, err := json.Marshal(persistData.Attributes)
+	if err != nil {
+		return state.PlayerState{}, fmt.Errorf("failed to serialize attributes: %w", err)
+	}
+
 	return state.PlayerState{
 		Pos:               player.Pos,
 		Logins:            0, // Will be set by caller
@@ -81,6 +132,7 @@ func SerializePlayerData(player *Player) (state.PlayerState, error) {
 		SkillsData:        skillsData,
 		CooldownTimers:    cooldownData,
 		EncumbranceConfig: encumbranceData,
+		AttributesData:    attributesData,
 	}, nil
 }
 
@@ -113,11 +165,15 @@ func DeserializePlayerData(state state.PlayerState, player *Player, templates ma
 
 	// Deserialize skills
 	if len(state.SkillsData) > 0 {
-		var skills map[string]int
-		if err := json.Unmarshal(state.SkillsData, &skills); err != nil {
+		skills, err := unmarshalSkills(state.SkillsData)
+		if err != nil {
 			return fmt.Errorf("failed to deserialize skills: %w", err)
 		}
-		player.Skills = skills
+		player.Skills = skills.Levels
+		player.SkillXP = skills.XP
+		if player.SkillXP == nil {
+			player.SkillXP = make(map[string]float64)
+		}
 	}
 
 	// Restore cooldown timers to equipment
@@ -151,6 +207,26 @@ func DeserializePlayerData(state state.PlayerState, player *Player, templates ma
 		}
 	}
 
+	// Restore health and stamina; without saved attributes the player keeps
+	// the pools they already have (full for a new record)
+	if len(state.AttributesData) > 0 {
+		var attrs persistedAttributes
+		if err := json.Unmarshal(state.AttributesData, &attrs); err != nil {
+			return fmt.Errorf("failed to deserialize attributes: %w", err)
+		}
+		player.Health, player.Stamina = attrs.Health, attrs.Stamina
+		// A player who logged out dead stays dead until their respawn time,
+		// which the engine's tick honours once they are back in the world
+		player.Dead, player.RespawnAt = attrs.Dead, time.Time{}
+		if attrs.RespawnAt != nil {
+			player.RespawnAt = *attrs.RespawnAt
+		}
+	}
+	// Equipment and skills were replaced wholesale, so stats must be derived
+	// again before the restored pools are clamped to them
+	player.statsBasis = statsBasis{}
+	player.AttributesVersion++
+
 	return nil
 }
 
@@ -159,7 +235,7 @@ func CreateDefaultPlayerState(playerID string, pos spatial.Vec2) state.PlayerSta
 	// Create default inventory and equipment
 	inventory := NewInventory()
 	equipment := NewEquipment()
-	skills := make(map[string]int)
+	skills := persistedSkills{Levels: make(map[string]int), XP: make(map[string]float64)}
 
 	// Serialize defaults - these should never fail, but handle errors for robustness
 	inventoryData, err := json.Marshal(inventory)
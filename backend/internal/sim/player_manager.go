@@ -10,13 +10,16 @@ const EquipCooldown = 2 * time.Second
 
 // PlayerManager handles inventory and equipment operations for players
 type PlayerManager struct {
-	itemTemplates map[ItemTemplateID]*ItemTemplate
+	itemTemplates  map[ItemTemplateID]*ItemTemplate
+	skillCurves    map[string]SkillCurve
+	inventoryGrids map[CompartmentType]GridSize
 }
 
 // NewPlayerManager creates a new player manager with item templates
 func NewPlayerManager() *PlayerManager {
 	return &PlayerManager{
 		itemTemplates: make(map[ItemTemplateID]*ItemTemplate),
+		skillCurves:   make(map[string]SkillCurve),
 	}
 }
 
@@ -46,12 +49,39 @@ func (pm *PlayerManager) InitializePlayer(player *Player) {
 		player.Inventory = NewInventory()
 	}
 	player.Inventory.SetTemplateCatalog(pm.itemTemplates)
+	pm.applyInventoryGrids(player.Inventory)
 	if player.Equipment == nil {
 		player.Equipment = NewEquipment()
 	}
 	if player.Skills == nil {
 		player.Skills = make(map[string]int)
 	}
+	if player.SkillXP == nil {
+		player.SkillXP = make(map[string]float64)
+	}
+	// New players start with full health and stamina
+	if !player.statsBasis.valid {
+		pm.RefreshStats(player)
+		player.Health = player.MaxHealth
+		player.Stamina = player.Stats.MaxStamina
+	}
+}
+
+// applyInventoryGrids brings an inventory's grids in line with the configured
+// ones. A restored grid whose size changed since it was saved is laid out
+// again, and one no longer configured is dropped; an inventory too full for a
+// grid stays unordered.
+func (pm *PlayerManager) applyInventoryGrids(inv *Inventory) {
+	for compartment, size := range inv.Grids {
+		if pm.inventoryGrids[compartment] != size {
+			inv.DisableGrid(compartment)
+		}
+	}
+	for compartment, size := range pm.inventoryGrids {
+		if !inv.HasGrid(compartment) {
+			_ = inv.EnableGrid(compartment, size)
+		}
+	}
 }
 
 // CheckSkillRequirements verifies if a player meets the skill requirements for an item
@@ -88,6 +118,48 @@ func (pm *PlayerManager) RemoveItemFromInventory(player *Player, instanceID Item
 	return err
 }
 
+// MoveItem moves quantity units of an item to another compartment of the
+// player's inventory; 0 moves the whole stack.
+func (pm *PlayerManager) MoveItem(player *Player, instanceID ItemInstanceID, compartment CompartmentType, quantity int) error {
+	if player.Inventory == nil {
+		return ErrItemNotFound
+	}
+	idx := player.Inventory.FindItem(instanceID)
+	if idx < 0 {
+		return ErrItemNotFound
+	}
+	newID := NewItemInstanceID(player.Inventory.Items[idx].Instance.TemplateID)
+	if err := player.Inventory.MoveItem(instanceID, compartment, quantity, newID); err != nil {
+		return err
+	}
+	player.InventoryVersion++
+	return nil
+}
+
+// PlaceItem moves an item to a position in its compartment's grid
+func (pm *PlayerManager) PlaceItem(player *Player, instanceID ItemInstanceID, pos GridPos) error {
+	if player.Inventory == nil {
+		return ErrItemNotFound
+	}
+	err := player.Inventory.PlaceItem(instanceID, pos)
+	if err == nil {
+		player.InventoryVersion++
+	}
+	return err
+}
+
+// SortInventory re-packs a grid compartment of the player's inventory
+func (pm *PlayerManager) SortInventory(player *Player, compartment CompartmentType) error {
+	if player.Inventory == nil {
+		return ErrNoGrid
+	}
+	err := player.Inventory.SortCompartment(compartment)
+	if err == nil {
+		player.InventoryVersion++
+	}
+	return err
+}
+
 // EquipItem equips an item from inventory to an equipment slot
 func (pm *PlayerManager) EquipItem(player *Player, instanceID ItemInstanceID, slot SlotID, now time.Time) error {
 	// Find the item in inventory
@@ -107,6 +179,11 @@ func (pm *PlayerManager) EquipItem(player *Player, instanceID ItemInstanceID, sl
 		return ErrIllegalSlot
 	}
 
+	// Broken items must be repaired first
+	if item.Instance.Broken() {
+		return ErrItemBroken
+	}
+
 	// Check skill requirements
 	if !pm.CheckSkillRequirements(player, template) {
 		return ErrSkillGate
@@ -253,6 +330,7 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		Bulk:        2,
 		DamageType:  DamageSlash,
 		SkillReq:    map[string]int{"melee": 10},
+		Repair:      &RepairCost{Material: "ingot_iron", Quantity: 4},
 	})
 
 	// Shield
@@ -264,6 +342,9 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		Bulk:        3,
 		DamageType:  DamageBlunt,
 		SkillReq:    map[string]int{"defense": 5},
+		Armor:       map[DamageType]float64{DamageSlash: 0.15, DamagePierce: 0.2, DamageBlunt: 0.05},
+		Stats:       &StatBonus{MaxHealth: 5},
+		Repair:      &RepairCost{Material: "leather_strip", Quantity: 3},
 	})
 
 	// Armor
@@ -275,6 +356,9 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		Bulk:        4,
 		DamageType:  "",
 		SkillReq:    map[string]int{},
+		Armor:       map[DamageType]float64{DamageSlash: 0.25, DamagePierce: 0.15, DamageBlunt: 0.1},
+		Stats:       &StatBonus{MaxHealth: 15},
+		Repair:      &RepairCost{Material: "leather_strip", Quantity: 5},
 	})
 
 	// Consumable item
@@ -286,6 +370,39 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		Bulk:        1,
 		DamageType:  "",
 		SkillReq:    map[string]int{},
+		MaxStack:    20,
+		Category:    CategoryPotion,
+		Effects:     []ItemEffect{{Kind: EffectHeal, Amount: 30}},
+	})
+
+	pm.RegisterItemTemplate(&ItemTemplate{
+		ID:          "draught_stamina",
+		DisplayName: "Stamina Draught",
+		SlotMask:    0,
+		Weight:      0.1,
+		Bulk:        1,
+		DamageType:  "",
+		SkillReq:    map[string]int{},
+		MaxStack:    20,
+		Category:    CategoryPotion,
+		Effects: []ItemEffect{{
+			Kind:     EffectBuff,
+			Bonus:    &StatBonus{MaxStamina: 20, StaminaRegen: 5},
+			Duration: time.Minute,
+		}},
+	})
+
+	pm.RegisterItemTemplate(&ItemTemplate{
+		ID:          "scroll_recall",
+		DisplayName: "Scroll of Recall",
+		SlotMask:    0,
+		Weight:      0.05,
+		Bulk:        1,
+		DamageType:  "",
+		SkillReq:    map[string]int{},
+		MaxStack:    10,
+		Category:    CategoryScroll,
+		Effects:     []ItemEffect{{Kind: EffectTeleport}}, // to the nearest spawn point
 	})
 
 	// Heavy test items for encumbrance testing
@@ -299,6 +416,29 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		SkillReq:    map[string]int{},
 	})
 
+	// Repair materials
+	pm.RegisterItemTemplate(&ItemTemplate{
+		ID:          "ingot_iron",
+		DisplayName: "Iron Ingot",
+		SlotMask:    0,
+		Weight:      1.0,
+		Bulk:        1,
+		DamageType:  "",
+		SkillReq:    map[string]int{},
+		MaxStack:    50,
+	})
+
+	pm.RegisterItemTemplate(&ItemTemplate{
+		ID:          "leather_strip",
+		DisplayName: "Leather Strip",
+		SlotMask:    0,
+		Weight:      0.2,
+		Bulk:        1,
+		DamageType:  "",
+		SkillReq:    map[string]int{},
+		MaxStack:    50,
+	})
+
 	pm.RegisterItemTemplate(&ItemTemplate{
 		ID:          "rock_small",
 		DisplayName: "Small Rock",
@@ -307,5 +447,6 @@ func (pm *PlayerManager) CreateTestItemTemplates() {
 		Bulk:        1,
 		DamageType:  "",
 		SkillReq:    map[string]int{},
+		MaxStack:    50,
 	})
 }
@@ -493,3 +493,70 @@ func TestPlayerManager_CreateTestItemTemplates(t *testing.T) {
 		}
 	}
 }
+
+func TestPlayerManager_MoveItem(t *testing.T) {
+	pm := NewPlayerManager()
+	pm.CreateTestItemTemplates()
+	player := &Player{}
+	pm.InitializePlayer(player)
+	player.Inventory.CompartmentCaps[CompartmentBelt] = 5
+
+	rocks := ItemInstance{InstanceID: "rocks", TemplateID: "rock_small", Quantity: 8, Durability: 1.0}
+	if err := pm.AddItemToInventory(player, rocks, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	sword := ItemInstance{InstanceID: "sword", TemplateID: "sword_iron", Quantity: 1, Durability: 1.0}
+	if err := pm.AddItemToInventory(player, sword, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		id       ItemInstanceID
+		to       CompartmentType
+		quantity int
+		want     error
+	}{
+		{"missing item", "nope", CompartmentBelt, 0, ErrItemNotFound},
+		{"same compartment", "rocks", CompartmentBackpack, 0, ErrBadCompartment},
+		{"unknown compartment", "rocks", "saddlebag", 0, ErrBadCompartment},
+		{"more than the stack", "rocks", CompartmentBelt, 9, ErrInvalidQuantity},
+		{"whole stack over the cap", "rocks", CompartmentBelt, 0, ErrExceedsBulk},
+	}
+	for _, tt := range tests {
+		if err := pm.MoveItem(player, tt.id, tt.to, tt.quantity); err != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// A partial move leaves the rest behind under the original ID.
+	version := player.InventoryVersion
+	if err := pm.MoveItem(player, "rocks", CompartmentBelt, 3); err != nil {
+		t.Fatalf("partial move: %v", err)
+	}
+	if player.InventoryVersion != version+1 {
+		t.Errorf("InventoryVersion = %d, want %d", player.InventoryVersion, version+1)
+	}
+	if got := player.Inventory.Items[player.Inventory.FindItem("rocks")]; got.Instance.Quantity != 5 || got.Compartment != CompartmentBackpack {
+		t.Errorf("source stack = %+v, want 5 left in the backpack", got)
+	}
+	if got := player.Inventory.GetCompartmentBulk(CompartmentBelt, nil); got != 3 {
+		t.Errorf("belt bulk = %d, want 3", got)
+	}
+
+	// Moved units join the stack already in the belt.
+	if err := pm.MoveItem(player, "rocks", CompartmentBelt, 2); err != nil {
+		t.Fatalf("second partial move: %v", err)
+	}
+	if belt := player.Inventory.GetCompartmentContents(CompartmentBelt); len(belt) != 1 || belt[0].Instance.Quantity != 5 {
+		t.Errorf("belt = %+v, want one stack of 5", belt)
+	}
+
+	// Whole items keep their ID.
+	if err := pm.MoveItem(player, "sword", CompartmentCraftBag, 0); err != nil {
+		t.Fatalf("move sword: %v", err)
+	}
+	if got := player.Inventory.Items[player.Inventory.FindItem("sword")].Compartment; got != CompartmentCraftBag {
+		t.Errorf("sword compartment = %s, want craft_bag", got)
+	}
+}
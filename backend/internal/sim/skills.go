@@ -0,0 +1,210 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+)
+
+// SkillCurve defines how much XP each level of a skill costs. Reaching level
+// n+1 from level n takes BaseXP * Growth^n XP.
+type SkillCurve struct {
+	BaseXP   float64 `json:"base_xp"`
+	Growth   float64 `json:"growth"`
+	MaxLevel int     `json:"max_level"`
+}
+
+// XPToNext returns the XP needed to advance from level to level+1, or 0 at
+// the maximum level.
+func (c SkillCurve) XPToNext(level int) float64 {
+	if level >= c.MaxLevel {
+		return 0
+	}
+	return math.Round(c.BaseXP * math.Pow(c.Growth, float64(level)))
+}
+
+// defaultSkillCurve applies to skills without a registered curve.
+var defaultSkillCurve = SkillCurve{BaseXP: 50, Growth: 1.15, MaxLevel: 100}
+
+// XP granted per action.
+const (
+	xpEquip          = 5.0  // per skill the equipped item requires
+	xpAttackHit      = 10.0 // to the weapon's skill
+	xpAttackMiss     = 4.0
+	xpDamageTaken    = 1.0 // defense XP per point of damage taken
+	xpRepairMaterial = 3.0 // crafting XP per repair material consumed
+)
+
+// damageTypeSkills is the skill trained by attacking with each damage type.
+var damageTypeSkills = map[DamageType]string{
+	DamageSlash:     "melee",
+	DamagePierce:    "melee",
+	DamageBlunt:     "melee",
+	DamageElemental: "magic",
+}
+
+// SkillLevelUp records a skill reaching a new level. Seq increases with each
+// level-up of the same player so sessions can forward each one once.
+type SkillLevelUp struct {
+	Seq   int64  `json:"seq"`
+	Skill string `json:"skill"`
+	Level int    `json:"level"`
+}
+
+// levelUpLogSize is how many recent level-ups a player keeps for sessions.
+const levelUpLogSize = 16
+
+// RegisterSkillCurve sets the level curve of a skill.
+func (pm *PlayerManager) RegisterSkillCurve(skill string, curve SkillCurve) {
+	pm.skillCurves[skill] = curve
+}
+
+// GetSkillCurve returns the level curve of a skill, or the default curve.
+func (pm *PlayerManager) GetSkillCurve(skill string) SkillCurve {
+	if curve, ok := pm.skillCurves[skill]; ok {
+		return curve
+	}
+	return defaultSkillCurve
+}
+
+// DefaultSkillCurves returns the level curves of the built-in skills, used
+// when no skills file is configured.
+func DefaultSkillCurves() map[string]SkillCurve {
+	return map[string]SkillCurve{
+		"melee":    {BaseXP: 50, Growth: 1.15, MaxLevel: 100},
+		"defense":  {BaseXP: 80, Growth: 1.15, MaxLevel: 100},
+		"magic":    {BaseXP: 60, Growth: 1.18, MaxLevel: 100},
+		"crafting": {BaseXP: 40, Growth: 1.12, MaxLevel: 100},
+	}
+}
+
+// LoadSkillCurves reads skill level curves from a JSON file keyed by skill, e.g.
+//
+//	{"melee": {"base_xp": 50, "growth": 1.15, "max_level": 100}}
+func LoadSkillCurves(path string) (map[string]SkillCurve, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var curves map[string]SkillCurve
+	if err := json.Unmarshal(bs, &curves); err != nil {
+		return nil, fmt.Errorf("skills %s: %w", path, err)
+	}
+	for skill, curve := range curves {
+		if err := curve.validate(); err != nil {
+			return nil, fmt.Errorf("skills %s: %s: %w", path, skill, err)
+		}
+	}
+	return curves, nil
+}
+
+func (c SkillCurve) validate() error {
+	switch {
+	case c.BaseXP <= 0:
+		return errors.New("base_xp must be positive")
+	case c.Growth < 1:
+		return errors.New("growth must be at least 1")
+	case c.MaxLevel <= 0:
+		return errors.New("max_level must be positive")
+	}
+	return nil
+}
+
+// GainSkillXP adds XP to a skill, advancing its level as often as the XP
+// allows. XP toward the next level carries over; at the maximum level no more
+// XP is kept. Only level-ups bump SkillsVersion; XP alone bumps
+// SkillXPVersion, which changes with nearly every action. Skills and SkillXP
+// are replaced rather than modified, because copies of the player handed out
+// by GetPlayer share them. It returns the level-ups, if any.
+func (pm *PlayerManager) GainSkillXP(player *Player, skill string, xp float64) []SkillLevelUp {
+	if xp <= 0 || skill == "" {
+		return nil
+	}
+	curve := pm.GetSkillCurve(skill)
+	level := player.Skills[skill]
+	if level >= curve.MaxLevel {
+		return nil
+	}
+	total := player.SkillXP[skill] + xp
+	var ups []SkillLevelUp
+	for need := curve.XPToNext(level); need > 0 && total >= need; need = curve.XPToNext(level) {
+		total -= need
+		level++
+		player.levelUpSeq++
+		ups = append(ups, SkillLevelUp{Seq: player.levelUpSeq, Skill: skill, Level: level})
+	}
+	if level >= curve.MaxLevel {
+		total = 0
+	}
+
+	skillXP := maps.Clone(player.SkillXP)
+	if skillXP == nil {
+		skillXP = make(map[string]float64)
+	}
+	skillXP[skill] = total
+	player.SkillXP = skillXP
+	if len(ups) > 0 {
+		skills := maps.Clone(player.Skills)
+		if skills == nil {
+			skills = make(map[string]int)
+		}
+		skills[skill] = level
+		player.Skills = skills
+		player.recordLevelUps(ups)
+		player.SkillsVersion++
+	}
+	player.SkillXPVersion++
+	return ups
+}
+
+// recordLevelUps appends to the player's recent level-ups, keeping the last
+// levelUpLogSize. The log is copied rather than trimmed in place for the same
+// reason as Skills.
+func (p *Player) recordLevelUps(ups []SkillLevelUp) {
+	recent := append(append([]SkillLevelUp(nil), p.LevelUps...), ups...)
+	if len(recent) > levelUpLogSize {
+		recent = recent[len(recent)-levelUpLogSize:]
+	}
+	p.LevelUps = recent
+}
+
+// LevelUpsSince returns the player's recorded level-ups after seq, oldest first.
+func (p *Player) LevelUpsSince(seq int64) []SkillLevelUp {
+	for i, up := range p.LevelUps {
+		if up.Seq > seq {
+			return p.LevelUps[i:]
+		}
+	}
+	return nil
+}
+
+// LatestLevelUpSeq returns the Seq of the player's last level-up.
+func (p *Player) LatestLevelUpSeq() int64 { return p.levelUpSeq }
+
+// GrantSkillXP awards XP to a player's skill for actions resolved outside
+// the engine's own systems, such as crafting.
+func (e *Engine) GrantSkillXP(playerID, skill string, xp float64) ([]SkillLevelUp, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return nil, fmt.Errorf("player %s not found", playerID)
+	}
+	return e.playerMgr.GainSkillXP(player, skill, xp), nil
+}
+
+// trainEquipLocked grants XP in each skill an equipped item requires.
+// e.mu must be held by caller.
+func (e *Engine) trainEquipLocked(player *Player, templateID ItemTemplateID) {
+	tmpl, ok := e.playerMgr.GetItemTemplate(templateID)
+	if !ok {
+		return
+	}
+	for skill := range tmpl.SkillReq {
+		e.playerMgr.GainSkillXP(player, skill, xpEquip)
+	}
+}
@@ -0,0 +1,154 @@
+package sim
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func TestSkillCurveXPToNext(t *testing.T) {
+	c := SkillCurve{BaseXP: 100, Growth: 1.5, MaxLevel: 3}
+	for level, want := range []float64{100, 150, 225, 0} {
+		if got := c.XPToNext(level); got != want {
+			t.Fatalf("XPToNext(%d) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestGainSkillXP_LevelsUpAndCarriesOver(t *testing.T) {
+	pm := NewPlayerManager()
+	pm.RegisterSkillCurve("melee", SkillCurve{BaseXP: 100, Growth: 1.5, MaxLevel: 3})
+	p := &Player{}
+	pm.InitializePlayer(p)
+	skills, version, xpVersion := p.Skills, p.SkillsVersion, p.SkillXPVersion
+
+	if ups := pm.GainSkillXP(p, "melee", 60); len(ups) != 0 || p.SkillXP["melee"] != 60 {
+		t.Fatalf("expected 60 xp and no level-up, got %v and %+v", p.SkillXP, ups)
+	}
+	if p.SkillsVersion != version || p.SkillXPVersion != xpVersion+1 {
+		t.Fatalf("an xp gain must bump SkillXPVersion only")
+	}
+
+	ups := pm.GainSkillXP(p, "melee", 200) // 260: level 1 at 100, level 2 at 250
+	if len(ups) != 2 || ups[0] != (SkillLevelUp{Seq: 1, Skill: "melee", Level: 1}) || ups[1] != (SkillLevelUp{Seq: 2, Skill: "melee", Level: 2}) {
+		t.Fatalf("unexpected level-ups %+v", ups)
+	}
+	if p.SkillsVersion != version+1 {
+		t.Fatalf("a level-up must bump SkillsVersion")
+	}
+	if p.Skills["melee"] != 2 || p.SkillXP["melee"] != 10 {
+		t.Fatalf("level=%d xp=%v, want level 2 with 10 xp carried over", p.Skills["melee"], p.SkillXP["melee"])
+	}
+	if len(skills) != 0 {
+		t.Fatalf("skills map handed out before the level-up was modified: %v", skills)
+	}
+	if got := p.LevelUpsSince(1); len(got) != 1 || got[0].Level != 2 {
+		t.Fatalf("LevelUpsSince(1) = %+v", got)
+	}
+
+	pm.GainSkillXP(p, "melee", 1000)
+	if p.Skills["melee"] != 3 || p.SkillXP["melee"] != 0 {
+		t.Fatalf("level=%d xp=%v, want max level 3 with no xp", p.Skills["melee"], p.SkillXP["melee"])
+	}
+	if ups := pm.GainSkillXP(p, "melee", 1000); ups != nil || p.LatestLevelUpSeq() != 3 {
+		t.Fatalf("no xp is gained past the max level, got %+v", ups)
+	}
+}
+
+func TestActionsGrantSkillXP(t *testing.T) {
+	e := newTestEngine()
+	withRolls(e, 0)
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("p2", "Bob", spatial.Vec2{X: 1, Z: 2})
+
+	equip(t, e, "p1", "sword_iron", SlotMainHand)
+	p1, _ := e.GetPlayer("p1")
+	if p1.SkillXP["melee"] != xpEquip {
+		t.Fatalf("equipping a sword should train melee, xp = %v", p1.SkillXP)
+	}
+
+	ev, err := e.Attack("p1", AttackRequest{TargetID: "p2", Aim: spatial.Vec2{Z: 1}}, time.Now())
+	if err != nil || !ev.Hit {
+		t.Fatalf("attack: %+v, %v", ev, err)
+	}
+	p1, _ = e.GetPlayer("p1")
+	if p1.SkillXP["melee"] != xpEquip+xpAttackHit {
+		t.Fatalf("a hit should train the weapon's skill, xp = %v", p1.SkillXP)
+	}
+	p2, _ := e.GetPlayer("p2")
+	if p2.SkillXP["defense"] != ev.Damage*xpDamageTaken {
+		t.Fatalf("taking %v damage should train defense, xp = %v", ev.Damage, p2.SkillXP)
+	}
+
+	if ups, err := e.GrantSkillXP("p2", "crafting", 1000); err != nil || len(ups) == 0 {
+		t.Fatalf("GrantSkillXP: %+v, %v", ups, err)
+	}
+	p2, _ = e.GetPlayer("p2")
+	if p2.Skills["crafting"] == 0 {
+		t.Fatalf("expected crafting to level up, skills = %v", p2.Skills)
+	}
+}
+
+func TestSkillXPPersistence(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{})
+	if _, err := e.GrantSkillXP("p1", "defense", 90); err != nil {
+		t.Fatal(err)
+	}
+	e.mu.Lock()
+	st, err := SerializePlayerData(e.players["p1"])
+	e.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := newTestEngine()
+	e2.DevSpawn("p1", "Alice", spatial.Vec2{})
+	templates := e2.GetPlayerManager().GetAllItemTemplates()
+	if err := e2.RestorePlayerState("p1", st, templates); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := e2.GetPlayer("p1")
+	if got.Skills["defense"] != 1 || got.SkillXP["defense"] != 10 {
+		t.Fatalf("restored defense level=%d xp=%v, want 1 and 10", got.Skills["defense"], got.SkillXP["defense"])
+	}
+
+	// Records saved before skill XP hold a flat name->level map.
+	st.SkillsData = []byte(`{"melee": 7}`)
+	if err := e2.RestorePlayerState("p1", st, templates); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = e2.GetPlayer("p1")
+	if got.Skills["melee"] != 7 || got.SkillXP == nil || len(got.SkillXP) != 0 {
+		t.Fatalf("legacy skills = %v xp %v, want melee 7 and no xp", got.Skills, got.SkillXP)
+	}
+}
+
+func TestLoadSkillCurves(t *testing.T) {
+	// The shipped config matches the built-in curves.
+	curves, err := LoadSkillCurves("../../../configs/skills.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(curves, DefaultSkillCurves()) {
+		t.Fatalf("configs/skills.json = %+v, want the built-in curves", curves)
+	}
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "skills.json")
+	if err := os.WriteFile(bad, []byte(`{"melee": {"base_xp": 50, "growth": 0.5, "max_level": 10}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSkillCurves(bad); err == nil {
+		t.Fatalf("expected a shrinking curve to be rejected")
+	}
+
+	e := NewEngine(Config{SkillCurves: map[string]SkillCurve{"melee": {BaseXP: 7, Growth: 1, MaxLevel: 2}}})
+	if got := e.playerMgr.GetSkillCurve("melee"); got.BaseXP != 7 {
+		t.Fatalf("configured melee curve = %+v", got)
+	}
+}
@@ -0,0 +1,194 @@
+package sim
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+// SnapshotEntity is an entity as captured in a snapshot, together with its
+// encoded wire form so sessions never re-encode identical entity data.
+type SnapshotEntity struct {
+	Entity
+	JSON json.RawMessage
+}
+
+// entityWire is the client-facing encoding of an entity in state messages.
+type entityWire struct {
+	ID   string       `json:"id"`
+	Pos  spatial.Vec2 `json:"pos"`
+	Vel  spatial.Vec2 `json:"vel"`
+	Kind int          `json:"kind"`
+	Name string       `json:"name"`
+	// Health lets clients draw health bars for everyone in range
+	Health    float64 `json:"health"`
+	MaxHealth float64 `json:"max_health"`
+}
+
+// CellSnapshot is the immutable content of one cell at a snapshot tick.
+type CellSnapshot struct {
+	Key      spatial.CellKey
+	Entities []SnapshotEntity
+}
+
+// PlayerSnapshot is the per-session view of a player at a snapshot tick.
+type PlayerSnapshot struct {
+	Entity
+	OwnedCell        spatial.CellKey
+	HandoverAt       time.Time
+	InventoryVersion int64
+	EquipmentVersion int64
+	SkillsVersion    int64
+	SkillXPVersion   int64
+	// Attributes are copied in full: they change often enough through
+	// regeneration that sessions should not take the engine lock to read them.
+	Stamina           float64
+	Stats             Stats
+	AttributesVersion int64
+	Buffs             []Buff
+	Dead              bool
+	RespawnAt         time.Time
+}
+
+// WorldSnapshot is produced once per snapshot tick and shared by every session.
+// It is never mutated after publication, so readers need no locks.
+type WorldSnapshot struct {
+	Seq        uint64
+	Time       time.Time
+	Tick       uint64        // engine tick the snapshot was taken at
+	ServerTime time.Duration // monotonic server time (see Engine.ServerTime)
+	cellSize   float64
+	cells      map[spatial.CellKey]*CellSnapshot
+	players    map[string]PlayerSnapshot
+	combat     []CombatEvent // recent combat events, oldest first (see CombatSince)
+	eng        *Engine       // for AOI query metrics only
+}
+
+// LatestSnapshot returns the most recently published snapshot, or nil if the
+// engine has not produced one yet.
+func (e *Engine) LatestSnapshot() *WorldSnapshot {
+	return e.latestSnap.Load()
+}
+
+// PublishSnapshot captures and publishes a snapshot immediately. The engine loop
+// does this every snapshot tick; callers driving the engine with Step use it directly.
+func (e *Engine) PublishSnapshot() *WorldSnapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.publishSnapshotLocked(time.Now())
+}
+
+// publishSnapshotLocked builds and publishes a new WorldSnapshot. Encoded
+// fragments from the previous snapshot are reused for entities that have not
+// changed. e.mu must be held (read or write) by caller.
+func (e *Engine) publishSnapshotLocked(now time.Time) *WorldSnapshot {
+	prev := e.latestSnap.Load()
+	var prevFrags map[string]SnapshotEntity
+	if prev != nil {
+		prevFrags = make(map[string]SnapshotEntity, len(prev.players)*2)
+		for _, c := range prev.cells {
+			for _, se := range c.Entities {
+				prevFrags[se.ID] = se
+			}
+		}
+	}
+
+	snap := &WorldSnapshot{
+		Time:       now,
+		Tick:       e.tickCount.Load(),
+		ServerTime: e.ServerTime(),
+		cellSize:   e.cfg.CellSize,
+		cells:      make(map[spatial.CellKey]*CellSnapshot, len(e.cells)),
+		players:    make(map[string]PlayerSnapshot, len(e.players)),
+		combat:     e.recentCombatLocked(),
+		eng:        e,
+	}
+	if prev != nil {
+		snap.Seq = prev.Seq + 1
+	}
+	for key, cell := range e.cells {
+		cs := &CellSnapshot{Key: key, Entities: make([]SnapshotEntity, 0, len(cell.Entities))}
+		for _, ent := range cell.Entities {
+			if old, ok := prevFrags[ent.ID]; ok && old.Entity == *ent {
+				cs.Entities = append(cs.Entities, old)
+				continue
+			}
+			bs, err := json.Marshal(entityWire{ID: ent.ID, Pos: ent.Pos, Vel: ent.Vel, Kind: int(ent.Kind), Name: ent.Name, Health: ent.Health, MaxHealth: ent.MaxHealth})
+			if err != nil {
+				continue
+			}
+			cs.Entities = append(cs.Entities, SnapshotEntity{Entity: *ent, JSON: bs})
+		}
+		snap.cells[key] = cs
+	}
+	for id, p := range e.players {
+		snap.players[id] = PlayerSnapshot{
+			Entity:            p.Entity,
+			OwnedCell:         p.OwnedCell,
+			HandoverAt:        p.HandoverAt,
+			InventoryVersion:  p.InventoryVersion,
+			EquipmentVersion:  p.EquipmentVersion,
+			SkillsVersion:     p.SkillsVersion,
+			SkillXPVersion:    p.SkillXPVersion,
+			Stamina:           p.Stamina,
+			Stats:             p.Stats,
+			AttributesVersion: p.AttributesVersion,
+			Buffs:             p.Buffs,
+			Dead:              p.Dead,
+			RespawnAt:         p.RespawnAt,
+		}
+	}
+	e.latestSnap.Store(snap)
+	return snap
+}
+
+// Player returns the player's view in this snapshot. It is safe to call on a nil snapshot.
+func (s *WorldSnapshot) Player(id string) (PlayerSnapshot, bool) {
+	if s == nil {
+		return PlayerSnapshot{}, false
+	}
+	p, ok := s.players[id]
+	return p, ok
+}
+
+// Cell returns the snapshot of a single cell.
+func (s *WorldSnapshot) Cell(key spatial.CellKey) (*CellSnapshot, bool) {
+	if s == nil {
+		return nil, false
+	}
+	c, ok := s.cells[key]
+	return c, ok
+}
+
+// QueryAOI mirrors Engine.QueryAOI against the snapshot: entities in the 3x3
+// neighborhood of pos within radius, excluding excludeID. It takes no locks.
+func (s *WorldSnapshot) QueryAOI(pos spatial.Vec2, radius float64, excludeID string) []SnapshotEntity {
+	if s == nil || radius <= 0 {
+		return nil
+	}
+	cx, cz := spatial.WorldToCell(pos.X, pos.Z, s.cellSize)
+	r2 := radius * radius
+	const eps = 1e-9 // same boundary tolerance as Engine.QueryAOI
+	out := make([]SnapshotEntity, 0, 16)
+	for _, k := range spatial.Neighbors3x3(spatial.CellKey{Cx: cx, Cz: cz}) {
+		cell, ok := s.cells[k]
+		if !ok {
+			continue
+		}
+		for _, se := range cell.Entities {
+			if se.ID == excludeID {
+				continue
+			}
+			if spatial.Dist2(se.Pos, pos) <= r2+eps {
+				out = append(out, se)
+			}
+		}
+	}
+	if s.eng != nil {
+		atomic.AddInt64(&s.eng.met.aoiQueries, 1)
+		atomic.AddInt64(&s.eng.met.aoiEntities, int64(len(out)))
+	}
+	return out
+}
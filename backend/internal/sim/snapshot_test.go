@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+func TestSnapshot_MatchesEngineQueryAOI(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("a", "A", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("b", "B", spatial.Vec2{X: 4, Z: 1})
+	e.DevSpawn("c", "C", spatial.Vec2{X: 11, Z: 1}) // neighbor cell, within radius of b
+	e.DevSpawn("d", "D", spatial.Vec2{X: 30, Z: 30})
+
+	snap := e.PublishSnapshot()
+	if e.LatestSnapshot() != snap {
+		t.Fatal("expected published snapshot to be the latest")
+	}
+	for _, pos := range []spatial.Vec2{{X: 1, Z: 1}, {X: 4, Z: 1}, {X: 9, Z: 0}, {X: 30, Z: 30}} {
+		want := ids(e.QueryAOI(pos, 5, ""))
+		var got []string
+		for _, se := range snap.QueryAOI(pos, 5, "") {
+			got = append(got, se.ID)
+		}
+		sort.Strings(got)
+		if len(got) != len(want) {
+			t.Fatalf("at %v: snapshot AOI %v, engine AOI %v", pos, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("at %v: snapshot AOI %v, engine AOI %v", pos, got, want)
+			}
+		}
+	}
+}
+
+func ids(ents []Entity) []string {
+	out := make([]string, 0, len(ents))
+	for _, e := range ents {
+		out = append(out, e.ID)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestSnapshot_ImmutableAfterPublish(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("p1", "Alice", spatial.Vec2{X: 1, Z: 1})
+	e.DevSetVelocity("p1", spatial.Vec2{X: 1})
+	snap := e.PublishSnapshot()
+
+	e.Step(time.Second)
+
+	p, ok := snap.Player("p1")
+	if !ok || p.Pos.X != 1 {
+		t.Fatalf("expected snapshot to keep pos 1, got %+v ok=%v", p.Pos, ok)
+	}
+	next := e.PublishSnapshot()
+	if p2, _ := next.Player("p1"); p2.Pos.X != 2 {
+		t.Fatalf("expected new snapshot to see pos 2, got %+v", p2.Pos)
+	}
+	if next.Seq != snap.Seq+1 {
+		t.Fatalf("expected snapshot seq to advance, got %d after %d", next.Seq, snap.Seq)
+	}
+}
+
+func TestSnapshot_ReusesEncodedFragments(t *testing.T) {
+	e := newTestEngine()
+	e.DevSpawn("still", "Still", spatial.Vec2{X: 1, Z: 1})
+	e.DevSpawn("moving", "Moving", spatial.Vec2{X: 2, Z: 2})
+	e.DevSetVelocity("moving", spatial.Vec2{X: 1})
+
+	first := fragments(e.PublishSnapshot())
+	e.Step(100 * time.Millisecond)
+	second := fragments(e.PublishSnapshot())
+
+	if &first["still"][0] != &second["still"][0] {
+		t.Error("expected unchanged entity to reuse its encoded fragment")
+	}
+	if string(first["moving"]) == string(second["moving"]) {
+		t.Error("expected moved entity to be re-encoded")
+	}
+	var wire map[string]any
+	if err := json.Unmarshal(second["still"], &wire); err != nil {
+		t.Fatalf("fragment is not valid JSON: %v", err)
+	}
+	for _, k := range []string{"id", "pos", "vel", "kind", "name"} {
+		if _, ok := wire[k]; !ok {
+			t.Errorf("fragment missing %q: %s", k, second["still"])
+		}
+	}
+}
+
+func fragments(s *WorldSnapshot) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage)
+	c, _ := s.Cell(spatial.CellKey{})
+	for _, se := range c.Entities {
+		out[se.ID] = se.JSON
+	}
+	return out
+}
+
+func TestSnapshot_NilSafe(t *testing.T) {
+	var s *WorldSnapshot
+	if _, ok := s.Player("p1"); ok {
+		t.Error("expected no player in nil snapshot")
+	}
+	if got := s.QueryAOI(spatial.Vec2{}, 5, ""); got != nil {
+		t.Errorf("expected nil AOI from nil snapshot, got %v", got)
+	}
+	if newTestEngine().LatestSnapshot() != nil {
+		t.Error("expected no snapshot before the first publish")
+	}
+}
+
+func TestSnapshot_ClockStamps(t *testing.T) {
+	e := NewEngine(Config{CellSize: 10, AOIRadius: 5, TickHz: 20, SnapshotHz: 10, HandoverHysteresisM: 1})
+	first := e.PublishSnapshot()
+	if first.Tick != 0 {
+		t.Fatalf("tick before stepping = %d, want 0", first.Tick)
+	}
+	for i := 0; i < 3; i++ {
+		e.Step(50 * time.Millisecond)
+	}
+	time.Sleep(time.Millisecond)
+	second := e.PublishSnapshot()
+	if second.Tick != 3 || e.CurrentTick() != 3 {
+		t.Fatalf("tick = %d (engine %d), want 3", second.Tick, e.CurrentTick())
+	}
+	if second.ServerTime <= first.ServerTime {
+		t.Fatalf("server time did not advance: %v -> %v", first.ServerTime, second.ServerTime)
+	}
+}
@@ -0,0 +1,180 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotStackable    = errors.New("item does not stack")
+	ErrInvalidQuantity = errors.New("invalid stack quantity")
+	ErrStackMismatch   = errors.New("items cannot stack together")
+	ErrStackFull       = errors.New("stack is full")
+)
+
+// Generated instance IDs are unique within the process by sequence and across
+// restarts by the process start time.
+var (
+	instanceSeq   atomic.Uint64
+	instanceEpoch = time.Now().UnixNano()
+)
+
+// NewItemInstanceID returns an instance ID for a new item of the template.
+func NewItemInstanceID(templateID ItemTemplateID) ItemInstanceID {
+	return ItemInstanceID(fmt.Sprintf("%s_%x_%d", templateID, instanceEpoch, instanceSeq.Add(1)))
+}
+
+// StacksWith reports whether two instances are the same kind of item and so
+// may share a stack.
+func (i ItemInstance) StacksWith(other ItemInstance) bool {
+	return i.TemplateID == other.TemplateID && i.Durability == other.Durability
+}
+
+// SplitStack moves quantity units of a stack into a new stack with ID newID in
+// the same compartment, placed at the first free position of a grid. Bulk and
+// weight are unchanged.
+func (inv *Inventory) SplitStack(instanceID ItemInstanceID, quantity int, newID ItemInstanceID) error {
+	idx := inv.FindItem(instanceID)
+	if idx < 0 {
+		return ErrItemNotFound
+	}
+	if inv.HasItem(newID) {
+		return ErrDuplicateInstance
+	}
+	item := &inv.Items[idx]
+	if quantity <= 0 || quantity >= item.Instance.Quantity {
+		return ErrInvalidQuantity
+	}
+	if template := inv.resolveTemplate(item, nil); template != nil && !inv.hasRoomFor(item.Compartment, template.Footprint(), 1) {
+		return ErrInsufficientSpace
+	}
+
+	return inv.transact(func() error {
+		item.Instance.Quantity -= quantity
+		split := item.Instance
+		split.InstanceID = newID
+		split.Quantity = quantity
+		return inv.appendItem(split, item.Compartment, item.template)
+	})
+}
+
+// MergeStack moves as many units of the source stack into the target stack as
+// it has room for, removing the source when it empties. Merging into another
+// compartment moves the units' bulk along with them. It returns the number of
+// units moved.
+func (inv *Inventory) MergeStack(sourceID, targetID ItemInstanceID, template *ItemTemplate) (int, error) {
+	srcIdx, dstIdx := inv.FindItem(sourceID), inv.FindItem(targetID)
+	if srcIdx < 0 || dstIdx < 0 {
+		return 0, ErrItemNotFound
+	}
+	if srcIdx == dstIdx {
+		return 0, ErrStackMismatch
+	}
+	src, dst := &inv.Items[srcIdx], &inv.Items[dstIdx]
+	if !src.Instance.StacksWith(dst.Instance) {
+		return 0, ErrStackMismatch
+	}
+	if template.StackSize() == 1 {
+		return 0, ErrNotStackable
+	}
+	moved := min(src.Instance.Quantity, template.StackSize()-dst.Instance.Quantity)
+	if moved <= 0 {
+		return 0, ErrStackFull
+	}
+	if src.Compartment != dst.Compartment {
+		if limit, ok := inv.CompartmentCaps[dst.Compartment]; ok &&
+			inv.GetCompartmentBulk(dst.Compartment, nil)+template.Bulk*moved > limit {
+			return 0, ErrExceedsBulk
+		}
+	}
+
+	dst.Instance.Quantity += moved
+	src.Instance.Quantity -= moved
+	if src.Instance.Quantity == 0 {
+		_ = inv.RemoveItem(sourceID)
+	}
+	return moved, nil
+}
+
+// SplitStack splits quantity units off a player's stack and returns the new
+// stack's instance ID.
+func (pm *PlayerManager) SplitStack(player *Player, instanceID ItemInstanceID, quantity int) (ItemInstanceID, error) {
+	if player.Inventory == nil {
+		return "", ErrItemNotFound
+	}
+	idx := player.Inventory.FindItem(instanceID)
+	if idx < 0 {
+		return "", ErrItemNotFound
+	}
+	templateID := player.Inventory.Items[idx].Instance.TemplateID
+	template, exists := pm.GetItemTemplate(templateID)
+	if !exists {
+		return "", fmt.Errorf("unknown item template: %s", templateID)
+	}
+	if template.StackSize() == 1 {
+		return "", ErrNotStackable
+	}
+
+	newID := NewItemInstanceID(templateID)
+	if err := player.Inventory.SplitStack(instanceID, quantity, newID); err != nil {
+		return "", err
+	}
+	player.InventoryVersion++
+	return newID, nil
+}
+
+// MergeStack merges a player's source stack into the target stack and returns
+// the number of units moved.
+func (pm *PlayerManager) MergeStack(player *Player, sourceID, targetID ItemInstanceID) (int, error) {
+	if player.Inventory == nil {
+		return 0, ErrItemNotFound
+	}
+	idx := player.Inventory.FindItem(sourceID)
+	if idx < 0 {
+		return 0, ErrItemNotFound
+	}
+	templateID := player.Inventory.Items[idx].Instance.TemplateID
+	template, exists := pm.GetItemTemplate(templateID)
+	if !exists {
+		return 0, fmt.Errorf("unknown item template: %s", templateID)
+	}
+
+	moved, err := player.Inventory.MergeStack(sourceID, targetID, template)
+	if err != nil {
+		return 0, err
+	}
+	player.InventoryVersion++
+	return moved, nil
+}
+
+// SplitStack splits a player's stack; see PlayerManager.SplitStack.
+func (e *Engine) SplitStack(playerID string, instanceID ItemInstanceID, quantity int) (ItemInstanceID, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return "", fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return "", ErrDead
+	}
+	return e.playerMgr.SplitStack(player, instanceID, quantity)
+}
+
+// MergeStack merges a player's stacks; see PlayerManager.MergeStack.
+func (e *Engine) MergeStack(playerID string, sourceID, targetID ItemInstanceID) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	player, ok := e.players[playerID]
+	if !ok {
+		return 0, fmt.Errorf("player %s not found", playerID)
+	}
+	if player.Dead {
+		return 0, ErrDead
+	}
+	return e.playerMgr.MergeStack(player, sourceID, targetID)
+}
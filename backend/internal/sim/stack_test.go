@@ -0,0 +1,126 @@
+package sim
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddItemFillsStacksBeforeCreatingNewOnes(t *testing.T) {
+	pm := NewPlayerManager()
+	pm.CreateTestItemTemplates()
+	p := &Player{}
+	pm.InitializePlayer(p)
+
+	add := func(id ItemInstanceID, qty int, c CompartmentType) {
+		t.Helper()
+		if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: id, TemplateID: "potion_health", Quantity: qty, Durability: 1}, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("a", 15, CompartmentBackpack)
+	add("b", 10, CompartmentBackpack) // 5 top up "a", 5 start "b"
+	add("c", 3, CompartmentBelt)      // other compartments are not merged into
+
+	want := map[ItemInstanceID]int{"a": 20, "b": 5, "c": 3}
+	if len(p.Inventory.Items) != len(want) {
+		t.Fatalf("items = %+v, want %v", p.Inventory.Items, want)
+	}
+	for id, qty := range want {
+		idx := p.Inventory.FindItem(id)
+		if idx < 0 || p.Inventory.Items[idx].Instance.Quantity != qty {
+			t.Fatalf("stack %s: idx=%d items=%+v, want quantity %d", id, idx, p.Inventory.Items, qty)
+		}
+	}
+
+	// More than a full stack is split across stacks with fresh IDs.
+	p.Inventory.CompartmentCaps[CompartmentCraftBag] = 50
+	add("d", 45, CompartmentCraftBag)
+	stacks := p.Inventory.GetCompartmentContents(CompartmentCraftBag)
+	if len(stacks) != 3 || stacks[0].Instance.InstanceID != "d" {
+		t.Fatalf("craft bag = %+v, want stacks of 20, 20 and 5 starting with d", stacks)
+	}
+	seen := map[ItemInstanceID]bool{}
+	for i, qty := range []int{20, 20, 5} {
+		if stacks[i].Instance.Quantity != qty || seen[stacks[i].Instance.InstanceID] {
+			t.Fatalf("craft bag = %+v, want stacks of 20, 20 and 5 with distinct IDs", stacks)
+		}
+		seen[stacks[i].Instance.InstanceID] = true
+	}
+	if got := p.Inventory.GetCompartmentBulk(CompartmentCraftBag, nil); got != 45 {
+		t.Fatalf("craft bag bulk = %d, want 45", got)
+	}
+}
+
+func TestSplitAndMergeStacks(t *testing.T) {
+	pm := NewPlayerManager()
+	pm.CreateTestItemTemplates()
+	p := &Player{}
+	pm.InitializePlayer(p)
+	if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: "rocks", TemplateID: "rock_small", Quantity: 12, Durability: 1}, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	weight := p.Inventory.GetTotalWeight(nil)
+
+	for _, qty := range []int{0, 12, 13} {
+		if _, err := pm.SplitStack(p, "rocks", qty); !errors.Is(err, ErrInvalidQuantity) {
+			t.Fatalf("splitting %d: got %v, want ErrInvalidQuantity", qty, err)
+		}
+	}
+	version := p.InventoryVersion
+	split, err := pm.SplitStack(p, "rocks", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if split == "rocks" || p.InventoryVersion != version+1 {
+		t.Fatalf("split id=%s version=%d", split, p.InventoryVersion)
+	}
+	if got := p.Inventory.Items[p.Inventory.FindItem(split)]; got.Instance.Quantity != 4 || got.Compartment != CompartmentBackpack {
+		t.Fatalf("split stack = %+v", got)
+	}
+	if got := p.Inventory.GetTotalWeight(nil); got != weight {
+		t.Fatalf("weight after split = %v, want %v", got, weight)
+	}
+
+	// Merging into the belt moves the bulk there and is capped by it.
+	p.Inventory.CompartmentCaps[CompartmentBelt] = 5
+	if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: "belt", TemplateID: "rock_small", Quantity: 2, Durability: 1}, CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.MergeStack(p, split, "belt"); !errors.Is(err, ErrExceedsBulk) {
+		t.Fatalf("merge over the belt cap: got %v, want ErrExceedsBulk", err)
+	}
+	p.Inventory.CompartmentCaps[CompartmentBelt] = 10
+	moved, err := pm.MergeStack(p, split, "belt")
+	if err != nil || moved != 4 {
+		t.Fatalf("merge = %d, %v", moved, err)
+	}
+	if p.Inventory.HasItem(split) || p.Inventory.GetCompartmentBulk(CompartmentBelt, nil) != 6 {
+		t.Fatalf("expected the emptied stack gone and 6 bulk in the belt, items=%+v", p.Inventory.Items)
+	}
+
+	// A merge moves only what fits in the target.
+	p.Inventory.CompartmentCaps[CompartmentCraftBag] = 50
+	if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: "more", TemplateID: "rock_small", Quantity: 45, Durability: 1}, CompartmentCraftBag); err != nil {
+		t.Fatal(err)
+	}
+	moved, err = pm.MergeStack(p, "rocks", "more")
+	if err != nil || moved != 5 {
+		t.Fatalf("partial merge = %d, %v", moved, err)
+	}
+	if got := p.Inventory.Items[p.Inventory.FindItem("rocks")].Instance.Quantity; got != 3 {
+		t.Fatalf("source left with %d, want 3", got)
+	}
+	if _, err := pm.MergeStack(p, "rocks", "more"); !errors.Is(err, ErrStackFull) {
+		t.Fatalf("merge into a full stack: got %v, want ErrStackFull", err)
+	}
+
+	if err := pm.AddItemToInventory(p, ItemInstance{InstanceID: "sword", TemplateID: "sword_iron", Quantity: 1, Durability: 1}, CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.MergeStack(p, "rocks", "sword"); !errors.Is(err, ErrStackMismatch) {
+		t.Fatalf("merge into another kind: got %v, want ErrStackMismatch", err)
+	}
+	if _, err := pm.SplitStack(p, "sword", 1); !errors.Is(err, ErrNotStackable) {
+		t.Fatalf("split a sword: got %v, want ErrNotStackable", err)
+	}
+}
@@ -11,6 +11,8 @@ type EntityKind int
 const (
 	KindPlayer EntityKind = iota
 	KindBot
+	KindCorpse     // left where an entity died; see death.go
+	KindGroundItem // an item lying in the world; see ground.go
 )
 
 type Entity struct {
@@ -18,8 +20,11 @@ type Entity struct {
 	Kind EntityKind
 	Pos  spatial.Vec2
 	Vel  spatial.Vec2
-	Yaw  float64
+	Yaw  float64 // facing in radians; 0 faces +Z, pi/2 faces +X
 	Name string
+	// Health pool; for players MaxHealth follows Stats.MaxHealth
+	Health    float64
+	MaxHealth float64
 }
 
 type Player struct {
@@ -34,11 +39,34 @@ type Player struct {
 	Inventory *Inventory     `json:"inventory"`
 	Equipment *Equipment     `json:"equipment"`
 	Skills    map[string]int `json:"skills"` // skill_name -> level
+	// XP toward each skill's next level and recent level-ups (see skills.go)
+	SkillXP    map[string]float64 `json:"skill_xp"`
+	LevelUps   []SkillLevelUp     `json:"-"`
+	levelUpSeq int64
 
 	// Delta tracking for efficient state updates
 	InventoryVersion int64 `json:"-"` // Increment when inventory changes
 	EquipmentVersion int64 `json:"-"` // Increment when equipment changes
-	SkillsVersion    int64 `json:"-"` // Increment when skills change
+	SkillsVersion    int64 `json:"-"` // Increment when skill levels change
+	SkillXPVersion   int64 `json:"-"` // Increment when skill XP changes
+
+	// Attributes: stamina and stats derived from equipment and skills (see attributes.go)
+	Stamina           float64 `json:"stamina"`
+	Stats             Stats   `json:"stats"`
+	AttributesVersion int64   `json:"-"` // Increment when health, stamina or stats change
+	statsBasis        statsBasis
+
+	// Combat
+	AttackReadyAt time.Time `json:"-"` // next attack allowed at; set from the weapon's cooldown
+
+	// Consumables: cooldown per item category and active buffs (see consumable.go)
+	UseCooldowns map[ItemCategory]time.Time `json:"-"`
+	Buffs        []Buff                     `json:"-"`
+	buffsVersion int64
+
+	// Death: a dead player cannot move, act or regenerate until RespawnAt (see death.go)
+	Dead      bool      `json:"dead"`
+	RespawnAt time.Time `json:"-"`
 }
 
 type Config struct {
@@ -52,4 +80,14 @@ type Config struct {
 	MaxBots              int // global cap across all cells
 	// Debug settings
 	DebugSnapshot bool // enable snapshot logging
+	// Death and respawn
+	Death DeathConfig
+	World *World // spawn points; nil uses DefaultWorld
+	// SkillCurves are the level curves by skill; nil uses DefaultSkillCurves
+	SkillCurves map[string]SkillCurve
+	// Items dropped into the world
+	Ground GroundConfig
+	// InventoryGrids lays out the listed compartments of every player's
+	// inventory as grids; other compartments stay unordered.
+	InventoryGrids map[CompartmentType]GridSize
 }
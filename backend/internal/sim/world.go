@@ -0,0 +1,73 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"prototype-game/backend/internal/spatial"
+)
+
+// World is static world data loaded at startup.
+type World struct {
+	SpawnPoints []SpawnPoint `json:"spawn_points"`
+}
+
+// SpawnPoint is a place where dead players come back.
+type SpawnPoint struct {
+	Name string       `json:"name"`
+	Pos  spatial.Vec2 `json:"pos"`
+}
+
+// DefaultWorld has a single spawn point at the origin, where new players start.
+func DefaultWorld() *World {
+	return &World{SpawnPoints: []SpawnPoint{{Name: "origin"}}}
+}
+
+// LoadWorld reads world data from a JSON file, e.g.
+//
+//	{"spawn_points": [{"name": "village", "pos": {"x": 0, "z": 0}}]}
+func LoadWorld(path string) (*World, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var w World
+	if err := json.Unmarshal(bs, &w); err != nil {
+		return nil, fmt.Errorf("world %s: %w", path, err)
+	}
+	if err := w.validate(); err != nil {
+		return nil, fmt.Errorf("world %s: %w", path, err)
+	}
+	return &w, nil
+}
+
+func (w *World) validate() error {
+	if len(w.SpawnPoints) == 0 {
+		return errors.New("at least one spawn point is required")
+	}
+	return nil
+}
+
+// SpawnPoint returns the spawn point with the given name.
+func (w *World) SpawnPoint(name string) (SpawnPoint, bool) {
+	for _, sp := range w.SpawnPoints {
+		if sp.Name == name {
+			return sp, true
+		}
+	}
+	return SpawnPoint{}, false
+}
+
+// NearestSpawn returns the spawn point closest to pos; ties go to the first listed.
+func (w *World) NearestSpawn(pos spatial.Vec2) SpawnPoint {
+	best := w.SpawnPoints[0]
+	bestD2 := spatial.Dist2(best.Pos, pos)
+	for _, sp := range w.SpawnPoints[1:] {
+		if d2 := spatial.Dist2(sp.Pos, pos); d2 < bestD2 {
+			best, bestD2 = sp, d2
+		}
+	}
+	return best
+}
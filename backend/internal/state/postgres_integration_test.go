@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
@@ -71,6 +72,27 @@ func TestPostgresStore_Integration(t *testing.T) {
 	}
 }
 
+func TestPostgresResumeStore_Integration(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("Integration test requires PostgreSQL connection (set POSTGRES_TEST_DSN)")
+	}
+
+	store, err := NewPostgresResumeStore(dsn)
+	if err != nil {
+		t.Fatalf("Failed to create PostgreSQL resume store: %v", err)
+	}
+	defer store.Close()
+
+	// Start from an empty table so earlier runs do not skew the purge count
+	if _, err := store.PurgeExpired(context.Background(), time.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("Failed to clear resume tokens: %v", err)
+	}
+	testResumeStoreTakeOnce(t, store)
+	testResumeStoreUpdate(t, store)
+	testResumeStorePurge(t, store)
+}
+
 func TestInventoryPersistence_Integration(t *testing.T) {
 	store := NewMemStore()
 	ctx := context.Background()
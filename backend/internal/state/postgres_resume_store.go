@@ -0,0 +1,155 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostgresResumeStore implements ResumeStore using PostgreSQL so resume tokens
+// can be shared across sim instances.
+type PostgresResumeStore struct {
+	db         *sql.DB
+	putStmt    *sql.Stmt
+	updateStmt *sql.Stmt
+	getStmt    *sql.Stmt
+	takeStmt   *sql.Stmt
+}
+
+// NewPostgresResumeStore creates a PostgreSQL-backed resume token store
+func NewPostgresResumeStore(dsn string) (*PostgresResumeStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	store := &PostgresResumeStore{db: db}
+	if err := store.createSchema(); err != nil {
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := store.prepareStatements(); err != nil {
+		return nil, fmt.Errorf("failed to prepare statements: %w", err)
+	}
+	return store, nil
+}
+
+// createSchema creates the resume token table if it doesn't exist
+func (ps *PostgresResumeStore) createSchema() error {
+	_, err := ps.db.Exec(`
+		CREATE TABLE IF NOT EXISTS resume_tokens (
+			token_key TEXT PRIMARY KEY,
+			player_id TEXT NOT NULL,
+			last_seq INTEGER NOT NULL DEFAULT 0,
+			expires TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_resume_tokens_expires ON resume_tokens(expires);
+	`)
+	return err
+}
+
+// prepareStatements prepares the token statements
+func (ps *PostgresResumeStore) prepareStatements() error {
+	var err error
+
+	ps.putStmt, err = ps.db.Prepare(`
+		INSERT INTO resume_tokens (token_key, player_id, last_seq, expires)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token_key) DO UPDATE SET
+			player_id = EXCLUDED.player_id,
+			last_seq = EXCLUDED.last_seq,
+			expires = EXCLUDED.expires
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare put statement: %w", err)
+	}
+
+	// A plain UPDATE never recreates a token another instance already took
+	ps.updateStmt, err = ps.db.Prepare(`
+		UPDATE resume_tokens SET player_id = $2, last_seq = $3, expires = $4
+		WHERE token_key = $1
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+
+	ps.getStmt, err = ps.db.Prepare(`
+		SELECT player_id, last_seq, expires FROM resume_tokens WHERE token_key = $1
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare get statement: %w", err)
+	}
+
+	// DELETE ... RETURNING makes consumption atomic across instances
+	ps.takeStmt, err = ps.db.Prepare(`
+		DELETE FROM resume_tokens WHERE token_key = $1
+		RETURNING player_id, last_seq, expires
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare take statement: %w", err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresResumeStore) Put(ctx context.Context, rec ResumeRecord) error {
+	if _, err := ps.putStmt.ExecContext(ctx, rec.Key, rec.PlayerID, rec.LastSeq, rec.Expires); err != nil {
+		return fmt.Errorf("failed to save resume token: %w", err)
+	}
+	return nil
+}
+
+func (ps *PostgresResumeStore) Update(ctx context.Context, rec ResumeRecord) (bool, error) {
+	res, err := ps.updateStmt.ExecContext(ctx, rec.Key, rec.PlayerID, rec.LastSeq, rec.Expires)
+	if err != nil {
+		return false, fmt.Errorf("failed to update resume token: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ps *PostgresResumeStore) Get(ctx context.Context, key string) (ResumeRecord, bool, error) {
+	return ps.scan(ps.getStmt.QueryRowContext(ctx, key), key)
+}
+
+func (ps *PostgresResumeStore) Take(ctx context.Context, key string) (ResumeRecord, bool, error) {
+	return ps.scan(ps.takeStmt.QueryRowContext(ctx, key), key)
+}
+
+func (ps *PostgresResumeStore) scan(row *sql.Row, key string) (ResumeRecord, bool, error) {
+	rec := ResumeRecord{Key: key}
+	if err := row.Scan(&rec.PlayerID, &rec.LastSeq, &rec.Expires); err != nil {
+		if err == sql.ErrNoRows {
+			return ResumeRecord{}, false, nil
+		}
+		return ResumeRecord{}, false, fmt.Errorf("failed to load resume token: %w", err)
+	}
+	return rec, true, nil
+}
+
+func (ps *PostgresResumeStore) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	res, err := ps.db.ExecContext(ctx, `DELETE FROM resume_tokens WHERE expires < $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge resume tokens: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Close closes the database connection
+func (ps *PostgresResumeStore) Close() error {
+	for _, stmt := range []*sql.Stmt{ps.putStmt, ps.updateStmt, ps.getStmt, ps.takeStmt} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return ps.db.Close()
+}
@@ -67,9 +67,13 @@ func (ps *PostgresStore) createSchema() error {
 			skills_data JSONB,
 			cooldown_timers JSONB,
 			encumbrance_config JSONB,
+			attributes_data JSONB,
 			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 		);
 
+		-- Columns added after the initial schema
+		ALTER TABLE player_state ADD COLUMN IF NOT EXISTS attributes_data JSONB;
+
 		-- Index for efficient lookups
 		CREATE INDEX IF NOT EXISTS idx_player_state_updated ON player_state(updated);
 		CREATE INDEX IF NOT EXISTS idx_player_state_version ON player_state(version);
@@ -87,8 +91,9 @@ func (ps *PostgresStore) prepareStatements() error {
 	ps.saveStmt, err = ps.db.Prepare(`
 		INSERT INTO player_state (
 			player_id, pos_x, pos_z, logins, updated, version,
-			inventory_data, equipment_data, skills_data, cooldown_timers, encumbrance_config
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			inventory_data, equipment_data, skills_data, cooldown_timers, encumbrance_config,
+			attributes_data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (player_id) DO UPDATE SET
 			pos_x = EXCLUDED.pos_x,
 			pos_z = EXCLUDED.pos_z,
@@ -99,7 +104,8 @@ func (ps *PostgresStore) prepareStatements() error {
 			equipment_data = EXCLUDED.equipment_data,
 			skills_data = EXCLUDED.skills_data,
 			cooldown_timers = EXCLUDED.cooldown_timers,
-			encumbrance_config = EXCLUDED.encumbrance_config
+			encumbrance_config = EXCLUDED.encumbrance_config,
+			attributes_data = EXCLUDED.attributes_data
 		WHERE player_state.version = $6
 		RETURNING version
 	`)
@@ -110,7 +116,8 @@ func (ps *PostgresStore) prepareStatements() error {
 	// Load statement
 	ps.loadStmt, err = ps.db.Prepare(`
 		SELECT pos_x, pos_z, logins, updated, version,
-			   inventory_data, equipment_data, skills_data, cooldown_timers, encumbrance_config
+			   inventory_data, equipment_data, skills_data, cooldown_timers, encumbrance_config,
+			   attributes_data
 		FROM player_state
 		WHERE player_id = $1
 	`)
@@ -124,11 +131,12 @@ func (ps *PostgresStore) prepareStatements() error {
 // Load retrieves player state from PostgreSQL
 func (ps *PostgresStore) Load(ctx context.Context, playerID string) (PlayerState, bool, error) {
 	var state PlayerState
-	var inventoryData, equipmentData, skillsData, cooldownTimers, encumbranceConfig sql.NullString
+	var inventoryData, equipmentData, skillsData, cooldownTimers, encumbranceConfig, attributesData sql.NullString
 
 	err := ps.loadStmt.QueryRowContext(ctx, playerID).Scan(
 		&state.Pos.X, &state.Pos.Z, &state.Logins, &state.Updated, &state.Version,
 		&inventoryData, &equipmentData, &skillsData, &cooldownTimers, &encumbranceConfig,
+		&attributesData,
 	)
 
 	if err != nil {
@@ -154,6 +162,9 @@ func (ps *PostgresStore) Load(ctx context.Context, playerID string) (PlayerState
 	if encumbranceConfig.Valid {
 		state.EncumbranceConfig = json.RawMessage(encumbranceConfig.String)
 	}
+	if attributesData.Valid {
+		state.AttributesData = json.RawMessage(attributesData.String)
+	}
 
 	return state, true, nil
 }
@@ -161,7 +172,7 @@ func (ps *PostgresStore) Load(ctx context.Context, playerID string) (PlayerState
 // Save persists player state to PostgreSQL with optimistic locking
 func (ps *PostgresStore) Save(ctx context.Context, playerID string, st PlayerState) error {
 	// Convert json.RawMessage to nullable strings for database storage
-	var inventoryData, equipmentData, skillsData, cooldownTimers, encumbranceConfig sql.NullString
+	var inventoryData, equipmentData, skillsData, cooldownTimers, encumbranceConfig, attributesData sql.NullString
 
 	if len(st.InventoryData) > 0 {
 		inventoryData = sql.NullString{String: string(st.InventoryData), Valid: true}
@@ -178,11 +189,15 @@ func (ps *PostgresStore) Save(ctx context.Context, playerID string, st PlayerSta
 	if len(st.EncumbranceConfig) > 0 {
 		encumbranceConfig = sql.NullString{String: string(st.EncumbranceConfig), Valid: true}
 	}
+	if len(st.AttributesData) > 0 {
+		attributesData = sql.NullString{String: string(st.AttributesData), Valid: true}
+	}
 
 	var newVersion int64
 	err := ps.saveStmt.QueryRowContext(ctx,
 		playerID, st.Pos.X, st.Pos.Z, st.Logins, st.Updated, st.Version,
 		inventoryData, equipmentData, skillsData, cooldownTimers, encumbranceConfig,
+		attributesData,
 	).Scan(&newVersion)
 
 	if err != nil {
@@ -0,0 +1,212 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ResumeRecord is the server-side state behind a session resume token.
+type ResumeRecord struct {
+	Key      string    `json:"key"` // opaque lookup key derived from the token
+	PlayerID string    `json:"player_id"`
+	LastSeq  int       `json:"last_seq"` // last input sequence acked to the client
+	Expires  time.Time `json:"expires"`
+}
+
+// ResumeStore persists resume records. Take must be atomic so a token can be
+// consumed at most once even when several servers share the store, and Update
+// must never bring a taken record back.
+type ResumeStore interface {
+	Put(ctx context.Context, rec ResumeRecord) error
+	Update(ctx context.Context, rec ResumeRecord) (bool, error)
+	Get(ctx context.Context, key string) (ResumeRecord, bool, error)
+	Take(ctx context.Context, key string) (ResumeRecord, bool, error)
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// MemResumeStore is an in-memory ResumeStore for development/testing.
+type MemResumeStore struct {
+	mu   sync.Mutex
+	data map[string]ResumeRecord
+}
+
+func NewMemResumeStore() *MemResumeStore {
+	return &MemResumeStore{data: make(map[string]ResumeRecord)}
+}
+
+func (m *MemResumeStore) Put(_ context.Context, rec ResumeRecord) error {
+	m.mu.Lock()
+	m.data[rec.Key] = rec
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemResumeStore) Update(_ context.Context, rec ResumeRecord) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[rec.Key]; !ok {
+		return false, nil
+	}
+	m.data[rec.Key] = rec
+	return true, nil
+}
+
+func (m *MemResumeStore) Get(_ context.Context, key string) (ResumeRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.data[key]
+	return rec, ok, nil
+}
+
+func (m *MemResumeStore) Take(_ context.Context, key string) (ResumeRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.data[key]
+	if ok {
+		delete(m.data, key)
+	}
+	return rec, ok, nil
+}
+
+func (m *MemResumeStore) PurgeExpired(_ context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for k, rec := range m.data {
+		if now.After(rec.Expires) {
+			delete(m.data, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// FileResumeStore is a ResumeStore backed by a JSON file. Every change is
+// written through to disk so tokens survive a restart.
+type FileResumeStore struct {
+	mu       sync.Mutex
+	data     map[string]ResumeRecord
+	filePath string
+}
+
+// NewFileResumeStore creates a file-backed resume store, loading any existing records.
+func NewFileResumeStore(filePath string) (*FileResumeStore, error) {
+	fs := &FileResumeStore{data: make(map[string]ResumeRecord), filePath: filePath}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(&fs.data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filePath, err)
+	}
+	return fs, nil
+}
+
+func (fs *FileResumeStore) Put(_ context.Context, rec ResumeRecord) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	prev, existed := fs.data[rec.Key]
+	fs.data[rec.Key] = rec
+	if err := fs.writeLocked(); err != nil {
+		// Keep memory consistent with disk
+		if existed {
+			fs.data[rec.Key] = prev
+		} else {
+			delete(fs.data, rec.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (fs *FileResumeStore) Update(_ context.Context, rec ResumeRecord) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	prev, ok := fs.data[rec.Key]
+	if !ok {
+		return false, nil
+	}
+	fs.data[rec.Key] = rec
+	if err := fs.writeLocked(); err != nil {
+		fs.data[rec.Key] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+func (fs *FileResumeStore) Get(_ context.Context, key string) (ResumeRecord, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	rec, ok := fs.data[key]
+	return rec, ok, nil
+}
+
+func (fs *FileResumeStore) Take(_ context.Context, key string) (ResumeRecord, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	rec, ok := fs.data[key]
+	if !ok {
+		return ResumeRecord{}, false, nil
+	}
+	delete(fs.data, key)
+	if err := fs.writeLocked(); err != nil {
+		fs.data[key] = rec
+		return ResumeRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (fs *FileResumeStore) PurgeExpired(_ context.Context, now time.Time) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	n := 0
+	for k, rec := range fs.data {
+		if now.After(rec.Expires) {
+			delete(fs.data, k)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, fs.writeLocked()
+}
+
+// writeLocked atomically rewrites the file (caller must hold lock).
+func (fs *FileResumeStore) writeLocked() error {
+	tempPath := fs.filePath + ".tmp"
+
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if err := json.NewEncoder(file).Encode(fs.data); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, fs.filePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to move temp file: %w", err)
+	}
+	return nil
+}
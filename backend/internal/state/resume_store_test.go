@@ -0,0 +1,130 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testResumeStoreTakeOnce(t *testing.T, store ResumeStore) {
+	ctx := context.Background()
+	rec := ResumeRecord{Key: "k1", PlayerID: "player1", LastSeq: 7, Expires: time.Now().Add(time.Minute)}
+	if err := store.Put(ctx, rec); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	if got, ok, err := store.Get(ctx, "k1"); err != nil || !ok || got.LastSeq != 7 {
+		t.Fatalf("get: rec=%+v ok=%v err=%v", got, ok, err)
+	}
+	got, ok, err := store.Take(ctx, "k1")
+	if err != nil || !ok {
+		t.Fatalf("take: ok=%v err=%v", ok, err)
+	}
+	if got.PlayerID != "player1" || got.LastSeq != 7 {
+		t.Errorf("unexpected record: %+v", got)
+	}
+	if _, ok, _ := store.Take(ctx, "k1"); ok {
+		t.Fatal("expected record to be consumed by the first take")
+	}
+}
+
+func testResumeStoreUpdate(t *testing.T, store ResumeStore) {
+	ctx := context.Background()
+	rec := ResumeRecord{Key: "u1", PlayerID: "player1", LastSeq: 1, Expires: time.Now().Add(time.Minute)}
+	if err := store.Put(ctx, rec); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	rec.LastSeq = 2
+	if ok, err := store.Update(ctx, rec); err != nil || !ok {
+		t.Fatalf("update: ok=%v err=%v", ok, err)
+	}
+	if got, _, _ := store.Take(ctx, "u1"); got.LastSeq != 2 {
+		t.Fatalf("expected updated seq 2, got %+v", got)
+	}
+	if ok, err := store.Update(ctx, rec); err != nil || ok {
+		t.Fatalf("update after take: ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := store.Get(ctx, "u1"); ok {
+		t.Fatal("update must not recreate a taken record")
+	}
+}
+
+func testResumeStorePurge(t *testing.T, store ResumeStore) {
+	ctx := context.Background()
+	now := time.Now()
+	_ = store.Put(ctx, ResumeRecord{Key: "old", PlayerID: "p", Expires: now.Add(-time.Second)})
+	_ = store.Put(ctx, ResumeRecord{Key: "new", PlayerID: "p", Expires: now.Add(time.Minute)})
+
+	n, err := store.PurgeExpired(ctx, now)
+	if err != nil || n != 1 {
+		t.Fatalf("purge: n=%d err=%v", n, err)
+	}
+	if _, ok, _ := store.Get(ctx, "old"); ok {
+		t.Error("expected expired record to be purged")
+	}
+	if _, ok, _ := store.Get(ctx, "new"); !ok {
+		t.Error("expected live record to remain")
+	}
+}
+
+func TestMemResumeStore(t *testing.T) {
+	testResumeStoreTakeOnce(t, NewMemResumeStore())
+	testResumeStoreUpdate(t, NewMemResumeStore())
+	testResumeStorePurge(t, NewMemResumeStore())
+}
+
+func TestFileResumeStore(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "resume_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store, err := NewFileResumeStore(filepath.Join(tmpDir, "a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResumeStoreTakeOnce(t, store)
+	testResumeStoreUpdate(t, store)
+
+	store, err = NewFileResumeStore(filepath.Join(tmpDir, "b.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResumeStorePurge(t, store)
+}
+
+func TestFileResumeStore_SurvivesRestart(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "resume_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "nested", "resume.json")
+	ctx := context.Background()
+
+	store, err := NewFileResumeStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put(ctx, ResumeRecord{Key: "a", PlayerID: "player1", LastSeq: 3, Expires: time.Now().Add(time.Minute)})
+	_ = store.Put(ctx, ResumeRecord{Key: "b", PlayerID: "player2", LastSeq: 4, Expires: time.Now().Add(time.Minute)})
+	if _, _, err := store.Take(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileResumeStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	rec, ok, _ := reloaded.Take(ctx, "a")
+	if !ok || rec.PlayerID != "player1" || rec.LastSeq != 3 {
+		t.Fatalf("expected record to survive restart, got %+v ok=%v", rec, ok)
+	}
+	if _, ok, _ := reloaded.Take(ctx, "b"); ok {
+		t.Fatal("a consumed token must stay consumed after restart")
+	}
+}
@@ -23,6 +23,7 @@ type PlayerState struct {
 	SkillsData        json.RawMessage `json:"skills_data"`        // Serialized skills state
 	CooldownTimers    json.RawMessage `json:"cooldown_timers"`    // Serialized equipment cooldowns
 	EncumbranceConfig json.RawMessage `json:"encumbrance_config"` // Weight/bulk limits
+	AttributesData    json.RawMessage `json:"attributes_data"`    // Health and stamina; empty means full
 }
 
 // Store is a minimal interface for persisting player state.
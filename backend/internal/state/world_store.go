@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WorldState captures persistent state of the world itself rather than of
+// any one player.
+type WorldState struct {
+	Updated     time.Time       `json:"updated"`
+	GroundItems json.RawMessage `json:"ground_items"` // Serialized items lying in the world
+}
+
+// WorldStore persists the state of one world.
+type WorldStore interface {
+	LoadWorld(ctx context.Context) (WorldState, bool, error)
+	SaveWorld(ctx context.Context, st WorldState) error
+}
+
+// MemWorldStore is an in-memory WorldStore for development/testing.
+type MemWorldStore struct {
+	mu    sync.RWMutex
+	st    WorldState
+	saved bool
+}
+
+func NewMemWorldStore() *MemWorldStore { return &MemWorldStore{} }
+
+func (m *MemWorldStore) LoadWorld(_ context.Context) (WorldState, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.st, m.saved, nil
+}
+
+func (m *MemWorldStore) SaveWorld(_ context.Context, st WorldState) error {
+	m.mu.Lock()
+	m.st, m.saved = st, true
+	m.mu.Unlock()
+	return nil
+}
+
+// FileWorldStore is a WorldStore backed by a JSON file. Every save is written
+// through to disk.
+type FileWorldStore struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+// NewFileWorldStore creates a file-backed world store. The file is created on
+// the first save.
+func NewFileWorldStore(filePath string) (*FileWorldStore, error) {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return &FileWorldStore{filePath: filePath}, nil
+}
+
+func (fs *FileWorldStore) LoadWorld(_ context.Context) (WorldState, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	file, err := os.Open(fs.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return WorldState{}, false, nil
+		}
+		return WorldState{}, false, fmt.Errorf("failed to open %s: %w", fs.filePath, err)
+	}
+	defer file.Close()
+	var st WorldState
+	if err := json.NewDecoder(file).Decode(&st); err != nil {
+		return WorldState{}, false, fmt.Errorf("failed to decode %s: %w", fs.filePath, err)
+	}
+	return st, true, nil
+}
+
+func (fs *FileWorldStore) SaveWorld(_ context.Context, st WorldState) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// Write to temporary file first for atomicity
+	tempPath := fs.filePath + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if err := json.NewEncoder(file).Encode(st); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tempPath, fs.filePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to move temp file: %w", err)
+	}
+	return nil
+}
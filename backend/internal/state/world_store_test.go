@@ -0,0 +1,49 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testWorldStoreRoundTrip(t *testing.T, store WorldStore) {
+	ctx := context.Background()
+	if _, ok, err := store.LoadWorld(ctx); err != nil || ok {
+		t.Fatalf("empty store: ok=%v err=%v", ok, err)
+	}
+	st := WorldState{Updated: time.Now().UTC().Truncate(time.Second), GroundItems: json.RawMessage(`[{"id":"ground-a"}]`)}
+	if err := store.SaveWorld(ctx, st); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, ok, err := store.LoadWorld(ctx)
+	if err != nil || !ok {
+		t.Fatalf("load: ok=%v err=%v", ok, err)
+	}
+	if !got.Updated.Equal(st.Updated) || string(got.GroundItems) != string(st.GroundItems) {
+		t.Fatalf("loaded %+v, want %+v", got, st)
+	}
+}
+
+func TestMemWorldStore(t *testing.T) {
+	testWorldStoreRoundTrip(t, NewMemWorldStore())
+}
+
+func TestFileWorldStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "world.json")
+	store, err := NewFileWorldStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWorldStoreRoundTrip(t, store)
+
+	// A new store over the same file sees what was saved.
+	reopened, err := NewFileWorldStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := reopened.LoadWorld(context.Background()); err != nil || !ok {
+		t.Fatalf("reopened: ok=%v err=%v", ok, err)
+	}
+}
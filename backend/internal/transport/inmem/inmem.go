@@ -0,0 +1,145 @@
+// Package inmem is an in-process transport for session.Server. It carries the
+// exact same JSON protocol as the websocket transport over channels, so
+// integration tests and server-controlled agents can join, send inputs and
+// read state without sockets or build tags:
+//
+//	srv := session.NewServer(auth, eng, store, session.Options{})
+//	c := inmem.Dial(ctx, srv.ServePlayer)
+//	_ = c.Write(ctx, join.Hello{Token: tok})
+//	ack, _ := c.Read(ctx)
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"prototype-game/backend/internal/transport/session"
+)
+
+// bufferSize is how many messages may be in flight in each direction before
+// Write blocks, standing in for socket buffers.
+const bufferSize = 64
+
+// CloseError is returned by Read and Write once either end has closed the pipe.
+type CloseError struct {
+	Code   session.StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("inmem: connection closed: status = %d and reason = %q", e.Code, e.Reason)
+}
+
+// CloseStatus returns the status code the connection was closed with, or -1
+// if err is not a close error.
+func CloseStatus(err error) session.StatusCode {
+	var ce *CloseError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	return -1
+}
+
+// pipe is the state shared by both ends.
+type pipe struct {
+	once   sync.Once
+	closed chan struct{}
+	err    *CloseError
+}
+
+// Conn is one end of an in-memory connection. It implements session.Conn; the
+// client end offers the same methods from the client's point of view.
+type Conn struct {
+	p   *pipe
+	in  chan json.RawMessage
+	out chan json.RawMessage
+}
+
+var _ session.Conn = (*Conn)(nil)
+
+// Pipe returns two connected ends: messages written to one are read from the other.
+func Pipe() (server, client *Conn) {
+	p := &pipe{closed: make(chan struct{})}
+	a := make(chan json.RawMessage, bufferSize)
+	b := make(chan json.RawMessage, bufferSize)
+	return &Conn{p: p, in: a, out: b}, &Conn{p: p, in: b, out: a}
+}
+
+// Dial runs serve on the server end of a new pipe in its own goroutine and
+// returns the client end. Like a transport handler, the server end is closed
+// normally when serve returns.
+func Dial(ctx context.Context, serve func(ctx context.Context, c session.Conn)) *Conn {
+	server, client := Pipe()
+	go func() {
+		defer server.Close(session.StatusNormalClosure, "bye")
+		serve(ctx, server)
+	}()
+	return client
+}
+
+// Read returns the next message from the peer. Messages sent before the pipe
+// was closed are still delivered.
+func (c *Conn) Read(ctx context.Context) (json.RawMessage, error) {
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	default:
+	}
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	case <-c.p.closed:
+		select {
+		case msg := <-c.in:
+			return msg, nil
+		default:
+			return nil, c.p.err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write encodes msg as JSON and sends it to the peer, blocking while the
+// peer's buffer is full.
+func (c *Conn) Write(ctx context.Context, msg any) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-c.p.closed:
+		return c.p.err
+	default:
+	}
+	select {
+	case c.out <- bs:
+		return nil
+	case <-c.p.closed:
+		return c.p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping succeeds immediately while the pipe is open.
+func (c *Conn) Ping(ctx context.Context) error {
+	select {
+	case <-c.p.closed:
+		return c.p.err
+	default:
+		return ctx.Err()
+	}
+}
+
+// Close closes both ends. Only the first close's status is kept.
+func (c *Conn) Close(code session.StatusCode, reason string) error {
+	c.p.once.Do(func() {
+		c.p.err = &CloseError{Code: code, Reason: reason}
+		close(c.p.closed)
+	})
+	return nil
+}
@@ -0,0 +1,646 @@
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+	"prototype-game/backend/internal/state"
+	"prototype-game/backend/internal/transport/session"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	if token == "tok" {
+		return "p1", "Alice", true
+	}
+	return "", "", false
+}
+
+type envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func readEnvelope(t *testing.T, ctx context.Context, c *Conn) envelope {
+	t.Helper()
+	raw, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return env
+}
+
+// joinedSession starts a server and joins it as p1, returning a context bounding
+// the test, the engine and the client end of the session.
+func joinedSession(t *testing.T) (context.Context, *sim.Engine, *Conn) {
+	t.Helper()
+	srv, eng := newServer(t, session.Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	c := Dial(ctx, srv.ServePlayer)
+	t.Cleanup(func() { c.Close(session.StatusNormalClosure, "bye") })
+	if err := c.Write(ctx, join.Hello{Token: "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	if env := readEnvelope(t, ctx, c); env.Type != "join_ack" {
+		t.Fatalf("expected join_ack, got %s", env.Type)
+	}
+	return ctx, eng, c
+}
+
+// readUntil reads messages until one of type typ arrives.
+func readUntil(t *testing.T, ctx context.Context, c *Conn, typ string) envelope {
+	t.Helper()
+	for {
+		if env := readEnvelope(t, ctx, c); env.Type == typ {
+			return env
+		}
+	}
+}
+
+func newServer(t *testing.T, opts session.Options) (*session.Server, *sim.Engine) {
+	t.Helper()
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	t.Cleanup(func() { eng.Stop(context.Background()) })
+	return session.NewServer(fakeAuth{}, eng, nil, opts), eng
+}
+
+func TestPipe_DeliversInOrderAndReportsClose(t *testing.T) {
+	ctx := context.Background()
+	a, b := Pipe()
+	for i := 1; i <= 3; i++ {
+		if err := a.Write(ctx, map[string]int{"n": i}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	a.Close(session.StatusPolicyViolation, "bad")
+	for i := 1; i <= 3; i++ {
+		raw, err := b.Read(ctx)
+		if err != nil {
+			t.Fatalf("messages sent before close must be delivered: %v", err)
+		}
+		var m map[string]int
+		if err := json.Unmarshal(raw, &m); err != nil || m["n"] != i {
+			t.Fatalf("got %s, want n=%d", raw, i)
+		}
+	}
+	if _, err := b.Read(ctx); CloseStatus(err) != session.StatusPolicyViolation {
+		t.Fatalf("expected close status %d, got %v", session.StatusPolicyViolation, err)
+	}
+	if err := b.Write(ctx, "x"); CloseStatus(err) != session.StatusPolicyViolation {
+		t.Fatalf("write after close: %v", err)
+	}
+}
+
+func TestSession_JoinInputAndState(t *testing.T) {
+	ctx, _, c := joinedSession(t)
+	input := map[string]any{"type": "input", "seq": 1, "dt": 0.05, "intent": map[string]float64{"x": 1, "z": 0}}
+	if err := c.Write(ctx, input); err != nil {
+		t.Fatalf("input: %v", err)
+	}
+	for {
+		env := readUntil(t, ctx, c, "state")
+		var st struct {
+			Ack    int `json:"ack"`
+			Player struct {
+				Pos struct{ X float64 } `json:"pos"`
+			} `json:"player"`
+		}
+		if err := json.Unmarshal(env.Data, &st); err != nil {
+			t.Fatalf("decode state: %v", err)
+		}
+		if st.Ack == 1 && st.Player.Pos.X > 0 {
+			return
+		}
+	}
+}
+
+func TestSession_TakeoverClosesOldConnection(t *testing.T) {
+	srv, _ := newServer(t, session.Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	first := Dial(ctx, srv.ServePlayer)
+	defer first.Close(session.StatusNormalClosure, "bye")
+	_ = first.Write(ctx, join.Hello{Token: "tok"})
+	if env := readEnvelope(t, ctx, first); env.Type != "join_ack" {
+		t.Fatalf("expected join_ack, got %s", env.Type)
+	}
+
+	second := Dial(ctx, srv.ServePlayer)
+	defer second.Close(session.StatusNormalClosure, "bye")
+	_ = second.Write(ctx, join.Hello{Token: "tok"})
+
+	replaced := false
+	for {
+		raw, err := first.Read(ctx)
+		if err != nil {
+			if status := CloseStatus(err); status != session.StatusSessionReplaced {
+				t.Fatalf("expected close status %d, got %v", session.StatusSessionReplaced, err)
+			}
+			break
+		}
+		var env envelope
+		_ = json.Unmarshal(raw, &env)
+		replaced = replaced || env.Type == "session_replaced"
+	}
+	if !replaced {
+		t.Fatal("expected session_replaced before close")
+	}
+	if env := readEnvelope(t, ctx, second); env.Type != "join_ack" {
+		t.Fatalf("expected join_ack for new session, got %s", env.Type)
+	}
+}
+
+// takeGatedStore holds writes to the first token it stores until that token is
+// taken (or briefly, if it never is), so a session that writes its token back
+// late cannot hide behind the takeover ordering.
+type takeGatedStore struct {
+	state.ResumeStore
+	mu        sync.Mutex
+	key       string
+	taken     chan struct{}
+	takenOnce sync.Once
+}
+
+func (s *takeGatedStore) gate(key string) {
+	s.mu.Lock()
+	first := s.key == ""
+	if first {
+		s.key = key
+	}
+	s.mu.Unlock()
+	if !first && key == s.key {
+		select {
+		case <-s.taken:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (s *takeGatedStore) Put(ctx context.Context, rec state.ResumeRecord) error {
+	s.gate(rec.Key)
+	return s.ResumeStore.Put(ctx, rec)
+}
+
+func (s *takeGatedStore) Update(ctx context.Context, rec state.ResumeRecord) (bool, error) {
+	s.gate(rec.Key)
+	return s.ResumeStore.Update(ctx, rec)
+}
+
+func (s *takeGatedStore) Take(ctx context.Context, key string) (state.ResumeRecord, bool, error) {
+	rec, ok, err := s.ResumeStore.Take(ctx, key)
+	s.mu.Lock()
+	if key == s.key {
+		s.takenOnce.Do(func() { close(s.taken) })
+	}
+	s.mu.Unlock()
+	return rec, ok, err
+}
+
+func TestSession_ResumedTakeoverKeepsOldTokenConsumed(t *testing.T) {
+	store := &takeGatedStore{ResumeStore: state.NewMemResumeStore(), taken: make(chan struct{})}
+	resume := session.NewResumeManagerWithStore(store, time.Minute)
+	srv, _ := newServer(t, session.Options{Resume: resume})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	firstDone := make(chan struct{})
+	first := Dial(ctx, func(ctx context.Context, c session.Conn) {
+		defer close(firstDone)
+		srv.ServePlayer(ctx, c)
+	})
+	defer first.Close(session.StatusNormalClosure, "bye")
+	_ = first.Write(ctx, join.Hello{Token: "tok"})
+	var ack join.JoinAck
+	if err := json.Unmarshal(readUntil(t, ctx, first, "join_ack").Data, &ack); err != nil || ack.ResumeToken == "" {
+		t.Fatalf("join_ack without a resume token: %+v, %v", ack, err)
+	}
+
+	// The second connection resumes with the first one's token and takes over.
+	second := Dial(ctx, srv.ServePlayer)
+	defer second.Close(session.StatusNormalClosure, "bye")
+	_ = second.Write(ctx, join.Hello{Token: "tok", Resume: ack.ResumeToken})
+	readUntil(t, ctx, second, "join_ack")
+	select {
+	case <-firstDone:
+	case <-ctx.Done():
+		t.Fatal("replaced session did not end")
+	}
+
+	if _, ok := resume.Consume(ack.ResumeToken, "p1"); ok {
+		t.Fatal("the replaced session must not bring back the token used to take over")
+	}
+}
+
+func TestSession_SlowConsumerDisconnectPersistsPlayer(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 200, HandoverHysteresisM: 1})
+	eng.Start()
+	t.Cleanup(func() { eng.Stop(context.Background()) })
+	store := state.NewMemStore()
+	eng.SetPersistenceStore(store)
+	persistCtx, persistCancel := context.WithCancel(context.Background())
+	defer persistCancel()
+	eng.StartPersistence(persistCtx)
+	defer eng.StopPersistence()
+	srv := session.NewServer(fakeAuth{}, eng, store, session.Options{SlowConsumerTimeout: 100 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The client joins and then stops reading, so state backs up until the
+	// session gives up on it.
+	c := Dial(ctx, srv.ServePlayer)
+	defer c.Close(session.StatusNormalClosure, "bye")
+	_ = c.Write(ctx, join.Hello{Token: "tok"})
+	for {
+		if err := c.Ping(ctx); err != nil {
+			if status := CloseStatus(err); status != session.StatusPolicyViolation {
+				t.Fatalf("expected close status %d, got %v", session.StatusPolicyViolation, err)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for {
+		if _, ok, _ := store.Load(ctx, "p1"); ok {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("a slow consumer disconnect must persist the player")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSession_RateLimitedMessagesDoNotKeepSessionAlive(t *testing.T) {
+	srv, _ := newServer(t, session.Options{
+		IdleTimeout: 200 * time.Millisecond,
+		RateLimits: session.RateLimitConfig{
+			Chat:            session.RateLimit{Rate: 0.001, Burst: 1},
+			WarnAfter:       1000,
+			DisconnectAfter: 1000,
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := Dial(ctx, srv.ServePlayer)
+	defer c.Close(session.StatusNormalClosure, "bye")
+	if err := c.Write(ctx, join.Hello{Token: "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	// Every chat after the first is dropped by the limiter, so the session
+	// must still idle out.
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if err := c.Write(ctx, map[string]any{"type": "chat", "text": "spam"}); err != nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("expected the idle timeout to close a session sending only rate-limited messages")
+}
+
+func TestSession_AttackResultAndCombatBroadcast(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	eng.DevSpawn("p2", "Bob", spatial.Vec2{Z: 1})
+
+	attack := map[string]any{"type": "attack", "seq": 1, "target_id": "p2", "dir": map[string]float64{"x": 0, "z": 1}}
+	if err := c.Write(ctx, attack); err != nil {
+		t.Fatalf("attack: %v", err)
+	}
+	var result struct {
+		Success bool            `json:"success"`
+		Code    string          `json:"code"`
+		Event   sim.CombatEvent `json:"event"`
+	}
+	for gotResult := false; ; {
+		env := readEnvelope(t, ctx, c)
+		switch env.Type {
+		case "attack_result":
+			if err := json.Unmarshal(env.Data, &result); err != nil {
+				t.Fatalf("decode attack_result: %v", err)
+			}
+			if !result.Success || (result.Code != "hit" && result.Code != "miss") || result.Event.TargetID != "p2" {
+				t.Fatalf("unexpected attack_result %s", env.Data)
+			}
+			gotResult = true
+		case "combat":
+			var ev sim.CombatEvent
+			if err := json.Unmarshal(env.Data, &ev); err != nil {
+				t.Fatalf("decode combat: %v", err)
+			}
+			if !gotResult || ev.Seq != result.Event.Seq || ev.AttackerID != "p1" {
+				t.Fatalf("unexpected combat event %s", env.Data)
+			}
+			return
+		}
+	}
+}
+
+func TestSession_LevelUpInSkillsDelta(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	need := eng.GetPlayerManager().GetSkillCurve("melee").XPToNext(0)
+	if _, err := eng.GrantSkillXP("p1", "melee", need+1); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		env := readUntil(t, ctx, c, "state")
+		var data struct {
+			Skills   map[string]int     `json:"skills"`
+			SkillXP  map[string]float64 `json:"skill_xp"`
+			LevelUps []sim.SkillLevelUp `json:"level_ups"`
+		}
+		if err := json.Unmarshal(env.Data, &data); err != nil {
+			t.Fatalf("decode state: %v", err)
+		}
+		if len(data.LevelUps) == 0 {
+			continue
+		}
+		if data.LevelUps[0] != (sim.SkillLevelUp{Seq: 1, Skill: "melee", Level: 1}) {
+			t.Fatalf("unexpected level-ups %+v", data.LevelUps)
+		}
+		if data.Skills["melee"] != 1 || data.SkillXP["melee"] != 1 {
+			t.Fatalf("skills delta = %v xp %v, want melee 1 with 1 xp", data.Skills, data.SkillXP)
+		}
+		return
+	}
+}
+
+func TestSession_SkillXPWithoutLevelUpOmitsSkills(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	granted := false
+	for {
+		env := readUntil(t, ctx, c, "state")
+		var data struct {
+			Skills  map[string]int     `json:"skills"`
+			SkillXP map[string]float64 `json:"skill_xp"`
+		}
+		if err := json.Unmarshal(env.Data, &data); err != nil {
+			t.Fatalf("decode state: %v", err)
+		}
+		// Gain XP once the initial skills delta is out of the way.
+		if !granted {
+			if data.Skills != nil {
+				if _, err := eng.GrantSkillXP("p1", "melee", 1); err != nil {
+					t.Fatal(err)
+				}
+				granted = true
+			}
+			continue
+		}
+		if data.SkillXP["melee"] != 1 {
+			continue
+		}
+		if data.Skills != nil {
+			t.Fatalf("XP progress resent the skills map: %s", env.Data)
+		}
+		return
+	}
+}
+
+func TestSession_RepairResult(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	if err := eng.DevAddItemToPlayer("p1", "armor_leather", 1, sim.CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	armor := p.Inventory.Items[0].Instance.InstanceID
+
+	if err := c.Write(ctx, map[string]any{"type": "repair", "seq": 1, "instance_id": armor}); err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	env := readUntil(t, ctx, c, "repair_result")
+	var result struct {
+		InstanceID string `json:"instance_id"`
+		Success    bool   `json:"success"`
+		Code       string `json:"code"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode repair_result: %v", err)
+	}
+	if result.Success || result.Code != "not_damaged" || result.InstanceID != string(armor) {
+		t.Fatalf("unexpected repair_result %s", env.Data)
+	}
+}
+
+func TestSession_UseItemResult(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	if err := eng.DevAddItemToPlayer("p1", "draught_stamina", 2, sim.CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	draught := p.Inventory.Items[0].Instance.InstanceID
+
+	if err := c.Write(ctx, map[string]any{"type": "use_item", "seq": 1, "instance_id": draught}); err != nil {
+		t.Fatalf("use_item: %v", err)
+	}
+	env := readUntil(t, ctx, c, "use_item_result")
+	var result struct {
+		Success    bool   `json:"success"`
+		Code       string `json:"code"`
+		TemplateID string `json:"template_id"`
+		Remaining  int    `json:"remaining"`
+		CooldownMs int64  `json:"cooldown_ms"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode use_item_result: %v", err)
+	}
+	if !result.Success || result.Code != "success" || result.TemplateID != "draught_stamina" || result.Remaining != 1 || result.CooldownMs <= 0 {
+		t.Fatalf("unexpected use_item_result %s", env.Data)
+	}
+}
+
+func TestSession_CommandDedupSurvivesResumeOnAnotherServer(t *testing.T) {
+	// Like separate transports in cmd/sim, two servers share sessions, resume
+	// tokens and the command log.
+	opts := session.Options{
+		Sessions:   session.NewSessionRegistry(session.TakeoverKickOld),
+		Resume:     session.NewResumeManager(time.Minute),
+		CommandLog: session.NewCommandLog(0, time.Minute),
+	}
+	first, eng := newServer(t, opts)
+	second := session.NewServer(fakeAuth{}, eng, nil, opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	firstDone := make(chan struct{})
+	c1 := Dial(ctx, func(ctx context.Context, c session.Conn) {
+		defer close(firstDone)
+		first.ServePlayer(ctx, c)
+	})
+	_ = c1.Write(ctx, join.Hello{Token: "tok"})
+	var ack join.JoinAck
+	if err := json.Unmarshal(readUntil(t, ctx, c1, "join_ack").Data, &ack); err != nil || ack.ResumeToken == "" {
+		t.Fatalf("join_ack without a resume token: %+v, %v", ack, err)
+	}
+	if err := eng.DevAddItemToPlayer("p1", "draught_stamina", 2, sim.CompartmentBelt); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	use := map[string]any{"type": "use_item", "seq": 1, "instance_id": p.Inventory.Items[0].Instance.InstanceID}
+	_ = c1.Write(ctx, use)
+	want := readUntil(t, ctx, c1, "use_item_result")
+	c1.Close(session.StatusNormalClosure, "bye")
+	select {
+	case <-firstDone:
+	case <-ctx.Done():
+		t.Fatal("first session did not end")
+	}
+
+	// The client retries the command after resuming elsewhere; it must not run twice.
+	c2 := Dial(ctx, second.ServePlayer)
+	defer c2.Close(session.StatusNormalClosure, "bye")
+	_ = c2.Write(ctx, join.Hello{Token: "tok", Resume: ack.ResumeToken})
+	readUntil(t, ctx, c2, "join_ack")
+	_ = c2.Write(ctx, use)
+	if got := readUntil(t, ctx, c2, "use_item_result"); string(got.Data) != string(want.Data) {
+		t.Fatalf("expected the cached result %s after resuming, got %s", want.Data, got.Data)
+	}
+}
+
+func TestSession_SplitStackResult(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	if err := eng.DevAddItemToPlayer("p1", "rock_small", 10, sim.CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	rocks := p.Inventory.Items[0].Instance.InstanceID
+
+	if err := c.Write(ctx, map[string]any{"type": "split_stack", "seq": 1, "instance_id": rocks, "quantity": 4}); err != nil {
+		t.Fatalf("split_stack: %v", err)
+	}
+	env := readUntil(t, ctx, c, "stack_result")
+	var result struct {
+		Operation     string `json:"operation"`
+		Success       bool   `json:"success"`
+		Code          string `json:"code"`
+		NewInstanceID string `json:"new_instance_id"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode stack_result: %v", err)
+	}
+	if !result.Success || result.Operation != "split_stack" || result.NewInstanceID == "" {
+		t.Fatalf("unexpected stack_result %s", env.Data)
+	}
+	p, _ = eng.GetPlayer("p1")
+	if idx := p.Inventory.FindItem(sim.ItemInstanceID(result.NewInstanceID)); idx < 0 || p.Inventory.Items[idx].Instance.Quantity != 4 {
+		t.Fatalf("split stack missing from inventory: %+v", p.Inventory.Items)
+	}
+}
+
+func TestSession_MoveItemResult(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	if err := eng.DevAddItemToPlayer("p1", "potion_health", 5, sim.CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	potions := p.Inventory.Items[0].Instance.InstanceID
+
+	if err := c.Write(ctx, map[string]any{"type": "move_item", "seq": 1, "instance_id": potions, "compartment": "belt", "quantity": 2}); err != nil {
+		t.Fatalf("move_item: %v", err)
+	}
+	env := readUntil(t, ctx, c, "move_item_result")
+	var result struct {
+		Success bool   `json:"success"`
+		Code    string `json:"code"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode move_item_result: %v", err)
+	}
+	if !result.Success || result.Code != "success" {
+		t.Fatalf("unexpected move_item_result %s", env.Data)
+	}
+	p, _ = eng.GetPlayer("p1")
+	belt := p.Inventory.GetCompartmentContents(sim.CompartmentBelt)
+	if len(belt) != 1 || belt[0].Instance.Quantity != 2 {
+		t.Fatalf("belt = %+v, want 2 potions", belt)
+	}
+}
+
+func TestSession_DropAndPickUp(t *testing.T) {
+	ctx, eng, c := joinedSession(t)
+	if err := eng.DevAddItemToPlayer("p1", "rock_small", 5, sim.CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	rocks := p.Inventory.Items[0].Instance.InstanceID
+
+	if err := c.Write(ctx, map[string]any{"type": "drop_item", "seq": 1, "instance_id": rocks, "quantity": 2}); err != nil {
+		t.Fatalf("drop_item: %v", err)
+	}
+	env := readUntil(t, ctx, c, "drop_item_result")
+	var dropped struct {
+		Success  bool   `json:"success"`
+		GroundID string `json:"ground_id"`
+	}
+	if err := json.Unmarshal(env.Data, &dropped); err != nil {
+		t.Fatalf("decode drop_item_result: %v", err)
+	}
+	if !dropped.Success || dropped.GroundID == "" {
+		t.Fatalf("unexpected drop_item_result %s", env.Data)
+	}
+	groundID := dropped.GroundID
+
+	if err := c.Write(ctx, map[string]any{"type": "pick_up", "seq": 2, "ground_id": groundID}); err != nil {
+		t.Fatalf("pick_up: %v", err)
+	}
+	env = readUntil(t, ctx, c, "pick_up_result")
+	var result struct {
+		Success     bool     `json:"success"`
+		Quantity    int      `json:"quantity"`
+		InstanceIDs []string `json:"instance_ids"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode pick_up_result: %v", err)
+	}
+	if !result.Success || result.Quantity != 2 || len(result.InstanceIDs) == 0 {
+		t.Fatalf("unexpected pick_up_result %s", env.Data)
+	}
+	// The reported stacks are the ones the client will see in its inventory.
+	p, _ = eng.GetPlayer("p1")
+	for _, id := range result.InstanceIDs {
+		if !p.Inventory.HasItem(sim.ItemInstanceID(id)) {
+			t.Fatalf("pick_up_result names %s, which is not in the inventory", id)
+		}
+	}
+	if _, ok := eng.GroundItem(groundID); ok {
+		t.Fatalf("item %s still on the ground", groundID)
+	}
+}
+
+func TestSession_SortInventoryWithoutGrid(t *testing.T) {
+	ctx, _, c := joinedSession(t)
+
+	if err := c.Write(ctx, map[string]any{"type": "sort_inventory", "seq": 1, "compartment": "belt"}); err != nil {
+		t.Fatalf("sort_inventory: %v", err)
+	}
+	env := readUntil(t, ctx, c, "layout_result")
+	var result struct {
+		Operation   string `json:"operation"`
+		Compartment string `json:"compartment"`
+		Success     bool   `json:"success"`
+		Code        string `json:"code"`
+	}
+	if err := json.Unmarshal(env.Data, &result); err != nil {
+		t.Fatalf("decode layout_result: %v", err)
+	}
+	if result.Success || result.Code != "no_grid" || result.Operation != "sort_inventory" || result.Compartment != "belt" {
+		t.Fatalf("unexpected layout_result %s", env.Data)
+	}
+}
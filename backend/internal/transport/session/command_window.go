@@ -0,0 +1,149 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultCommandWindow is how many recent command sequences are remembered per player.
+const defaultCommandWindow = 128
+
+// isMutatingCommand reports whether a client message type changes game state and
+// must therefore be deduplicated by seq.
+func isMutatingCommand(msgType string) bool {
+	switch msgType {
+	case "equip", "unequip", "repair", "use_item", "move_item", "place_item", "sort_inventory", "split_stack", "merge_stack", "drop_item", "pick_up", "attack":
+		return true
+	default:
+		return false
+	}
+}
+
+// commandVerdict says whether a command should run.
+type commandVerdict int
+
+const (
+	commandNew       commandVerdict = iota // not seen before; execute it
+	commandDuplicate                       // seen within the window; resend the cached reply
+	commandStale                           // older than the window; cannot tell, so refuse it
+)
+
+// commandWindow deduplicates mutating commands by sequence number. It keeps the
+// reply for every sequence within (high-size, high] so a retransmitted command
+// gets its original result back instead of being executed twice.
+type commandWindow struct {
+	mu      sync.Mutex
+	size    int
+	high    int
+	replies map[int]map[string]any
+}
+
+func newCommandWindow(size int) *commandWindow {
+	if size <= 0 {
+		size = defaultCommandWindow
+	}
+	return &commandWindow{size: size, replies: make(map[int]map[string]any)}
+}
+
+// Do runs exec for a new seq and caches its reply; for a seq already in the
+// window it returns the cached reply without running exec. Sequences at or
+// below zero cannot be tracked and always execute.
+func (w *commandWindow) Do(seq int, exec func() map[string]any) (map[string]any, commandVerdict) {
+	if seq <= 0 {
+		return exec(), commandNew
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if reply, ok := w.replies[seq]; ok {
+		return reply, commandDuplicate
+	}
+	if seq <= w.high-w.size {
+		return nil, commandStale
+	}
+	reply := exec()
+	w.replies[seq] = reply
+	if seq > w.high {
+		w.high = seq
+		for s := range w.replies {
+			if s <= w.high-w.size {
+				delete(w.replies, s)
+			}
+		}
+	}
+	return reply, commandNew
+}
+
+// CommandLog holds each player's command window so it outlives a single
+// connection: a resumed session continues the previous window, while a fresh
+// login starts a new one (clients restart their sequence numbers). Share one
+// log across servers so a session resumed on another transport keeps it.
+type CommandLog struct {
+	mu        sync.Mutex
+	size      int
+	ttl       time.Duration
+	windows   map[string]*commandLogEntry
+	lastSweep time.Time
+}
+
+// commandLogEntry tracks which sessions still use a player's window.
+type commandLogEntry struct {
+	w         *commandWindow
+	active    int       // sessions currently using w
+	idleSince time.Time // when the last of them ended
+}
+
+// NewCommandLog creates a log remembering size recent command seqs per player;
+// if size is zero, it defaults to 128. A window nobody has used for ttl is
+// dropped: by then the resume token that could continue it has expired. If ttl
+// is zero, it defaults to 60 seconds like the resume TTL.
+func NewCommandLog(size int, ttl time.Duration) *CommandLog {
+	if ttl <= 0 {
+		ttl = 60 * time.Second
+	}
+	return &CommandLog{size: size, ttl: ttl, windows: make(map[string]*commandLogEntry)}
+}
+
+// window returns the window for playerID, continuing the existing one when
+// resumed. The caller must release it when its session ends.
+func (l *CommandLog) window(playerID string, resumed bool) *commandWindow {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweepLocked(now)
+	if e, ok := l.windows[playerID]; ok && resumed {
+		e.active++
+		return e.w
+	}
+	w := newCommandWindow(l.size)
+	l.windows[playerID] = &commandLogEntry{w: w, active: 1}
+	return w
+}
+
+// release marks a session done with w; once no session uses it, the window
+// is kept for ttl so a resumed session can continue it.
+func (l *CommandLog) release(playerID string, w *commandWindow) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.windows[playerID]; ok && e.w == w {
+		e.active--
+		if e.active <= 0 {
+			e.idleSince = now
+		}
+	}
+	l.sweepLocked(now)
+}
+
+// sweepLocked drops windows idle for longer than ttl, at most once per ttl.
+// l.mu must be held.
+func (l *CommandLog) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < l.ttl {
+		return
+	}
+	l.lastSweep = now
+	for id, e := range l.windows {
+		if e.active <= 0 && now.Sub(e.idleSince) >= l.ttl {
+			delete(l.windows, id)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommandWindow_DuplicateReturnsCachedReply(t *testing.T) {
+	w := newCommandWindow(4)
+	runs := 0
+	exec := func() map[string]any {
+		runs++
+		return map[string]any{"type": "equipment_result", "run": runs}
+	}
+
+	first, v := w.Do(1, exec)
+	if v != commandNew || runs != 1 {
+		t.Fatalf("expected first command to execute, verdict=%v runs=%d", v, runs)
+	}
+	again, v := w.Do(1, exec)
+	if v != commandDuplicate || runs != 1 {
+		t.Fatalf("expected duplicate not to execute, verdict=%v runs=%d", v, runs)
+	}
+	if again["run"] != first["run"] {
+		t.Fatalf("expected cached reply %v, got %v", first, again)
+	}
+}
+
+func TestCommandWindow_Slides(t *testing.T) {
+	w := newCommandWindow(3)
+	noop := func() map[string]any { return map[string]any{} }
+	for seq := 1; seq <= 5; seq++ {
+		w.Do(seq, noop)
+	}
+	// Window now covers seqs 3..5.
+	if _, v := w.Do(4, noop); v != commandDuplicate {
+		t.Fatalf("expected seq 4 to be a duplicate, got %v", v)
+	}
+	if _, v := w.Do(2, noop); v != commandStale {
+		t.Fatalf("expected seq 2 to be stale, got %v", v)
+	}
+	if len(w.replies) != 3 {
+		t.Fatalf("expected window to hold 3 replies, got %d", len(w.replies))
+	}
+	// Out-of-order seqs inside the window still execute once.
+	w2 := newCommandWindow(3)
+	w2.Do(5, noop)
+	if _, v := w2.Do(4, noop); v != commandNew {
+		t.Fatalf("expected in-window out-of-order seq to execute, got %v", v)
+	}
+}
+
+func TestCommandLog_SurvivesResumeOnly(t *testing.T) {
+	l := NewCommandLog(8, time.Minute)
+	w := l.window("p1", false)
+	if l.window("p1", true) != w {
+		t.Fatal("expected resumed session to continue the existing window")
+	}
+	if l.window("p1", false) == w {
+		t.Fatal("expected a fresh login to start a new window")
+	}
+}
+
+func TestCommandLog_DropsWindowsUnusedForTTL(t *testing.T) {
+	l := NewCommandLog(8, 20*time.Millisecond)
+	idle := l.window("p1", false)
+	l.window("p2", false) // still in use
+	l.release("p1", idle)
+	if l.window("p1", true) != idle {
+		t.Fatal("expected a session resumed within the TTL to continue the window")
+	}
+	l.release("p1", idle)
+
+	time.Sleep(30 * time.Millisecond)
+	l.window("p3", false) // any later session sweeps
+
+	l.mu.Lock()
+	_, kept := l.windows["p1"]
+	_, inUse := l.windows["p2"]
+	l.mu.Unlock()
+	if kept {
+		t.Fatal("expected a window unused for longer than the TTL to be dropped")
+	}
+	if !inUse {
+		t.Fatal("expected a window still in use to be kept")
+	}
+}
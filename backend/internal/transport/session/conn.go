@@ -0,0 +1,48 @@
+// Package session implements the client protocol (join, input, commands,
+// state stream, resume, observers) independently of the wire. Transports such
+// as transport/ws accept a connection, wrap it in a Conn and hand it to a
+// Server; the in-memory transport (transport/inmem) drives the same code
+// without sockets for tests and server-side agents.
+package session
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Conn is one client connection carrying JSON messages in both directions.
+// Implementations must allow Write, Ping and Close to be called concurrently
+// with Read and with each other.
+type Conn interface {
+	// Read blocks until the next client message arrives. The connection is
+	// unusable after an error, including ctx expiring.
+	Read(ctx context.Context) (json.RawMessage, error)
+	// Write encodes msg as JSON and sends it to the client.
+	Write(ctx context.Context, msg any) error
+	// Ping checks the client is responsive; its round trip feeds telemetry.
+	Ping(ctx context.Context) error
+	// Close ends the connection, telling the client why.
+	Close(code StatusCode, reason string) error
+}
+
+// StatusCode is the reason a connection was closed. Values follow the
+// WebSocket close codes so transports can pass them through unchanged.
+type StatusCode int
+
+const (
+	StatusNormalClosure   StatusCode = 1000
+	StatusPolicyViolation StatusCode = 1008
+	StatusServiceRestart  StatusCode = 1012
+	StatusTryAgainLater   StatusCode = 1013
+	// StatusSessionReplaced is sent to a connection displaced by a newer login.
+	StatusSessionReplaced StatusCode = 4001
+)
+
+// readJSON reads the next client message into v.
+func readJSON(ctx context.Context, c Conn, v any) error {
+	raw, err := c.Read(ctx)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
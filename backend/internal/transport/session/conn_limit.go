@@ -0,0 +1,59 @@
+package session
+
+import (
+	"net"
+	"sync"
+)
+
+// ConnLimiter caps concurrent connections per remote IP. Transports acquire a
+// slot before accepting a connection and release it when the connection ends;
+// share one limiter across servers so the cap covers every transport.
+type ConnLimiter struct {
+	mu     sync.Mutex
+	max    int // zero disables the cap
+	counts map[string]int
+}
+
+// NewConnLimiter creates a limiter allowing max connections per IP; zero
+// disables the cap.
+func NewConnLimiter(max int) *ConnLimiter {
+	return &ConnLimiter{max: max, counts: make(map[string]int)}
+}
+
+// Acquire reserves a connection slot for ip, returning false if the cap is reached.
+func (l *ConnLimiter) Acquire(ip string) bool {
+	if l.max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[ip] >= l.max {
+		return false
+	}
+	l.counts[ip]++
+	return true
+}
+
+// Release frees a slot previously reserved with Acquire.
+func (l *ConnLimiter) Release(ip string) {
+	if l.max <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[ip] <= 1 {
+		delete(l.counts, ip)
+		return
+	}
+	l.counts[ip]--
+}
+
+// RemoteIP extracts the client IP from a remote address such as
+// http.Request.RemoteAddr or a UDP peer address.
+func RemoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,39 @@
+package session
+
+import "testing"
+
+func TestConnLimiter(t *testing.T) {
+	l := NewConnLimiter(2)
+	if !l.Acquire("1.2.3.4") || !l.Acquire("1.2.3.4") {
+		t.Fatal("expected two connections to be allowed")
+	}
+	if l.Acquire("1.2.3.4") {
+		t.Fatal("expected third connection from same IP to be rejected")
+	}
+	if !l.Acquire("5.6.7.8") {
+		t.Fatal("expected other IP to be allowed")
+	}
+	l.Release("1.2.3.4")
+	if !l.Acquire("1.2.3.4") {
+		t.Fatal("expected slot to be available after release")
+	}
+
+	unlimited := NewConnLimiter(0)
+	for i := 0; i < 10; i++ {
+		if !unlimited.Acquire("1.2.3.4") {
+			t.Fatal("expected no cap when max is zero")
+		}
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	if ip := RemoteIP("1.2.3.4:5678"); ip != "1.2.3.4" {
+		t.Fatalf("expected host without port, got %q", ip)
+	}
+	if ip := RemoteIP("[::1]:80"); ip != "::1" {
+		t.Fatalf("expected IPv6 host without port, got %q", ip)
+	}
+	if ip := RemoteIP("pipe"); ip != "pipe" {
+		t.Fatalf("expected address without port unchanged, got %q", ip)
+	}
+}
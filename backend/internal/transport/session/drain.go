@@ -0,0 +1,112 @@
+package session
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DrainNotice is broadcast to connected clients when the server starts draining.
+type DrainNotice struct {
+	Reason          string        // human readable, e.g. "restart"; defaults to "shutdown"
+	ReconnectAfter  time.Duration // minimum delay before clients should reconnect
+	ReconnectJitter time.Duration // random extra delay per client so reconnects do not arrive at once
+	Address         string        // optional alternate endpoint clients should reconnect to
+}
+
+// message builds the server_shutdown message for one client:
+//
+//	{"type":"server_shutdown","data":{"reason":...,"reconnect_after_ms":N,"address":...}}
+func (n DrainNotice) message() map[string]any {
+	delay := n.ReconnectAfter
+	if n.ReconnectJitter > 0 {
+		delay += rand.N(n.ReconnectJitter)
+	}
+	reason := n.Reason
+	if reason == "" {
+		reason = "shutdown"
+	}
+	data := map[string]any{
+		"reason":             reason,
+		"reconnect_after_ms": delay.Milliseconds(),
+	}
+	if n.Address != "" {
+		data["address"] = n.Address
+	}
+	return map[string]any{"type": "server_shutdown", "data": data}
+}
+
+// Drainer coordinates a graceful shutdown across handlers. Once Start is called
+// new connections are refused and every live session is told to leave; Wait
+// returns when they have all persisted their players and closed.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	notice   DrainNotice
+	started  chan struct{}
+	live     sync.WaitGroup
+}
+
+// NewDrainer creates a drainer in the accepting state.
+func NewDrainer() *Drainer {
+	return &Drainer{started: make(chan struct{})}
+}
+
+// Start switches to drain mode and signals every live session. Later calls are no-ops.
+func (d *Drainer) Start(n DrainNotice) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return
+	}
+	d.draining = true
+	d.notice = n
+	close(d.started)
+}
+
+// Draining reports whether Start has been called.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Started is closed when the drain begins.
+func (d *Drainer) Started() <-chan struct{} { return d.started }
+
+// Notice returns the notice passed to Start.
+func (d *Drainer) Notice() DrainNotice {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.notice
+}
+
+// Wait blocks until every session admitted before the drain has finished, or ctx is done.
+func (d *Drainer) Wait(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		d.live.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enter admits a session unless the drain has started. Admitted sessions must call leave.
+func (d *Drainer) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.live.Add(1)
+	return true
+}
+
+// leave marks an admitted session as finished.
+func (d *Drainer) leave() { d.live.Done() }
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainer_RefusesAfterStartAndWaits(t *testing.T) {
+	d := NewDrainer()
+	if !d.enter() {
+		t.Fatal("expected session to be admitted before drain")
+	}
+	d.Start(DrainNotice{ReconnectAfter: time.Second})
+	d.Start(DrainNotice{ReconnectAfter: time.Hour}) // no-op
+	if !d.Draining() || d.Notice().ReconnectAfter != time.Second {
+		t.Fatalf("unexpected drain state: draining=%v notice=%+v", d.Draining(), d.Notice())
+	}
+	if d.enter() {
+		t.Fatal("expected new sessions to be refused while draining")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Wait(ctx); err == nil {
+		t.Fatal("expected Wait to block while a session is live")
+	}
+	d.leave()
+	if err := d.Wait(context.Background()); err != nil {
+		t.Fatalf("wait after leave: %v", err)
+	}
+}
+
+func TestDrainNotice_Message(t *testing.T) {
+	n := DrainNotice{ReconnectAfter: 2 * time.Second, ReconnectJitter: time.Second, Address: "ws://alt:8081/ws"}
+	for i := 0; i < 20; i++ {
+		data := n.message()["data"].(map[string]any)
+		delay := data["reconnect_after_ms"].(int64)
+		if delay < 2000 || delay >= 3000 {
+			t.Fatalf("reconnect_after_ms %d outside [2000, 3000)", delay)
+		}
+		if data["address"] != "ws://alt:8081/ws" || data["reason"] != "shutdown" {
+			t.Fatalf("unexpected notice data: %v", data)
+		}
+	}
+}
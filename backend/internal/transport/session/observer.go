@@ -0,0 +1,208 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/spatial"
+)
+
+// ServeObserver runs a read-only spectator session on c. Observers authenticate
+// with an observer-role token and either follow a player or watch a fixed
+// position. They receive the same AOI-filtered state stream and handover events
+// as players, but no sim.Player is spawned and nothing is persisted.
+//
+// Protocol:
+//   - Client hello: {"token":..., "follow":"<player id>"} or {"token":..., "pos":{"x":..,"z":..}}
+//   - Server replies: {"type":"observe_ack", "data":{...}}
+//   - Client may retarget: {"type":"observe", "follow":"<player id>"} or {"type":"observe", "pos":{...}}
+//   - Server sends periodic: {"type":"state", "data":{"tick":K, "server_time_ms":S, "observer":{...}, "player":{...}, "entities":[...], "deferred":[ids]}}
+//   - Client may probe the server clock with {"type":"time_sync", ...} like players
+func (s *Server) ServeObserver(ctx context.Context, c Conn) {
+	eng, opts := s.eng, s.opts
+	if !s.drain.enter() {
+		s.refuseDraining(c)
+		return
+	}
+	defer s.drain.leave()
+
+	hctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var hello join.ObserveHello
+	if err := readJSON(hctx, c, &hello); err != nil {
+		_ = c.Write(hctx, map[string]any{"type": "error", "error": join.ErrorMsg{Code: "bad_request", Message: "invalid hello"}})
+		return
+	}
+	ack, em := join.HandleObserve(hctx, s.auth, eng, hello)
+	if em != nil {
+		_ = c.Write(hctx, map[string]any{"type": "error", "error": em})
+		if em.Code == "forbidden" {
+			c.Close(StatusPolicyViolation, "observer role required")
+		}
+		return
+	}
+	if err := c.Write(hctx, map[string]any{"type": "observe_ack", "data": ack}); err != nil {
+		return
+	}
+
+	queue := newSendQueue(sendQueueConfig{
+		Size:                opts.SendQueueSize,
+		WriteTimeout:        opts.WriteTimeout,
+		SlowConsumerTimeout: opts.SlowConsumerTimeout,
+	})
+	go queue.Run(ctx, func(ctx context.Context, msg any) error {
+		return c.Write(ctx, msg)
+	})
+
+	type observeMsg struct {
+		Type   string        `json:"type"`
+		Follow string        `json:"follow"`
+		Pos    *spatial.Vec2 `json:"pos"`
+	}
+	retargets := make(chan observeMsg, 4)
+	done := make(chan struct{})
+
+	// Reader goroutine: only retarget requests and clock probes are accepted;
+	// everything else is ignored so an observer can never act on the world.
+	limiter := newSessionRateLimiter(opts.RateLimits, time.Now())
+	go func() {
+		defer close(done)
+		for {
+			raw, err := c.Read(ctx)
+			if err != nil {
+				return
+			}
+			var msg observeMsg
+			if err := json.Unmarshal(raw, &msg); err != nil || (msg.Type != "observe" && msg.Type != "time_sync") {
+				continue
+			}
+			class := classifyMessage(msg.Type)
+			switch decision := limiter.Allow(class, time.Now()); decision {
+			case rateAllow:
+			case rateDisconnect:
+				metrics.IncRateLimited(string(class), decision.String())
+				c.Close(StatusPolicyViolation, "rate limit exceeded")
+				return
+			default:
+				metrics.IncRateLimited(string(class), decision.String())
+				continue
+			}
+			if msg.Type == "time_sync" {
+				var req timeSyncRequest
+				if err := json.Unmarshal(raw, &req); err == nil {
+					_ = queue.EnqueueReliable("time_sync", timeSyncReply(req, eng.ServerTime(), eng.CurrentTick()))
+				}
+				continue
+			}
+			select {
+			case retargets <- msg:
+			default:
+			}
+		}
+	}()
+
+	cfg := eng.GetConfig()
+	ticker := time.NewTicker(time.Second / time.Duration(max(1, cfg.SnapshotHz)))
+	defer ticker.Stop()
+	telemTicker := time.NewTicker(time.Second)
+	defer telemTicker.Stop()
+
+	follow := ack.Follow
+	watchPos := ack.Pos
+	lastCell := ack.Cell
+	entBudget := newEntityBudget()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.drain.Started():
+			wctx, cancelW := context.WithTimeout(context.Background(), time.Second)
+			_ = c.Write(wctx, s.drain.Notice().message())
+			cancelW()
+			c.Close(StatusServiceRestart, "server shutting down")
+			return
+		case <-queue.Failed():
+			if queue.Err() == errSlowConsumer {
+				log.Printf("session: disconnecting slow observer %s (queue depth %d)", ack.ObserverID, queue.Depth())
+				c.Close(StatusPolicyViolation, "slow consumer")
+			}
+			return
+		case msg := <-retargets:
+			var at spatial.Vec2
+			if msg.Pos != nil {
+				at = *msg.Pos
+			}
+			if _, _, ok := join.ObserveTarget(eng, msg.Follow, at); !ok {
+				sendError(queue, "target_not_found", "followed player not found")
+				continue
+			}
+			follow = msg.Follow
+			watchPos = at
+		case <-ticker.C:
+			snap := eng.LatestSnapshot()
+			if snap == nil {
+				continue
+			}
+			pos, cell := watchPos, lastCell
+			followed, following := snap.Player(follow)
+			if follow != "" {
+				if !following {
+					continue
+				}
+				pos, cell = followed.Pos, followed.OwnedCell
+			} else {
+				cx, cz := spatial.WorldToCell(pos.X, pos.Z, cfg.CellSize)
+				cell = spatial.CellKey{Cx: cx, Cz: cz}
+			}
+			if cell != lastCell {
+				hov := map[string]any{
+					"type": "handover",
+					"data": map[string]any{
+						"from": lastCell,
+						"to":   cell,
+					},
+				}
+				_ = queue.EnqueueReliable("handover", hov)
+				lastCell = cell
+			}
+			observer := map[string]any{"pos": pos, "cell": cell}
+			msgData := map[string]any{
+				"tick":           snap.Tick,
+				"server_time_ms": durationMs(snap.ServerTime),
+				"observer":       observer,
+			}
+			if following {
+				observer["follow"] = follow
+				msgData["player"] = map[string]any{"id": followed.ID, "pos": followed.Pos, "vel": followed.Vel}
+			}
+			// Same AOI filter and byte budget as the followed player (or a player standing at pos) would get
+			msg := map[string]any{"type": "state", "data": msgData}
+			fitEntities(msg, msgData, entBudget, s.byteBudget, pos, cfg.AOIRadius, snap.QueryAOI(pos, cfg.AOIRadius, follow), nil)
+			_ = queue.EnqueueLatest("state", msg)
+		case <-telemTicker.C:
+			start := time.Now()
+			pingCtx, cancelPing := context.WithTimeout(ctx, 500*time.Millisecond)
+			err := c.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return
+			}
+			telem := map[string]any{
+				"type": "telemetry",
+				"data": map[string]any{
+					"tick_rate":      cfg.TickHz,
+					"rtt_ms":         time.Since(start).Seconds() * 1000.0,
+					"tick":           eng.CurrentTick(),
+					"server_time_ms": durationMs(eng.ServerTime()),
+				},
+			}
+			_ = queue.EnqueueLatest("telemetry", telem)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package session
+
+import "time"
+
+// Options tunes session behaviour. Zero values fall back to the defaults noted
+// on each field.
+type Options struct {
+	IdleTimeout time.Duration // if zero, defaults to 30 seconds
+
+	// Outbound queue tuning (see sendQueue)
+	SendQueueSize       int           // max pending outbound messages per session; if zero, defaults to 64
+	WriteTimeout        time.Duration // per-message write deadline; if zero, defaults to 2 seconds
+	SlowConsumerTimeout time.Duration // disconnect when the oldest pending message is older; if zero, defaults to 5 seconds
+
+	// Abuse protection
+	RateLimits    RateLimitConfig // per-session message limits; zero fields use defaults
+	MaxConnsPerIP int             // concurrent connections allowed per remote IP when ConnLimits is nil; zero disables the cap
+	ConnLimits    *ConnLimiter    // optional; share one limiter across servers so the cap spans every transport
+
+	// Duplicate login handling
+	TakeoverPolicy TakeoverPolicy   // applied when Sessions is nil; defaults to TakeoverKickOld
+	Sessions       *SessionRegistry // optional registry shared with other servers
+
+	// Session resume
+	Resume    *ResumeManager // optional token manager (e.g. backed by a durable store)
+	ResumeTTL time.Duration  // lifetime of tokens issued by the default manager; if zero, defaults to 60 seconds
+
+	// Command idempotency
+	CommandWindow int         // recent command seqs remembered per player for dedup when CommandLog is nil; if zero, defaults to 128
+	CommandLog    *CommandLog // optional; share one log across servers so resumed sessions keep their dedup window
+
+	// Snapshot size
+	SnapshotByteBudget int // max encoded bytes of a state message; lowest priority entities are deferred; if zero, defaults to 32KB
+
+	// Graceful shutdown
+	Drain *Drainer // optional; share one drainer across servers so a single Start drains them all
+}
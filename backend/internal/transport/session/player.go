@@ -0,0 +1,1095 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+// skillXPInterval is how often XP progress alone is sent to a player; level-ups
+// are sent, with the current XP, as soon as they happen.
+const skillXPInterval = time.Second
+
+// ServePlayer runs one player session on c until the client leaves, is
+// replaced, idles out or the server drains. The caller owns c and closes it
+// after ServePlayer returns if it is still open.
+func (s *Server) ServePlayer(ctx context.Context, c Conn) {
+	eng, store, opts := s.eng, s.store, s.opts
+	if !s.drain.enter() {
+		s.refuseDraining(c)
+		return
+	}
+	defer s.drain.leave()
+
+	hctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var hello join.Hello
+	if err := readJSON(hctx, c, &hello); err != nil {
+		_ = c.Write(hctx, map[string]any{"type": "error", "error": join.ErrorMsg{Code: "bad_request", Message: "invalid hello"}})
+		return
+	}
+	// Handle join (resume is optional; token still required by AuthService).
+	// Authenticate first so the session can be claimed before the player record is touched.
+	pid, name, em := join.Authenticate(hctx, s.auth, hello)
+	if em != nil {
+		_ = c.Write(hctx, map[string]any{"type": "error", "error": em})
+		return
+	}
+	sess, displaced, err := s.sessions.claim(pid)
+	if err != nil {
+		metrics.IncSessionTakeovers("rejected")
+		_ = c.Write(hctx, map[string]any{"type": "error", "error": join.ErrorMsg{Code: "session_active", Message: "player already connected"}})
+		c.Close(StatusPolicyViolation, "session active")
+		return
+	}
+	defer s.sessions.release(sess)
+	if displaced != nil {
+		// Let the displaced session save its final state before we restore from the store.
+		metrics.IncSessionTakeovers("replaced")
+		select {
+		case <-displaced.Released():
+		case <-hctx.Done():
+		}
+	}
+	ack := join.JoinAuthenticated(hctx, eng, pid, name)
+	// Redeem the presented resume token (single use) to recover the last acked
+	// input; hello.LastSeq is not trusted.
+	lastAck := 0
+	resumed := false
+	if hello.Resume != "" {
+		if seq, ok := s.resume.Consume(hello.Resume, pid); ok {
+			lastAck = seq
+			resumed = true
+		}
+	}
+	// Command dedup state carries over only when the session is resumed.
+	cmdWindow := s.commandLogs.window(pid, resumed)
+	defer s.commandLogs.release(pid, cmdWindow)
+	// Rotate: issue a fresh token for the next reconnect and record our final ack
+	// against it when the session ends (runs before the session is released).
+	// A replaced session skips the checkpoint: its token now belongs to the
+	// session that took over.
+	ack.ResumeToken = s.resume.IssueWithSeq(ack.PlayerID, lastAck)
+	replaced := false
+	defer func() {
+		if !replaced {
+			s.resume.Checkpoint(ack.ResumeToken, ack.PlayerID, lastAck)
+		}
+	}()
+	if err := c.Write(hctx, map[string]any{"type": "join_ack", "data": ack}); err != nil {
+		return
+	}
+
+	// All further writes go through the per-session outbound queue so that a
+	// slow client never blocks input processing below.
+	queue := newSendQueue(sendQueueConfig{
+		Size:                opts.SendQueueSize,
+		WriteTimeout:        opts.WriteTimeout,
+		SlowConsumerTimeout: opts.SlowConsumerTimeout,
+	})
+	go queue.Run(ctx, func(ctx context.Context, msg any) error {
+		return c.Write(ctx, msg)
+	})
+
+	// Keep connection open for input/state loop (US-103).
+	// Basic protocol:
+	//  - Client sends: {"type":"input", "seq":N, "dt":seconds, "intent":{"x":-1..1, "z":-1..1}}
+	//  - Server sends periodic: {"type":"state", "data":{"ack":N, "tick":K, "server_time_ms":S, "player":{...}, "entities":[...], "deferred":[ids]}}
+	//    where deferred lists AOI entities held back by the byte budget (keep their last known state)
+	//  - Client may probe the server clock: {"type":"time_sync", "id":N, "client_time":T}
+	//    and gets {"type":"time_sync", "data":{"id":N, "client_time":T, "server_time_ms":S, "tick":K}}
+	//  - Client may attack: {"type":"attack", "seq":N, "target_id":ID?, "dir":{"x":X, "z":Z}?}
+	//    and gets {"type":"attack_result", ...}; resolved attacks reach every player in
+	//    range as {"type":"combat", "data":{...}} (see sim.CombatEvent)
+	//  - Client may repair: {"type":"repair", "seq":N, "instance_id":ID}
+	//    and gets {"type":"repair_result", ...}
+	//  - Client may use a consumable: {"type":"use_item", "seq":N, "instance_id":ID}
+	//    and gets {"type":"use_item_result", ...}
+	//  - Client may split a stack: {"type":"split_stack", "seq":N, "instance_id":ID, "quantity":Q}
+	//    or merge one into another: {"type":"merge_stack", "seq":N, "instance_id":ID, "target_id":ID}
+	//    and gets {"type":"stack_result", ...}
+	//  - Client may move items between compartments:
+	//    {"type":"move_item", "seq":N, "instance_id":ID, "compartment":C, "quantity":Q?}
+	//    and gets {"type":"move_item_result", ...}; quantity 0 or absent moves the whole stack
+	//  - In grid compartments, client may place an item:
+	//    {"type":"place_item", "seq":N, "instance_id":ID, "x":X, "y":Y, "rotated":bool?}
+	//    or auto-sort one: {"type":"sort_inventory", "seq":N, "compartment":C}
+	//    and gets {"type":"layout_result", ...}
+	//  - Client may drop an item at its position:
+	//    {"type":"drop_item", "seq":N, "instance_id":ID, "quantity":Q?}
+	//    and gets {"type":"drop_item_result", ...}; quantity 0 or absent drops the whole stack
+	//  - Client may pick up a ground item in range:
+	//    {"type":"pick_up", "seq":N, "ground_id":ID, "compartment":C?}
+	//    and gets {"type":"pick_up_result", ...}
+
+	// Reader goroutine -> inputs channel
+	type inputMsg struct {
+		Type   string  `json:"type"`
+		Seq    int     `json:"seq"`
+		Dt     float64 `json:"dt"`
+		Intent struct {
+			X float64 `json:"x"`
+			Z float64 `json:"z"`
+		} `json:"intent"`
+	}
+
+	// Mutating commands carry a seq and are deduplicated through cmdWindow.
+	type commandMsg struct {
+		Type string
+		Seq  int
+		Raw  json.RawMessage
+	}
+
+	// Equipment command messages
+	type equipMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+		Slot       string `json:"slot"`
+	}
+
+	type unequipMsg struct {
+		Type        string `json:"type"`
+		Seq         int    `json:"seq"`
+		Slot        string `json:"slot"`
+		Compartment string `json:"compartment,omitempty"` // defaults to backpack if empty
+	}
+
+	type repairMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+	}
+
+	type useItemMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+	}
+
+	type splitStackMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+		Quantity   int    `json:"quantity"`
+	}
+
+	type mergeStackMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+		TargetID   string `json:"target_id"`
+	}
+
+	type moveItemMsg struct {
+		Type        string `json:"type"`
+		Seq         int    `json:"seq"`
+		InstanceID  string `json:"instance_id"`
+		Compartment string `json:"compartment"`
+		Quantity    int    `json:"quantity,omitempty"` // whole stack if 0
+	}
+
+	type placeItemMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+		X          int    `json:"x"`
+		Y          int    `json:"y"`
+		Rotated    bool   `json:"rotated,omitempty"`
+	}
+
+	type sortInventoryMsg struct {
+		Type        string `json:"type"`
+		Seq         int    `json:"seq"`
+		Compartment string `json:"compartment,omitempty"` // defaults to backpack if empty
+	}
+
+	type dropItemMsg struct {
+		Type       string `json:"type"`
+		Seq        int    `json:"seq"`
+		InstanceID string `json:"instance_id"`
+		Quantity   int    `json:"quantity,omitempty"` // whole stack if 0
+	}
+
+	type pickUpMsg struct {
+		Type        string `json:"type"`
+		Seq         int    `json:"seq"`
+		GroundID    string `json:"ground_id"`
+		Compartment string `json:"compartment,omitempty"` // defaults to backpack if empty
+	}
+
+	type attackMsg struct {
+		Type     string       `json:"type"`
+		Seq      int          `json:"seq"`
+		TargetID string       `json:"target_id,omitempty"` // nearest entity within reach if empty
+		Dir      spatial.Vec2 `json:"dir"`                 // defaults to the player's facing
+	}
+	inputs := make(chan inputMsg, 16)
+	commands := make(chan commandMsg, 16)
+	done := make(chan struct{})
+	activityCh := make(chan time.Time, 1)
+
+	limiter := newSessionRateLimiter(opts.RateLimits, time.Now())
+	go func() {
+		defer close(done)
+		// per-message read deadline to prevent hanging on slow/malicious clients
+		for {
+			readCtx, cancelRead := context.WithTimeout(ctx, 2*time.Second)
+			raw, err := c.Read(readCtx)
+			cancelRead()
+			if err != nil {
+				return
+			}
+			// Enforce per-class rate limits before doing any work on the message
+			var head struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal(raw, &head)
+			class := classifyMessage(head.Type)
+			switch decision := limiter.Allow(class, time.Now()); decision {
+			case rateAllow:
+			case rateDisconnect:
+				metrics.IncRateLimited(string(class), decision.String())
+				log.Printf("session: disconnecting client %s for exceeding %s rate limit", ack.PlayerID, class)
+				c.Close(StatusPolicyViolation, "rate limit exceeded")
+				return
+			case rateWarn:
+				metrics.IncRateLimited(string(class), decision.String())
+				sendError(queue, "rate_limited", "Too many "+string(class)+" messages; slow down")
+				continue
+			default:
+				metrics.IncRateLimited(string(class), decision.String())
+				continue
+			}
+			// Signal activity; rate-limited messages do not keep a session alive
+			select {
+			case activityCh <- time.Now():
+			default:
+			}
+			// Clock probes are answered straight from the reader so the server
+			// timestamp is taken as close to receipt as possible.
+			if head.Type == "time_sync" {
+				var req timeSyncRequest
+				if err := json.Unmarshal(raw, &req); err == nil {
+					_ = queue.EnqueueReliable("time_sync", timeSyncReply(req, eng.ServerTime(), eng.CurrentTick()))
+				}
+				continue
+			}
+
+			// Try to decode as input
+			var in inputMsg
+			if err := json.Unmarshal(raw, &in); err == nil && in.Type == "input" {
+				select {
+				case inputs <- in:
+				default:
+					// drop if backpressured
+				}
+				continue
+			}
+
+			// Mutating commands are decoded by their handler in the writer loop
+			if isMutatingCommand(head.Type) {
+				var cmd struct {
+					Seq int `json:"seq"`
+				}
+				if err := json.Unmarshal(raw, &cmd); err != nil {
+					continue
+				}
+				select {
+				case commands <- commandMsg{Type: head.Type, Seq: cmd.Seq, Raw: raw}:
+				default:
+					// drop if backpressured
+				}
+				continue
+			}
+
+			// ignore unknown message types
+		}
+	}()
+
+	// State ticker
+	cfg := eng.GetConfig()
+	snapDur := time.Second / time.Duration(max(1, cfg.SnapshotHz))
+	telemetryDur := time.Second // 1Hz telemetry
+	ticker := time.NewTicker(snapDur)
+	defer ticker.Stop()
+	telemTicker := time.NewTicker(telemetryDur)
+	defer telemTicker.Stop()
+	// idle timeout: disconnect clients idle for more than configured timeout
+	idleTimer := time.NewTimer(s.idleTimeout)
+	defer idleTimer.Stop()
+	playerID := ack.PlayerID
+	lastCell := ack.Cell // track last known owned cell to emit handover events
+
+	// Track last sent versions for delta updates
+	var lastInventoryVersion int64 = -1  // Force initial send
+	var lastEquipmentVersion int64 = -1  // Force initial send
+	var lastSkillsVersion int64 = -1     // Force initial send
+	var lastSkillXPVersion int64 = -1    // XP alone is sent at most every skillXPInterval
+	var lastAttributesVersion int64 = -1 // Force initial send
+	var lastSkillXPSent time.Time
+	// Entities that do not fit the snapshot byte budget are deferred by priority
+	entBudget := newEntityBudget()
+	// Combat events are forwarded once each; the last attack target is kept
+	// ahead of other entities when the byte budget defers some.
+	lastCombatSeq := eng.CombatSeq()
+	// Level-ups earned before this session are reflected in the join ack's skills.
+	var lastLevelUpSeq int64
+	if p, ok := eng.GetPlayer(playerID); ok {
+		lastLevelUpSeq = p.LatestLevelUpSeq()
+	}
+	lastTarget := ""
+	isTarget := func(id string) bool { return id == lastTarget }
+	// movement speed meters/sec when intent vector length is 1
+	const moveSpeed = 3.0
+	applyInput := func(in inputMsg) {
+		// clamp intent and update velocity
+		vx := clamp(in.Intent.X, -1, 1) * moveSpeed
+		vz := clamp(in.Intent.Z, -1, 1) * moveSpeed
+		_ = eng.DevSetVelocity(playerID, spatial.Vec2{X: vx, Z: vz})
+		if in.Seq > lastAck {
+			lastAck = in.Seq
+		}
+	}
+	// drainInputs applies inputs read before the connection ended so the final
+	// ack recorded for resume covers everything the client sent.
+	drainInputs := func() {
+		for {
+			select {
+			case in := <-inputs:
+				applyInput(in)
+			default:
+				return
+			}
+		}
+	}
+	// persistOnDisconnect applies the inputs still queued and saves the player
+	// once the client is gone, whichever way the session noticed.
+	persistOnDisconnect := func() {
+		drainInputs()
+		// On disconnect, persist last known state including inventory/equipment (US-006)
+		if store != nil {
+			// Use background context with timeout instead of request context which will be canceled
+			persistCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			eng.RequestPlayerDisconnectPersist(persistCtx, playerID)
+		}
+	}
+
+	// commandHandlers execute a mutating command and return the reply to send.
+	// Every entry here is deduplicated by seq through cmdWindow.
+	commandHandlers := map[string]func(raw json.RawMessage) map[string]any{
+		"equip": func(raw json.RawMessage) map[string]any {
+			var equipCmd equipMsg
+			_ = json.Unmarshal(raw, &equipCmd)
+			slotID := sim.SlotID(equipCmd.Slot)
+			instanceID := sim.ItemInstanceID(equipCmd.InstanceID)
+			err := eng.EquipItem(playerID, instanceID, slotID, time.Now())
+			success := err == nil
+
+			// Record metrics
+			metrics.ObserveEquipOperation("equip", success)
+			if err == sim.ErrEquipLocked {
+				metrics.IncEquipCooldownBlocks()
+			}
+			if success {
+				// Force inventory and equipment delta on next state update
+				lastInventoryVersion = -1
+				lastEquipmentVersion = -1
+			}
+			return equipResult(success, "equip", equipCmd.Slot, err)
+		},
+		"unequip": func(raw json.RawMessage) map[string]any {
+			var unequipCmd unequipMsg
+			_ = json.Unmarshal(raw, &unequipCmd)
+			slotID := sim.SlotID(unequipCmd.Slot)
+			compartment := sim.CompartmentType(unequipCmd.Compartment)
+			if compartment == "" {
+				compartment = sim.CompartmentBackpack // Default compartment
+			}
+			err := eng.UnequipItem(playerID, slotID, compartment, time.Now())
+			success := err == nil
+
+			// Record metrics
+			metrics.ObserveEquipOperation("unequip", success)
+			if err == sim.ErrEquipLocked {
+				metrics.IncEquipCooldownBlocks()
+			}
+			if success {
+				// Force inventory and equipment delta on next state update
+				lastInventoryVersion = -1
+				lastEquipmentVersion = -1
+			}
+			return equipResult(success, "unequip", unequipCmd.Slot, err)
+		},
+		"repair": func(raw json.RawMessage) map[string]any {
+			var repairCmd repairMsg
+			_ = json.Unmarshal(raw, &repairCmd)
+			used, err := eng.RepairItem(playerID, sim.ItemInstanceID(repairCmd.InstanceID))
+			success := err == nil
+			metrics.ObserveInventoryOperation("repair", success)
+			if success {
+				// Force inventory and equipment delta on next state update
+				lastInventoryVersion = -1
+				lastEquipmentVersion = -1
+			}
+			return repairResult(repairCmd.InstanceID, used, err)
+		},
+		"use_item": func(raw json.RawMessage) map[string]any {
+			var useCmd useItemMsg
+			_ = json.Unmarshal(raw, &useCmd)
+			now := time.Now()
+			res, err := eng.UseItem(playerID, sim.ItemInstanceID(useCmd.InstanceID), now)
+			success := err == nil
+			metrics.ObserveInventoryOperation("use_item", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return useItemResult(useCmd.InstanceID, res, err, now)
+		},
+		"move_item": func(raw json.RawMessage) map[string]any {
+			var moveCmd moveItemMsg
+			_ = json.Unmarshal(raw, &moveCmd)
+			err := eng.MoveItem(playerID, sim.ItemInstanceID(moveCmd.InstanceID), sim.CompartmentType(moveCmd.Compartment), moveCmd.Quantity)
+			success := err == nil
+			metrics.ObserveInventoryOperation("move_item", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return moveItemResult(moveCmd.InstanceID, moveCmd.Compartment, err)
+		},
+		"place_item": func(raw json.RawMessage) map[string]any {
+			var placeCmd placeItemMsg
+			_ = json.Unmarshal(raw, &placeCmd)
+			pos := sim.GridPos{X: placeCmd.X, Y: placeCmd.Y, Rotated: placeCmd.Rotated}
+			err := eng.PlaceItem(playerID, sim.ItemInstanceID(placeCmd.InstanceID), pos)
+			success := err == nil
+			metrics.ObserveInventoryOperation("place_item", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return layoutResult("place_item", map[string]any{"instance_id": placeCmd.InstanceID}, err)
+		},
+		"sort_inventory": func(raw json.RawMessage) map[string]any {
+			var sortCmd sortInventoryMsg
+			_ = json.Unmarshal(raw, &sortCmd)
+			compartment := sim.CompartmentType(sortCmd.Compartment)
+			if compartment == "" {
+				compartment = sim.CompartmentBackpack
+			}
+			err := eng.SortInventory(playerID, compartment)
+			success := err == nil
+			metrics.ObserveInventoryOperation("sort_inventory", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return layoutResult("sort_inventory", map[string]any{"compartment": compartment}, err)
+		},
+		"drop_item": func(raw json.RawMessage) map[string]any {
+			var dropCmd dropItemMsg
+			_ = json.Unmarshal(raw, &dropCmd)
+			groundID, err := eng.DropItem(playerID, sim.ItemInstanceID(dropCmd.InstanceID), dropCmd.Quantity, time.Now())
+			success := err == nil
+			metrics.ObserveInventoryOperation("drop_item", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return dropItemResult(dropCmd.InstanceID, groundID, err)
+		},
+		"pick_up": func(raw json.RawMessage) map[string]any {
+			var pickCmd pickUpMsg
+			_ = json.Unmarshal(raw, &pickCmd)
+			compartment := sim.CompartmentType(pickCmd.Compartment)
+			if compartment == "" {
+				compartment = sim.CompartmentBackpack
+			}
+			res, err := eng.PickUpItem(playerID, pickCmd.GroundID, compartment, time.Now())
+			success := err == nil
+			metrics.ObserveInventoryOperation("pick_up", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return pickUpResult(pickCmd.GroundID, res, err)
+		},
+		"split_stack": func(raw json.RawMessage) map[string]any {
+			var splitCmd splitStackMsg
+			_ = json.Unmarshal(raw, &splitCmd)
+			newID, err := eng.SplitStack(playerID, sim.ItemInstanceID(splitCmd.InstanceID), splitCmd.Quantity)
+			success := err == nil
+			metrics.ObserveInventoryOperation("split_stack", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return stackResult("split_stack", splitCmd.InstanceID, map[string]any{"new_instance_id": newID}, err)
+		},
+		"merge_stack": func(raw json.RawMessage) map[string]any {
+			var mergeCmd mergeStackMsg
+			_ = json.Unmarshal(raw, &mergeCmd)
+			moved, err := eng.MergeStack(playerID, sim.ItemInstanceID(mergeCmd.InstanceID), sim.ItemInstanceID(mergeCmd.TargetID))
+			success := err == nil
+			metrics.ObserveInventoryOperation("merge_stack", success)
+			if success {
+				// Force inventory delta on next state update
+				lastInventoryVersion = -1
+			}
+			return stackResult("merge_stack", mergeCmd.InstanceID, map[string]any{"moved": moved}, err)
+		},
+		"attack": func(raw json.RawMessage) map[string]any {
+			var attackCmd attackMsg
+			_ = json.Unmarshal(raw, &attackCmd)
+			ev, err := eng.Attack(playerID, sim.AttackRequest{TargetID: attackCmd.TargetID, Aim: attackCmd.Dir}, time.Now())
+			code, _ := attackCode(ev, err)
+			metrics.IncAttacks(code)
+			if err == nil {
+				lastTarget = ev.TargetID
+			}
+			return attackResult(ev, err)
+		},
+	}
+
+	// writer loop
+	for {
+		select {
+		case <-idleTimer.C:
+			log.Printf("session: disconnecting idle client %s after %v", playerID, s.idleTimeout)
+			return
+		case <-sess.Replaced():
+			// Another connection took over this player: hand off the live state and leave
+			// without running the normal disconnect path.
+			log.Printf("session: session for %s replaced by a new connection", playerID)
+			drainInputs()
+			replaced = true
+			if store != nil {
+				persistCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				eng.PersistPlayerNow(persistCtx, playerID)
+				cancel()
+			}
+			wctx, cancelW := context.WithTimeout(context.Background(), time.Second)
+			_ = c.Write(wctx, map[string]any{
+				"type": "session_replaced",
+				"data": map[string]any{"reason": "logged_in_elsewhere"},
+			})
+			cancelW()
+			// Release before the close handshake so the new session is not held up by it.
+			s.sessions.release(sess)
+			c.Close(StatusSessionReplaced, "session replaced")
+			return
+		case <-s.drain.Started():
+			// Server is draining: tell the client when and where to reconnect, save
+			// the player, and only then close. The resume token stays valid.
+			log.Printf("session: draining session for %s", playerID)
+			drainInputs()
+			s.resume.Checkpoint(ack.ResumeToken, playerID, lastAck)
+			wctx, cancelW := context.WithTimeout(context.Background(), time.Second)
+			_ = c.Write(wctx, s.drain.Notice().message())
+			cancelW()
+			if store != nil {
+				persistCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				eng.PersistPlayerNow(persistCtx, playerID)
+				cancel()
+			}
+			c.Close(StatusServiceRestart, "server shutting down")
+			return
+		case <-queue.Failed():
+			if queue.Err() == errSlowConsumer {
+				log.Printf("session: disconnecting slow consumer %s (queue depth %d)", playerID, queue.Depth())
+				c.Close(StatusPolicyViolation, "slow consumer")
+			}
+			persistOnDisconnect()
+			return
+		case <-done:
+			persistOnDisconnect()
+			return
+		case <-activityCh:
+			idleTimer.Reset(s.idleTimeout)
+		case in := <-inputs:
+			applyInput(in)
+		case cmd := <-commands:
+			handle, ok := commandHandlers[cmd.Type]
+			if !ok {
+				continue
+			}
+			reply, verdict := cmdWindow.Do(cmd.Seq, func() map[string]any { return handle(cmd.Raw) })
+			switch verdict {
+			case commandStale:
+				metrics.IncDuplicateCommands("stale")
+				sendError(queue, "stale_command", "Command sequence is too old to be processed")
+				continue
+			case commandDuplicate:
+				// retransmission: resend the original result without re-executing
+				metrics.IncDuplicateCommands("cached")
+			}
+			replyType, _ := reply["type"].(string)
+			_ = queue.EnqueueReliable(replyType, reply)
+		case <-ticker.C:
+			// send state with AOI entities, read lock-free from the shared snapshot
+			snap := eng.LatestSnapshot()
+			p, ok := snap.Player(playerID)
+			if !ok {
+				if _, exists := eng.GetPlayer(playerID); !exists {
+					return
+				}
+				// Just joined: not in a published snapshot yet
+				continue
+			}
+			// If player's owned cell changed since last snapshot, emit a handover event first
+			if p.OwnedCell != lastCell {
+				metrics.ObserveHandoverLatency(time.Since(p.HandoverAt))
+				hov := map[string]any{
+					"type": "handover",
+					"data": map[string]any{
+						"from": lastCell,
+						"to":   p.OwnedCell,
+					},
+				}
+				_ = queue.EnqueueReliable("handover", hov)
+				lastCell = p.OwnedCell
+			}
+			// Forward combat involving this player or seen within its AOI. Events
+			// are one-off, so they must not be coalesced like state.
+			for _, ev := range snap.CombatSince(lastCombatSeq) {
+				lastCombatSeq = ev.Seq
+				if combatVisible(ev, p.ID, p.Pos, cfg.AOIRadius) {
+					_ = queue.EnqueueReliable("combat", map[string]any{"type": "combat", "data": ev})
+				}
+			}
+			nearby := snap.QueryAOI(p.Pos, cfg.AOIRadius, p.ID)
+			metrics.ObserveEntitiesInAOI(len(nearby))
+
+			// Prepare state message data
+			msgData := map[string]any{
+				"ack":            lastAck,
+				"tick":           snap.Tick,
+				"server_time_ms": durationMs(snap.ServerTime),
+				"player":         map[string]any{"id": p.ID, "pos": p.Pos, "vel": p.Vel},
+			}
+
+			// Attributes change with every hit and regenerated point, so they
+			// come straight from the snapshot.
+			if p.AttributesVersion != lastAttributesVersion {
+				attrs := map[string]any{
+					"health":  p.Health,
+					"stamina": p.Stamina,
+					"stats":   p.Stats,
+					"dead":    p.Dead,
+				}
+				if len(p.Buffs) > 0 {
+					attrs["buffs"] = p.Buffs
+				}
+				if p.Dead {
+					attrs["respawn_in_ms"] = respawnInMs(p.RespawnAt, snap.Time)
+				}
+				msgData["attributes"] = attrs
+				lastAttributesVersion = p.AttributesVersion
+			}
+
+			// Inventory/equipment/skills deltas are rare; only then read the full live record.
+			skillXPDue := p.SkillXPVersion != lastSkillXPVersion && snap.Time.Sub(lastSkillXPSent) >= skillXPInterval
+			if p.InventoryVersion != lastInventoryVersion || p.EquipmentVersion != lastEquipmentVersion || p.SkillsVersion != lastSkillsVersion || skillXPDue {
+				full, ok := eng.GetPlayer(playerID)
+				if !ok {
+					return
+				}
+				// Add inventory delta if changed
+				if full.InventoryVersion != lastInventoryVersion {
+					playerMgr := eng.GetPlayerManager()
+					encumbrance := playerMgr.GetPlayerEncumbrance(&full)
+					inventory := map[string]any{
+						"items":            full.Inventory.Items,
+						"compartment_caps": full.Inventory.CompartmentCaps,
+						"weight_limit":     full.Inventory.WeightLimit,
+						"encumbrance":      encumbrance,
+					}
+					if len(full.Inventory.Grids) > 0 {
+						inventory["grids"] = full.Inventory.Grids
+					}
+					msgData["inventory"] = inventory
+					lastInventoryVersion = full.InventoryVersion
+				}
+
+				// Add equipment delta if changed
+				if full.EquipmentVersion != lastEquipmentVersion {
+					msgData["equipment"] = full.Equipment
+					lastEquipmentVersion = full.EquipmentVersion
+				}
+
+				// Add skills delta on level-ups, and XP progress alone when due
+				leveled := full.SkillsVersion != lastSkillsVersion
+				if leveled {
+					msgData["skills"] = full.Skills
+					if ups := full.LevelUpsSince(lastLevelUpSeq); len(ups) > 0 {
+						msgData["level_ups"] = ups
+						lastLevelUpSeq = ups[len(ups)-1].Seq
+					}
+					lastSkillsVersion = full.SkillsVersion
+				}
+				if leveled || skillXPDue {
+					msgData["skill_xp"] = full.SkillXP
+					lastSkillXPVersion = full.SkillXPVersion
+					lastSkillXPSent = snap.Time
+				}
+			}
+
+			msg := map[string]any{
+				"type": "state",
+				"data": msgData,
+			}
+			fitEntities(msg, msgData, entBudget, s.byteBudget, p.Pos, cfg.AOIRadius, nearby, isTarget)
+			// Observe snapshot payload size (JSON encoded)
+			if bs, err := json.Marshal(msg); err == nil {
+				metrics.ObserveSnapshotBytes(len(bs))
+			}
+			// a newer state supersedes one the client has not received yet
+			_ = queue.EnqueueLatest("state", msg)
+		case <-telemTicker.C:
+			// measure RTT via the transport ping
+			start := time.Now()
+			pingCtx, cancelPing := context.WithTimeout(ctx, 500*time.Millisecond)
+			err := c.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				log.Printf("session: ping failed for client %s: %v", playerID, err)
+				return
+			}
+			rtt := time.Since(start).Seconds() * 1000.0 // ms
+			telem := map[string]any{
+				"type": "telemetry",
+				"data": map[string]any{
+					"tick_rate":      cfg.TickHz,
+					"rtt_ms":         rtt,
+					"tick":           eng.CurrentTick(),
+					"server_time_ms": durationMs(eng.ServerTime()),
+				},
+			}
+			_ = queue.EnqueueLatest("telemetry", telem)
+		}
+	}
+}
+
+// fitEntities adds the AOI entities to a state message, deferring the lowest
+// priority ones so the whole message stays within byteBudget. Deltas in the
+// rest of the message are never deferred and count against the budget first.
+// "deferred" is always present so that coalescing in the send queue cannot
+// carry an older message's list forward.
+func fitEntities(msg, data map[string]any, budget *entityBudget, byteBudget int, self spatial.Vec2, radius float64, nearby []sim.SnapshotEntity, relevant func(id string) bool) {
+	// room for `,"entities":[],"deferred":[]`
+	const arraysOverhead = 32
+	base := 0
+	if bs, err := json.Marshal(msg); err == nil {
+		base = len(bs)
+	}
+	sel := budget.Select(byteBudget-base-arraysOverhead, self, radius, nearby, relevant, time.Now())
+	if sel.Deferred == nil {
+		sel.Deferred = []string{}
+	}
+	data["entities"] = sel.Entities
+	data["deferred"] = sel.Deferred
+	if n := len(sel.Deferred); n > 0 {
+		metrics.AddDeferredEntities(n)
+	}
+}
+
+func clamp(x, lo, hi float64) float64 {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
+
+// sendError queues an error message for the client
+func sendError(q *sendQueue, code, message string) {
+	errorMsg := map[string]any{
+		"type": "error",
+		"data": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	}
+	_ = q.EnqueueReliable("error", errorMsg)
+}
+
+// equipResult builds the equipment_result message for an equip/unequip command
+func equipResult(success bool, operation, slot string, err error) map[string]any {
+	code, message := "success", "Equipment operation successful"
+	if !success {
+		code, message = itemErrorCode(err, "equip_failed")
+	}
+
+	return map[string]any{
+		"type": "equipment_result",
+		"data": map[string]any{
+			"operation": operation,
+			"slot":      slot,
+			"success":   success,
+			"code":      code,
+			"message":   message,
+		},
+	}
+}
+
+// useItemResult builds the use_item_result message for a use_item command.
+func useItemResult(instanceID string, res sim.UseResult, err error, now time.Time) map[string]any {
+	code, message := "success", "Item used"
+	if err != nil {
+		code, message = itemErrorCode(err, "use_failed")
+	}
+	data := map[string]any{
+		"instance_id": instanceID,
+		"success":     err == nil,
+		"code":        code,
+		"message":     message,
+	}
+	if err == nil {
+		data["template_id"] = res.TemplateID
+		data["remaining"] = res.Remaining
+		data["cooldown_ms"] = max(0, res.CooldownUntil.Sub(now).Milliseconds())
+	}
+	return map[string]any{"type": "use_item_result", "data": data}
+}
+
+// moveItemResult builds the move_item_result message for a move_item command.
+func moveItemResult(instanceID, compartment string, err error) map[string]any {
+	code, message := "success", "Item moved"
+	if err != nil {
+		code, message = itemErrorCode(err, "move_failed")
+	}
+	return map[string]any{
+		"type": "move_item_result",
+		"data": map[string]any{
+			"instance_id": instanceID,
+			"compartment": compartment,
+			"success":     err == nil,
+			"code":        code,
+			"message":     message,
+		},
+	}
+}
+
+// dropItemResult builds the drop_item_result message for a drop_item command.
+func dropItemResult(instanceID, groundID string, err error) map[string]any {
+	code, message := "success", "Item dropped"
+	if err != nil {
+		code, message = itemErrorCode(err, "drop_failed")
+	}
+	data := map[string]any{
+		"instance_id": instanceID,
+		"success":     err == nil,
+		"code":        code,
+		"message":     message,
+	}
+	if err == nil {
+		data["ground_id"] = groundID
+	}
+	return map[string]any{"type": "drop_item_result", "data": data}
+}
+
+// pickUpResult builds the pick_up_result message for a pick_up command.
+func pickUpResult(groundID string, res sim.PickUp, err error) map[string]any {
+	code, message := "success", "Item picked up"
+	if err != nil {
+		code, message = itemErrorCode(err, "pick_up_failed")
+	}
+	data := map[string]any{
+		"ground_id": groundID,
+		"success":   err == nil,
+		"code":      code,
+		"message":   message,
+	}
+	if err == nil {
+		data["instance_ids"] = res.Stacks
+		data["template_id"] = res.Item.TemplateID
+		data["quantity"] = res.Item.Quantity
+	}
+	return map[string]any{"type": "pick_up_result", "data": data}
+}
+
+// layoutResult builds the layout_result message for a place_item or
+// sort_inventory command; subject identifies what was laid out.
+func layoutResult(operation string, subject map[string]any, err error) map[string]any {
+	code, message := "success", "Inventory layout updated"
+	if err != nil {
+		code, message = itemErrorCode(err, "layout_failed")
+	}
+	data := map[string]any{
+		"operation": operation,
+		"success":   err == nil,
+		"code":      code,
+		"message":   message,
+	}
+	maps.Copy(data, subject)
+	return map[string]any{"type": "layout_result", "data": data}
+}
+
+// stackResult builds the stack_result message for a split_stack or
+// merge_stack command; detail is added to the data on success.
+func stackResult(operation, instanceID string, detail map[string]any, err error) map[string]any {
+	code, message := "success", "Stack updated"
+	if err != nil {
+		code, message = itemErrorCode(err, "stack_failed")
+	}
+	data := map[string]any{
+		"operation":   operation,
+		"instance_id": instanceID,
+		"success":     err == nil,
+		"code":        code,
+		"message":     message,
+	}
+	if err == nil {
+		maps.Copy(data, detail)
+	}
+	return map[string]any{"type": "stack_result", "data": data}
+}
+
+// repairResult builds the repair_result message for a repair command.
+func repairResult(instanceID string, materialsUsed int, err error) map[string]any {
+	code, message := "success", "Item repaired"
+	if err != nil {
+		code, message = itemErrorCode(err, "repair_failed")
+	}
+	return map[string]any{
+		"type": "repair_result",
+		"data": map[string]any{
+			"instance_id":    instanceID,
+			"success":        err == nil,
+			"code":           code,
+			"message":        message,
+			"materials_used": materialsUsed,
+		},
+	}
+}
+
+// itemErrorCode maps an equipment or inventory command's error to the result
+// code and message sent to the client, so every item command reports the same
+// failure the same way. Errors without a code of their own get fallback.
+func itemErrorCode(err error, fallback string) (code, message string) {
+	switch {
+	case err == nil:
+		return fallback, ""
+	case errors.Is(err, sim.ErrItemNotFound):
+		return "item_not_found", "Item not found"
+	case errors.Is(err, sim.ErrDead):
+		return "dead", "Not possible while dead"
+	case errors.Is(err, sim.ErrIllegalSlot):
+		return "illegal_slot", "Item cannot be equipped to this slot"
+	case errors.Is(err, sim.ErrSkillGate):
+		return "skill_gate", "Insufficient skill level to equip item"
+	case errors.Is(err, sim.ErrEquipLocked):
+		return "equip_locked", "Equipment slot is on cooldown"
+	case errors.Is(err, sim.ErrItemBroken):
+		return "item_broken", "Item is broken and must be repaired"
+	case errors.Is(err, sim.ErrExceedsWeight):
+		return "exceeds_weight", "Item is too heavy to carry"
+	case errors.Is(err, sim.ErrExceedsBulk):
+		return "exceeds_bulk", "Target compartment is full"
+	case errors.Is(err, sim.ErrInsufficientSpace):
+		return "insufficient_space", "No room for the item in the grid"
+	case errors.Is(err, sim.ErrBadCompartment):
+		return "bad_compartment", "Invalid target compartment"
+	case errors.Is(err, sim.ErrInvalidQuantity):
+		return "invalid_quantity", "Invalid quantity for the stack"
+	case errors.Is(err, sim.ErrNotStackable):
+		return "not_stackable", "Item does not stack"
+	case errors.Is(err, sim.ErrStackMismatch):
+		return "stack_mismatch", "Items cannot stack together"
+	case errors.Is(err, sim.ErrStackFull):
+		return "stack_full", "Target stack is full"
+	case errors.Is(err, sim.ErrNoGrid):
+		return "no_grid", "Compartment has no grid"
+	case errors.Is(err, sim.ErrInvalidPlacement):
+		return "invalid_placement", "Item does not fit at that position"
+	case errors.Is(err, sim.ErrNotRepairable):
+		return "not_repairable", "Item cannot be repaired"
+	case errors.Is(err, sim.ErrNotDamaged):
+		return "not_damaged", "Item is not damaged"
+	case errors.Is(err, sim.ErrMissingMaterials):
+		return "missing_materials", "Not enough repair materials"
+	case errors.Is(err, sim.ErrNotUsable):
+		return "not_usable", "Item cannot be used"
+	case errors.Is(err, sim.ErrUseCooldown):
+		return "use_cooldown", "Items of this kind are on cooldown"
+	case errors.Is(err, sim.ErrNothingToHeal):
+		return "full_health", "Already at full health"
+	case errors.Is(err, sim.ErrOutOfRange):
+		return "out_of_range", "Item is out of reach"
+	case errors.Is(err, sim.ErrNotLootable):
+		return "not_lootable", "Item belongs to another player"
+	}
+	return fallback, err.Error()
+}
+
+// combatVisible reports whether a player at pos should see a combat event:
+// they took part, or the attacker or target is within their AOI radius.
+func combatVisible(ev sim.CombatEvent, playerID string, pos spatial.Vec2, radius float64) bool {
+	if ev.AttackerID == playerID || ev.TargetID == playerID {
+		return true
+	}
+	r2 := radius * radius
+	return spatial.Dist2(ev.AttackerPos, pos) <= r2 || spatial.Dist2(ev.TargetPos, pos) <= r2
+}
+
+// attackResult builds the attack_result message for an attack command. A
+// resolved attack succeeds whether it hit or missed and carries its event.
+func attackResult(ev sim.CombatEvent, err error) map[string]any {
+	code, message := attackCode(ev, err)
+	data := map[string]any{
+		"success": err == nil,
+		"code":    code,
+		"message": message,
+	}
+	if err == nil {
+		data["event"] = ev
+	}
+	return map[string]any{"type": "attack_result", "data": data}
+}
+
+// attackCode maps an attack's outcome to its result code and message.
+func attackCode(ev sim.CombatEvent, err error) (code, message string) {
+	switch {
+	case err == nil && ev.Hit:
+		return "hit", "Attack hit"
+	case err == nil:
+		return "miss", "Attack missed"
+	case errors.Is(err, sim.ErrDead):
+		return "dead", "Cannot attack while dead"
+	case errors.Is(err, sim.ErrAttackCooldown):
+		return "attack_cooldown", "Weapon is not ready yet"
+	case errors.Is(err, sim.ErrExhausted):
+		return "exhausted", "Not enough stamina"
+	case errors.Is(err, sim.ErrTargetNotFound):
+		return "target_not_found", "Target not found"
+	case errors.Is(err, sim.ErrInvalidTarget):
+		return "invalid_target", "Cannot attack this target"
+	case errors.Is(err, sim.ErrNoTarget):
+		return "no_target", "No target within reach"
+	case errors.Is(err, sim.ErrOutOfRange):
+		return "out_of_range", "Target is out of range"
+	case errors.Is(err, sim.ErrOutOfArc):
+		return "out_of_arc", "Target is outside the attack arc"
+	default:
+		return "attack_failed", err.Error()
+	}
+}
+
+// respawnInMs is the time left until a dead player respawns, never negative.
+func respawnInMs(respawnAt, now time.Time) int64 {
+	return max(0, respawnAt.Sub(now).Milliseconds())
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/sim"
+)
+
+func TestItemResultsShareErrorCodes(t *testing.T) {
+	err := fmt.Errorf("player p1: %w", sim.ErrDead)
+	results := map[string]map[string]any{
+		"equip":  equipResult(false, "equip", "chest", err),
+		"repair": repairResult("i1", 0, err),
+		"use":    useItemResult("i1", sim.UseResult{}, err, time.Now()),
+		"stack":  stackResult("merge_stack", "i1", nil, err),
+		"move":   moveItemResult("i1", "belt", err),
+		"layout": layoutResult("sort_inventory", nil, err),
+		"drop":   dropItemResult("i1", "", err),
+		"pick":   pickUpResult("g1", sim.PickUp{}, err),
+	}
+	for name, msg := range results {
+		if code := msg["data"].(map[string]any)["code"]; code != "dead" {
+			t.Errorf("%s: code = %v, want dead", name, code)
+		}
+	}
+
+	other := errors.New("boom")
+	if code, message := itemErrorCode(other, "repair_failed"); code != "repair_failed" || message != "boom" {
+		t.Fatalf("unmapped error = %s %q, want the fallback code and error text", code, message)
+	}
+}
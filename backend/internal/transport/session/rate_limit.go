@@ -0,0 +1,191 @@
+package session
+
+import (
+	"time"
+)
+
+// messageClass groups client message types that share a rate limit.
+type messageClass string
+
+const (
+	classMovement  messageClass = "movement"
+	classInventory messageClass = "inventory"
+	classCombat    messageClass = "combat"
+	classChat      messageClass = "chat"
+	classOther     messageClass = "other"
+)
+
+// classifyMessage maps a client message type to its rate limit class.
+func classifyMessage(msgType string) messageClass {
+	switch msgType {
+	case "input":
+		return classMovement
+	case "equip", "unequip", "repair", "use_item", "move_item", "place_item", "sort_inventory", "split_stack", "merge_stack", "drop_item", "pick_up":
+		return classInventory
+	case "attack":
+		return classCombat
+	case "chat":
+		return classChat
+	default:
+		return classOther
+	}
+}
+
+// RateLimit configures a token bucket: Rate tokens refill per second up to Burst.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures per-session message limits and the escalation policy
+// applied to clients that exceed them. Zero fields fall back to defaults.
+type RateLimitConfig struct {
+	Movement  RateLimit // "input" messages; defaults to 60/s, burst 120
+	Inventory RateLimit // equip/unequip commands; defaults to 10/s, burst 20
+	Combat    RateLimit // attack commands; defaults to 5/s, burst 10
+	Chat      RateLimit // chat messages; defaults to 2/s, burst 5
+	Other     RateLimit // anything else; defaults to 20/s, burst 40
+
+	// Escalation: every rejected message is a violation counted within Window.
+	// Violations are dropped silently until WarnAfter is reached, at which point
+	// the client receives a rate_limited error once per window; reaching
+	// DisconnectAfter closes the connection.
+	WarnAfter       int           // defaults to 10
+	DisconnectAfter int           // defaults to 100
+	Window          time.Duration // defaults to 10 seconds
+}
+
+func (c RateLimitConfig) withDefaults() RateLimitConfig {
+	fill := func(l *RateLimit, rate float64, burst int) {
+		if l.Rate <= 0 {
+			l.Rate = rate
+		}
+		if l.Burst <= 0 {
+			l.Burst = burst
+		}
+	}
+	fill(&c.Movement, 60, 120)
+	fill(&c.Inventory, 10, 20)
+	fill(&c.Combat, 5, 10)
+	fill(&c.Chat, 2, 5)
+	fill(&c.Other, 20, 40)
+	if c.WarnAfter <= 0 {
+		c.WarnAfter = 10
+	}
+	if c.DisconnectAfter <= 0 {
+		c.DisconnectAfter = 100
+	}
+	if c.DisconnectAfter < c.WarnAfter {
+		c.DisconnectAfter = c.WarnAfter
+	}
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	return c
+}
+
+// tokenBucket is a classic token bucket; callers provide the clock.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(l RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: l.Rate, burst: float64(l.Burst), tokens: float64(l.Burst), last: now}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateDecision is the escalating response to an incoming message.
+type rateDecision int
+
+const (
+	rateAllow      rateDecision = iota // process the message
+	rateDrop                           // silently discard
+	rateWarn                           // discard and tell the client it is being limited
+	rateDisconnect                     // discard and close the connection
+)
+
+func (d rateDecision) String() string {
+	switch d {
+	case rateAllow:
+		return "allow"
+	case rateDrop:
+		return "drop"
+	case rateWarn:
+		return "warn"
+	case rateDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// sessionRateLimiter enforces per-class token buckets for a single session.
+// It is only used from the session's reader goroutine and is not safe for
+// concurrent use.
+type sessionRateLimiter struct {
+	cfg     RateLimitConfig
+	buckets map[messageClass]*tokenBucket
+
+	windowStart time.Time
+	violations  int
+	warned      bool
+}
+
+func newSessionRateLimiter(cfg RateLimitConfig, now time.Time) *sessionRateLimiter {
+	cfg = cfg.withDefaults()
+	return &sessionRateLimiter{
+		cfg: cfg,
+		buckets: map[messageClass]*tokenBucket{
+			classMovement:  newTokenBucket(cfg.Movement, now),
+			classInventory: newTokenBucket(cfg.Inventory, now),
+			classCombat:    newTokenBucket(cfg.Combat, now),
+			classChat:      newTokenBucket(cfg.Chat, now),
+			classOther:     newTokenBucket(cfg.Other, now),
+		},
+		windowStart: now,
+	}
+}
+
+// Allow charges one message of the given class and returns how to respond.
+func (l *sessionRateLimiter) Allow(class messageClass, now time.Time) rateDecision {
+	b, ok := l.buckets[class]
+	if !ok {
+		b = l.buckets[classOther]
+	}
+	if b.allow(now) {
+		return rateAllow
+	}
+
+	if now.Sub(l.windowStart) > l.cfg.Window {
+		l.windowStart = now
+		l.violations = 0
+		l.warned = false
+	}
+	l.violations++
+	switch {
+	case l.violations >= l.cfg.DisconnectAfter:
+		return rateDisconnect
+	case l.violations >= l.cfg.WarnAfter && !l.warned:
+		l.warned = true
+		return rateWarn
+	default:
+		return rateDrop
+	}
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_RefillsOverTime(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(RateLimit{Rate: 2, Burst: 2}, now)
+
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("expected burst of 2 to be allowed")
+	}
+	if b.allow(now) {
+		t.Fatal("expected third message in same instant to be rejected")
+	}
+	// 2 tokens/s -> one token after 500ms
+	if !b.allow(now.Add(500 * time.Millisecond)) {
+		t.Fatal("expected token to refill after 500ms")
+	}
+	if b.allow(now.Add(500 * time.Millisecond)) {
+		t.Fatal("expected bucket to be empty again")
+	}
+}
+
+func TestSessionRateLimiter_Escalation(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newSessionRateLimiter(RateLimitConfig{
+		Chat:            RateLimit{Rate: 0.001, Burst: 1},
+		WarnAfter:       2,
+		DisconnectAfter: 4,
+		Window:          time.Minute,
+	}, now)
+
+	want := []rateDecision{rateAllow, rateDrop, rateWarn, rateDrop, rateDisconnect}
+	for i, w := range want {
+		if got := l.Allow(classChat, now); got != w {
+			t.Fatalf("message %d: got %v, want %v", i, got, w)
+		}
+	}
+
+	// Other classes have independent buckets.
+	if got := l.Allow(classMovement, now); got != rateAllow {
+		t.Fatalf("movement should not be limited by chat usage, got %v", got)
+	}
+}
+
+func TestSessionRateLimiter_WindowResetsViolations(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newSessionRateLimiter(RateLimitConfig{
+		Inventory:       RateLimit{Rate: 0.001, Burst: 1},
+		WarnAfter:       2,
+		DisconnectAfter: 3,
+		Window:          time.Second,
+	}, now)
+
+	l.Allow(classInventory, now) // consumes burst
+	l.Allow(classInventory, now) // violation 1
+	l.Allow(classInventory, now) // violation 2 (warn)
+
+	later := now.Add(2 * time.Second)
+	if got := l.Allow(classInventory, later); got != rateDrop {
+		t.Fatalf("expected violations to reset after window, got %v", got)
+	}
+}
+
+func TestClassifyMessage(t *testing.T) {
+	cases := map[string]messageClass{
+		"input":          classMovement,
+		"equip":          classInventory,
+		"unequip":        classInventory,
+		"repair":         classInventory,
+		"use_item":       classInventory,
+		"move_item":      classInventory,
+		"place_item":     classInventory,
+		"sort_inventory": classInventory,
+		"split_stack":    classInventory,
+		"merge_stack":    classInventory,
+		"drop_item":      classInventory,
+		"pick_up":        classInventory,
+		"attack":         classCombat,
+		"chat":           classChat,
+		"bogus":          classOther,
+		"":               classOther,
+	}
+	for msgType, want := range cases {
+		if got := classifyMessage(msgType); got != want {
+			t.Errorf("classifyMessage(%q) = %q, want %q", msgType, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"errors"
+	"sync"
+)
+
+// TakeoverPolicy decides what happens when a player connects while another
+// session for the same player is still active.
+type TakeoverPolicy int
+
+const (
+	// TakeoverKickOld displaces the existing session; it receives a
+	// session_replaced message and is closed. This is the default.
+	TakeoverKickOld TakeoverPolicy = iota
+	// TakeoverRejectNew keeps the existing session and refuses the new connection.
+	TakeoverRejectNew
+)
+
+// errSessionActive is returned by claim when the player already has an active
+// session and the policy is TakeoverRejectNew.
+var errSessionActive = errors.New("player already has an active session")
+
+// activeSession is a registry entry for one connection driving a player.
+type activeSession struct {
+	playerID    string
+	replaced    chan struct{}
+	released    chan struct{}
+	replaceOnce sync.Once
+	releaseOnce sync.Once
+}
+
+// Replaced is closed when another connection takes over this session.
+func (s *activeSession) Replaced() <-chan struct{} { return s.replaced }
+
+// Released is closed once the session's handler has finished winding down.
+func (s *activeSession) Released() <-chan struct{} { return s.released }
+
+// SessionRegistry enforces at most one active session per player.
+// A registry may be shared by several handlers so the policy applies across transports.
+type SessionRegistry struct {
+	mu       sync.Mutex
+	policy   TakeoverPolicy
+	sessions map[string]*activeSession
+}
+
+// NewSessionRegistry creates a registry enforcing the given takeover policy.
+func NewSessionRegistry(policy TakeoverPolicy) *SessionRegistry {
+	return &SessionRegistry{policy: policy, sessions: make(map[string]*activeSession)}
+}
+
+// claim registers a new session for playerID. Under TakeoverKickOld any
+// existing session is signalled as replaced and returned so the caller can wait
+// for it to release; under TakeoverRejectNew errSessionActive is returned instead.
+func (r *SessionRegistry) claim(playerID string) (sess, displaced *activeSession, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.sessions[playerID]; ok {
+		if r.policy == TakeoverRejectNew {
+			return nil, nil, errSessionActive
+		}
+		old.replaceOnce.Do(func() { close(old.replaced) })
+		displaced = old
+	}
+	sess = &activeSession{
+		playerID: playerID,
+		replaced: make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	r.sessions[playerID] = sess
+	return sess, displaced, nil
+}
+
+// release removes the session if it is still the player's active one and
+// signals anyone waiting for it to finish.
+func (r *SessionRegistry) release(sess *activeSession) {
+	r.mu.Lock()
+	if cur, ok := r.sessions[sess.playerID]; ok && cur == sess {
+		delete(r.sessions, sess.playerID)
+	}
+	r.mu.Unlock()
+	sess.releaseOnce.Do(func() { close(sess.released) })
+}
+
+// Active reports whether playerID currently has a registered session.
+func (r *SessionRegistry) Active(playerID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.sessions[playerID]
+	return ok
+}
+
+// Count returns the number of active sessions.
+func (r *SessionRegistry) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
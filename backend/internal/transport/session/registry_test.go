@@ -0,0 +1,63 @@
+package session
+
+import "testing"
+
+func TestSessionRegistry_KickOld(t *testing.T) {
+	r := NewSessionRegistry(TakeoverKickOld)
+
+	first, displaced, err := r.claim("p1")
+	if err != nil || displaced != nil {
+		t.Fatalf("first claim: displaced=%v err=%v", displaced, err)
+	}
+	second, displaced, err := r.claim("p1")
+	if err != nil {
+		t.Fatalf("second claim: %v", err)
+	}
+	if displaced != first {
+		t.Fatal("expected first session to be displaced")
+	}
+	select {
+	case <-first.Replaced():
+	default:
+		t.Fatal("expected first session to be signalled as replaced")
+	}
+
+	// Releasing the displaced session must not evict its replacement.
+	r.release(first)
+	if !r.Active("p1") {
+		t.Fatal("expected replacement session to remain active")
+	}
+	select {
+	case <-first.Released():
+	default:
+		t.Fatal("expected released signal for first session")
+	}
+	r.release(second)
+	if r.Active("p1") || r.Count() != 0 {
+		t.Fatal("expected no active sessions after release")
+	}
+}
+
+func TestSessionRegistry_RejectNew(t *testing.T) {
+	r := NewSessionRegistry(TakeoverRejectNew)
+
+	first, _, err := r.claim("p1")
+	if err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if _, _, err := r.claim("p1"); err != errSessionActive {
+		t.Fatalf("expected errSessionActive, got %v", err)
+	}
+	select {
+	case <-first.Replaced():
+		t.Fatal("existing session must not be replaced under RejectNew")
+	default:
+	}
+	if _, _, err := r.claim("p2"); err != nil {
+		t.Fatalf("other players must not be affected: %v", err)
+	}
+	r.release(first)
+	if _, _, err := r.claim("p1"); err != nil {
+		t.Fatalf("expected claim to succeed after release: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"prototype-game/backend/internal/state"
+)
+
+// storeTimeout bounds each resume store operation so a slow backend cannot
+// stall a handshake.
+const storeTimeout = 2 * time.Second
+
+// ResumeManager issues single-use resume tokens and keeps the last acked input
+// sequence for each one server-side, so reconnecting clients do not have to be
+// trusted to report it. Every successful resume consumes the presented token;
+// the session then receives a fresh one.
+type ResumeManager struct {
+	store state.ResumeStore
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewResumeManager creates a manager backed by an in-memory store.
+func NewResumeManager(ttl time.Duration) *ResumeManager {
+	return NewResumeManagerWithStore(state.NewMemResumeStore(), ttl)
+}
+
+// NewResumeManagerWithStore creates a manager backed by the given store, e.g. a
+// state.FileResumeStore so tokens survive a server restart.
+func NewResumeManagerWithStore(store state.ResumeStore, ttl time.Duration) *ResumeManager {
+	return &ResumeManager{store: store, ttl: ttl}
+}
+
+// tokenKey derives the store key for a token. Only the hash is stored so a
+// leaked store cannot be replayed as live tokens.
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a new token for playerID with no acked input.
+func (m *ResumeManager) Issue(playerID string) string {
+	return m.IssueWithSeq(playerID, 0)
+}
+
+// IssueWithSeq creates a new token for playerID carrying lastSeq.
+func (m *ResumeManager) IssueWithSeq(playerID string, lastSeq int) string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// If random number generation fails, do not issue a token.
+		return ""
+	}
+	tok := hex.EncodeToString(b[:])
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	m.purgeExpired(ctx)
+	rec := state.ResumeRecord{Key: tokenKey(tok), PlayerID: playerID, LastSeq: lastSeq, Expires: time.Now().Add(m.ttl)}
+	if err := m.store.Put(ctx, rec); err != nil {
+		log.Printf("resume: failed to store token for %s: %v", playerID, err)
+		return ""
+	}
+	return tok
+}
+
+// Lookup returns the player a token belongs to without consuming it.
+func (m *ResumeManager) Lookup(token string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec, ok, err := m.store.Get(ctx, tokenKey(token))
+	if err != nil || !ok || time.Now().After(rec.Expires) {
+		return "", false
+	}
+	return rec.PlayerID, true
+}
+
+// Validate checks if a resume token is valid for the specified player ID.
+// This method provides a cleaner interface for resume token validation.
+func (m *ResumeManager) Validate(token, playerID string) bool {
+	if token == "" || playerID == "" {
+		return false
+	}
+	resumePlayerID, ok := m.Lookup(token)
+	return ok && resumePlayerID == playerID
+}
+
+// Consume redeems a token for playerID and returns the server-side last acked
+// sequence. The token is invalidated whether or not it matches, so a token
+// presented once can never be replayed.
+func (m *ResumeManager) Consume(token, playerID string) (lastSeq int, ok bool) {
+	if token == "" || playerID == "" {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec, found, err := m.store.Take(ctx, tokenKey(token))
+	if err != nil {
+		log.Printf("resume: failed to consume token for %s: %v", playerID, err)
+		return 0, false
+	}
+	if !found || rec.PlayerID != playerID || time.Now().After(rec.Expires) {
+		return 0, false
+	}
+	return rec.LastSeq, true
+}
+
+// Checkpoint records lastSeq against an issued token and restarts its TTL.
+// Sessions call it when they end so the next resume picks up where they left off.
+// A token that was already consumed stays consumed.
+func (m *ResumeManager) Checkpoint(token, playerID string, lastSeq int) {
+	if token == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec := state.ResumeRecord{Key: tokenKey(token), PlayerID: playerID, LastSeq: lastSeq, Expires: time.Now().Add(m.ttl)}
+	if _, err := m.store.Update(ctx, rec); err != nil {
+		log.Printf("resume: failed to checkpoint token for %s: %v", playerID, err)
+	}
+}
+
+// purgeExpired drops expired records at most once per TTL.
+func (m *ResumeManager) purgeExpired(ctx context.Context) {
+	now := time.Now()
+	m.mu.Lock()
+	if now.Sub(m.lastPurge) < m.ttl {
+		m.mu.Unlock()
+		return
+	}
+	m.lastPurge = now
+	m.mu.Unlock()
+	if _, err := m.store.PurgeExpired(ctx, now); err != nil {
+		log.Printf("resume: failed to purge expired tokens: %v", err)
+	}
+}
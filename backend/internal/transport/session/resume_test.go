@@ -0,0 +1,126 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/state"
+)
+
+func TestResumeManager_Validate(t *testing.T) {
+	rm := NewResumeManager(time.Second)
+
+	// Test validation with empty inputs
+	if rm.Validate("", "") {
+		t.Error("expected false for empty token and playerID")
+	}
+	if rm.Validate("token", "") {
+		t.Error("expected false for empty playerID")
+	}
+	if rm.Validate("", "player1") {
+		t.Error("expected false for empty token")
+	}
+
+	// Issue a token for player1
+	token := rm.Issue("player1")
+	if token == "" {
+		t.Fatal("expected non-empty token")
+	}
+
+	// Test successful validation
+	if !rm.Validate(token, "player1") {
+		t.Error("expected true for valid token and matching playerID")
+	}
+
+	// Test validation with wrong player ID
+	if rm.Validate(token, "player2") {
+		t.Error("expected false for valid token but wrong playerID")
+	}
+
+	// Test validation with invalid token
+	if rm.Validate("invalid", "player1") {
+		t.Error("expected false for invalid token")
+	}
+
+	// Test validation after expiration
+	rmShort := NewResumeManager(1 * time.Millisecond)
+	tokenShort := rmShort.Issue("player1")
+	time.Sleep(2 * time.Millisecond)
+	if rmShort.Validate(tokenShort, "player1") {
+		t.Error("expected false for expired token")
+	}
+}
+
+func TestResumeManager_ConsumeIsSingleUse(t *testing.T) {
+	rm := NewResumeManager(time.Minute)
+
+	token := rm.IssueWithSeq("player1", 5)
+	if token == "" {
+		t.Fatal("expected non-empty token")
+	}
+	seq, ok := rm.Consume(token, "player1")
+	if !ok || seq != 5 {
+		t.Fatalf("expected to consume token with seq 5, got seq=%d ok=%v", seq, ok)
+	}
+	if _, ok := rm.Consume(token, "player1"); ok {
+		t.Fatal("expected token to be rejected on reuse")
+	}
+
+	// A mismatched player burns the token too.
+	stolen := rm.Issue("player1")
+	if _, ok := rm.Consume(stolen, "player2"); ok {
+		t.Fatal("expected token to be rejected for another player")
+	}
+	if _, ok := rm.Consume(stolen, "player1"); ok {
+		t.Fatal("expected token to be invalidated after a failed redemption")
+	}
+
+	expired := NewResumeManager(time.Millisecond)
+	tok := expired.Issue("player1")
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := expired.Consume(tok, "player1"); ok {
+		t.Fatal("expected expired token to be rejected")
+	}
+}
+
+func TestResumeManager_CheckpointUpdatesSeq(t *testing.T) {
+	rm := NewResumeManager(time.Minute)
+	token := rm.Issue("player1")
+	rm.Checkpoint(token, "player1", 42)
+	seq, ok := rm.Consume(token, "player1")
+	if !ok || seq != 42 {
+		t.Fatalf("expected checkpointed seq 42, got seq=%d ok=%v", seq, ok)
+	}
+}
+
+func TestResumeManager_CheckpointKeepsConsumedTokensConsumed(t *testing.T) {
+	rm := NewResumeManager(time.Minute)
+	token := rm.Issue("player1")
+	if _, ok := rm.Consume(token, "player1"); !ok {
+		t.Fatal("expected to consume a fresh token")
+	}
+	rm.Checkpoint(token, "player1", 42)
+	if _, ok := rm.Consume(token, "player1"); ok {
+		t.Fatal("a checkpoint must not revive a consumed token")
+	}
+}
+
+func TestResumeManager_DurableStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	store, err := state.NewFileResumeStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := NewResumeManagerWithStore(store, time.Minute).IssueWithSeq("player1", 9)
+
+	// A new manager over the reopened file (e.g. after a restart) can redeem it.
+	reopened, err := state.NewFileResumeStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, ok := NewResumeManagerWithStore(reopened, time.Minute).Consume(token, "player1")
+	if !ok || seq != 9 {
+		t.Fatalf("expected token to survive restart, got seq=%d ok=%v", seq, ok)
+	}
+}
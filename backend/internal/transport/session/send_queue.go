@@ -0,0 +1,269 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/sim"
+)
+
+var (
+	errSlowConsumer = errors.New("client is not keeping up with outbound messages")
+	errQueueClosed  = errors.New("send queue closed")
+)
+
+// sendQueueConfig bounds a per-session outbound queue.
+type sendQueueConfig struct {
+	Size                int           // max pending messages across both lanes
+	WriteTimeout        time.Duration // deadline for a single socket write
+	SlowConsumerTimeout time.Duration // max age of the oldest pending message
+}
+
+// outbound is a message waiting to be written to the client.
+type outbound struct {
+	msgType  string
+	msg      any
+	queuedAt time.Time
+}
+
+// sendQueue is a bounded per-session outbound queue drained by its own writer
+// goroutine so that a slow socket never stalls input processing.
+//
+// Messages travel in two lanes:
+//   - reliable (equipment_result, handover, error) are delivered in FIFO order
+//     and always ahead of unreliable messages;
+//   - latest (state, telemetry) keep at most one pending message per type, so a
+//     newer snapshot supersedes an older one that has not been written yet.
+//
+// A client whose backlog exceeds the configured size or age is flagged as a slow
+// consumer and the queue fails; the session is expected to disconnect it.
+type sendQueue struct {
+	cfg sendQueueConfig
+
+	mu       sync.Mutex
+	reliable []outbound
+	latest   []outbound
+
+	wake     chan struct{}
+	failed   chan struct{}
+	failOnce sync.Once
+	err      error
+}
+
+func newSendQueue(cfg sendQueueConfig) *sendQueue {
+	if cfg.Size <= 0 {
+		cfg.Size = 64
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 2 * time.Second
+	}
+	if cfg.SlowConsumerTimeout <= 0 {
+		cfg.SlowConsumerTimeout = 5 * time.Second
+	}
+	return &sendQueue{
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		failed: make(chan struct{}),
+	}
+}
+
+// EnqueueReliable appends a message that must be delivered in order.
+func (q *sendQueue) EnqueueReliable(msgType string, msg any) error {
+	return q.enqueue(outbound{msgType: msgType, msg: msg, queuedAt: time.Now()}, true)
+}
+
+// EnqueueLatest queues a message that supersedes any pending message of the same type.
+func (q *sendQueue) EnqueueLatest(msgType string, msg any) error {
+	return q.enqueue(outbound{msgType: msgType, msg: msg, queuedAt: time.Now()}, false)
+}
+
+func (q *sendQueue) enqueue(m outbound, reliable bool) error {
+	q.mu.Lock()
+	if q.err != nil {
+		q.mu.Unlock()
+		return errQueueClosed
+	}
+	if q.behindLocked(m.queuedAt) {
+		q.mu.Unlock()
+		q.fail(errSlowConsumer)
+		return errSlowConsumer
+	}
+
+	coalesced := false
+	if !reliable {
+		for i := range q.latest {
+			if q.latest[i].msgType == m.msgType {
+				// Keep the original queue time: the client still has not consumed
+				// anything of this type since then.
+				q.latest[i].msg = coalesce(q.latest[i].msg, m.msg)
+				coalesced = true
+				break
+			}
+		}
+	}
+	if !coalesced {
+		if q.depthLocked() >= q.cfg.Size {
+			q.mu.Unlock()
+			q.fail(errSlowConsumer)
+			return errSlowConsumer
+		}
+		if reliable {
+			q.reliable = append(q.reliable, m)
+		} else {
+			q.latest = append(q.latest, m)
+		}
+	}
+	depth := q.depthLocked()
+	q.mu.Unlock()
+
+	if coalesced {
+		metrics.IncSendQueueCoalesced(m.msgType)
+	}
+	metrics.ObserveSendQueueDepth(depth)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// behindLocked reports whether the oldest pending message has waited too long.
+func (q *sendQueue) behindLocked(now time.Time) bool {
+	oldest := time.Time{}
+	if len(q.reliable) > 0 {
+		oldest = q.reliable[0].queuedAt
+	}
+	for _, m := range q.latest {
+		if oldest.IsZero() || m.queuedAt.Before(oldest) {
+			oldest = m.queuedAt
+		}
+	}
+	return !oldest.IsZero() && now.Sub(oldest) > q.cfg.SlowConsumerTimeout
+}
+
+func (q *sendQueue) depthLocked() int {
+	return len(q.reliable) + len(q.latest)
+}
+
+// Depth returns the number of pending messages.
+func (q *sendQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depthLocked()
+}
+
+// pop removes the next message to write, reliable lane first.
+func (q *sendQueue) pop() (outbound, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.reliable) > 0 {
+		m := q.reliable[0]
+		q.reliable[0] = outbound{}
+		q.reliable = q.reliable[1:]
+		return m, true
+	}
+	if len(q.latest) > 0 {
+		m := q.latest[0]
+		q.latest[0] = outbound{}
+		q.latest = q.latest[1:]
+		return m, true
+	}
+	return outbound{}, false
+}
+
+// Run drains the queue using write until ctx is done or a write fails.
+func (q *sendQueue) Run(ctx context.Context, write func(ctx context.Context, msg any) error) {
+	for {
+		select {
+		case <-ctx.Done():
+			q.fail(ctx.Err())
+			return
+		case <-q.failed:
+			return
+		case <-q.wake:
+		}
+		for {
+			m, ok := q.pop()
+			if !ok {
+				break
+			}
+			wctx, cancel := context.WithTimeout(ctx, q.cfg.WriteTimeout)
+			err := write(wctx, m.msg)
+			cancel()
+			if err != nil {
+				q.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// Failed is closed once the queue stops delivering messages.
+func (q *sendQueue) Failed() <-chan struct{} { return q.failed }
+
+// Err returns the reason the queue failed, if any.
+func (q *sendQueue) Err() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err
+}
+
+func (q *sendQueue) fail(err error) {
+	q.failOnce.Do(func() {
+		q.mu.Lock()
+		q.err = err
+		q.mu.Unlock()
+		if err == errSlowConsumer {
+			metrics.IncSlowConsumerDisconnects()
+		}
+		close(q.failed)
+	})
+}
+
+// coalesce merges a superseded message into its replacement. Fields present in
+// the older message's data but absent from the newer one (one-shot deltas such
+// as inventory or equipment) are carried forward so they are not lost. Event
+// lists such as level_ups are concatenated, oldest first.
+func coalesce(older, newer any) any {
+	o, ok := older.(map[string]any)
+	if !ok {
+		return newer
+	}
+	n, ok := newer.(map[string]any)
+	if !ok {
+		return newer
+	}
+	od, ok := o["data"].(map[string]any)
+	if !ok {
+		return newer
+	}
+	nd, ok := n["data"].(map[string]any)
+	if !ok {
+		return newer
+	}
+	for k, v := range od {
+		if _, exists := nd[k]; !exists {
+			nd[k] = v
+		} else if k == "level_ups" {
+			nd[k] = appendEvents(v, nd[k])
+		}
+	}
+	return n
+}
+
+// appendEvents concatenates two event lists of the same type.
+func appendEvents(older, newer any) any {
+	o, ok := older.([]sim.SkillLevelUp)
+	if !ok {
+		return newer
+	}
+	n, ok := newer.([]sim.SkillLevelUp)
+	if !ok {
+		return newer
+	}
+	return append(append([]sim.SkillLevelUp(nil), o...), n...)
+}
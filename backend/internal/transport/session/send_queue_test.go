@@ -0,0 +1,178 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/sim"
+)
+
+// recordingWriter captures messages written by a sendQueue.
+type recordingWriter struct {
+	mu   sync.Mutex
+	msgs []any
+	gate chan struct{} // if non-nil, each write waits for a token
+}
+
+func (w *recordingWriter) write(ctx context.Context, msg any) error {
+	if w.gate != nil {
+		select {
+		case <-w.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	w.mu.Lock()
+	w.msgs = append(w.msgs, msg)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *recordingWriter) snapshot() []any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]any(nil), w.msgs...)
+}
+
+func envelope(msgType string, data map[string]any) map[string]any {
+	return map[string]any{"type": msgType, "data": data}
+}
+
+func TestSendQueue_CoalescesLatestAndKeepsDeltas(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 8})
+
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 1, "inventory": "inv-v1"}))
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 2}))
+	if d := q.Depth(); d != 1 {
+		t.Fatalf("expected coalesced depth 1, got %d", d)
+	}
+
+	m, ok := q.pop()
+	if !ok {
+		t.Fatal("expected a pending message")
+	}
+	data := m.msg.(map[string]any)["data"].(map[string]any)
+	if data["ack"] != 2 {
+		t.Errorf("expected newest ack 2, got %v", data["ack"])
+	}
+	if data["inventory"] != "inv-v1" {
+		t.Errorf("expected inventory delta to be carried forward, got %v", data["inventory"])
+	}
+}
+
+func TestSendQueue_CoalesceConcatenatesLevelUps(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 8})
+
+	first := []sim.SkillLevelUp{{Seq: 1, Skill: "melee", Level: 1}}
+	second := []sim.SkillLevelUp{{Seq: 2, Skill: "defense", Level: 1}}
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 1, "level_ups": first}))
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 2, "level_ups": second}))
+
+	m, ok := q.pop()
+	if !ok {
+		t.Fatal("expected a pending message")
+	}
+	ups := m.msg.(map[string]any)["data"].(map[string]any)["level_ups"].([]sim.SkillLevelUp)
+	if len(ups) != 2 || ups[0].Seq != 1 || ups[1].Seq != 2 {
+		t.Fatalf("expected both level-ups in order, got %+v", ups)
+	}
+}
+
+func TestSendQueue_ReliableBeforeLatest(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 8})
+
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 1}))
+	_ = q.EnqueueReliable("equipment_result", envelope("equipment_result", map[string]any{"n": 1}))
+	_ = q.EnqueueReliable("equipment_result", envelope("equipment_result", map[string]any{"n": 2}))
+
+	var order []string
+	for {
+		m, ok := q.pop()
+		if !ok {
+			break
+		}
+		order = append(order, m.msgType)
+	}
+	want := []string{"equipment_result", "equipment_result", "state"}
+	if len(order) != len(want) {
+		t.Fatalf("got order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSendQueue_RunDeliversInOrder(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 8})
+	w := &recordingWriter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx, w.write)
+
+	for i := 1; i <= 3; i++ {
+		if err := q.EnqueueReliable("equipment_result", i); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && len(w.snapshot()) < 3 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := w.snapshot()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 writes, got %d", len(got))
+	}
+	for i, m := range got {
+		if m != i+1 {
+			t.Fatalf("write %d: got %v, want %d", i, m, i+1)
+		}
+	}
+}
+
+func TestSendQueue_SlowConsumerOnOverflow(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 2})
+
+	_ = q.EnqueueReliable("equipment_result", 1)
+	_ = q.EnqueueReliable("equipment_result", 2)
+	if err := q.EnqueueReliable("equipment_result", 3); err != errSlowConsumer {
+		t.Fatalf("expected errSlowConsumer on overflow, got %v", err)
+	}
+	select {
+	case <-q.Failed():
+	default:
+		t.Fatal("expected queue to be failed after overflow")
+	}
+	if err := q.EnqueueLatest("state", 4); err != errQueueClosed {
+		t.Fatalf("expected errQueueClosed after failure, got %v", err)
+	}
+}
+
+func TestSendQueue_SlowConsumerOnAge(t *testing.T) {
+	q := newSendQueue(sendQueueConfig{Size: 16, SlowConsumerTimeout: 20 * time.Millisecond})
+	w := &recordingWriter{gate: make(chan struct{})} // writer never completes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx, w.write)
+
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 1}))
+	// Wait until the writer has taken the first message and is stuck writing it.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && q.Depth() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 2}))
+	time.Sleep(40 * time.Millisecond)
+
+	// Coalescing keeps depth bounded, but the client is still behind.
+	if err := q.EnqueueLatest("state", envelope("state", map[string]any{"ack": 3})); err != errSlowConsumer {
+		t.Fatalf("expected errSlowConsumer for stale backlog, got %v", err)
+	}
+	if q.Err() != errSlowConsumer {
+		t.Fatalf("expected queue error errSlowConsumer, got %v", q.Err())
+	}
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/state"
+)
+
+// Server runs client sessions against an engine. One Server is shared by all
+// connections of an endpoint; each connection is served by ServePlayer or
+// ServeObserver until it ends.
+type Server struct {
+	auth  join.AuthService
+	eng   *sim.Engine
+	store state.Store
+	opts  Options
+
+	idleTimeout time.Duration
+	byteBudget  int
+	sessions    *SessionRegistry
+	resume      *ResumeManager
+	commandLogs *CommandLog
+	connLimits  *ConnLimiter
+	drain       *Drainer
+}
+
+// NewServer creates a server. store may be nil, in which case nothing is
+// persisted when sessions end.
+func NewServer(auth join.AuthService, eng *sim.Engine, store state.Store, opts Options) *Server {
+	s := &Server{
+		auth:        auth,
+		eng:         eng,
+		store:       store,
+		opts:        opts,
+		idleTimeout: opts.IdleTimeout,
+		byteBudget:  opts.SnapshotByteBudget,
+		sessions:    opts.Sessions,
+		resume:      opts.Resume,
+		commandLogs: opts.CommandLog,
+		connLimits:  opts.ConnLimits,
+		drain:       opts.Drain,
+	}
+	if s.idleTimeout == 0 {
+		s.idleTimeout = 30 * time.Second
+	}
+	if s.byteBudget == 0 {
+		s.byteBudget = defaultSnapshotByteBudget
+	}
+	if s.sessions == nil {
+		s.sessions = NewSessionRegistry(opts.TakeoverPolicy)
+	}
+	if s.resume == nil {
+		ttl := opts.ResumeTTL
+		if ttl == 0 {
+			ttl = 60 * time.Second
+		}
+		s.resume = NewResumeManager(ttl)
+	}
+	if s.commandLogs == nil {
+		s.commandLogs = NewCommandLog(opts.CommandWindow, s.resume.ttl)
+	}
+	if s.connLimits == nil {
+		s.connLimits = NewConnLimiter(opts.MaxConnsPerIP)
+	}
+	if s.drain == nil {
+		s.drain = NewDrainer()
+	}
+	return s
+}
+
+// Drainer returns the drainer sessions on this server obey, so transports can
+// refuse new connections early while it drains.
+func (s *Server) Drainer() *Drainer { return s.drain }
+
+// ConnLimiter returns the per-IP connection cap transports apply before
+// accepting a connection for this server.
+func (s *Server) ConnLimiter() *ConnLimiter { return s.connLimits }
+
+// Sessions returns the registry of active player sessions.
+func (s *Server) Sessions() *SessionRegistry { return s.sessions }
+
+// refuseDraining turns away a connection that arrived after the drain started.
+func (s *Server) refuseDraining(c Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = c.Write(ctx, s.drain.Notice().message())
+	c.Close(StatusTryAgainLater, "server draining")
+}
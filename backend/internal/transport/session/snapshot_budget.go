@@ -0,0 +1,151 @@
+package session
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+// defaultSnapshotByteBudget bounds a state message when Options.SnapshotByteBudget is zero.
+const defaultSnapshotByteBudget = 32 << 10
+
+// Entity priority scoring. Each term is normalized to roughly [0, 1] before
+// weighting so the weights read as relative importance.
+const (
+	priorityDistanceWeight  = 1.0 // nearest entity scores 1, one at the AOI edge 0
+	priorityStalenessWeight = 1.5 // grows until the entity has gone maxStaleness without an update
+	priorityPlayerBonus     = 0.5 // other players ahead of bots at equal distance
+	priorityRelevantBonus   = 4.0 // party members and targets always go first
+	maxStaleness            = 2 * time.Second
+)
+
+// entityBudget fits the AOI entity list of a session's state messages into a
+// byte budget. Entities are scored by distance, relevance and staleness; those
+// that do not fit are deferred and listed by id only, so the client keeps its
+// last known state for them instead of treating them as gone. Staleness rises
+// every snapshot an entity is deferred, so nothing is starved for long.
+type entityBudget struct {
+	lastSent map[string]time.Time
+}
+
+func newEntityBudget() *entityBudget {
+	return &entityBudget{lastSent: make(map[string]time.Time)}
+}
+
+// entitySelection is the outcome of fitting one snapshot into the budget.
+type entitySelection struct {
+	Entities []json.RawMessage // encoded entities to send this snapshot
+	Deferred []string          // ids still in the AOI whose update was held back
+}
+
+type scoredEntity struct {
+	se    sim.SnapshotEntity
+	score float64
+}
+
+// Select picks the entities to send within budget bytes of encoded array
+// elements (entities and deferred ids together; keys and brackets are left to
+// the caller). relevant reports ids the session cares about most, such as
+// party members or targets; it may be nil.
+func (b *entityBudget) Select(budget int, self spatial.Vec2, radius float64, nearby []sim.SnapshotEntity, relevant func(id string) bool, now time.Time) entitySelection {
+	defer b.prune(nearby)
+
+	full := 0
+	for _, se := range nearby {
+		full += len(se.JSON) + 1
+	}
+	if full <= budget {
+		out := entitySelection{Entities: make([]json.RawMessage, 0, len(nearby))}
+		for _, se := range nearby {
+			out.Entities = append(out.Entities, se.JSON)
+			b.lastSent[se.ID] = now
+		}
+		return out
+	}
+
+	ranked := make([]scoredEntity, 0, len(nearby))
+	for _, se := range nearby {
+		ranked = append(ranked, scoredEntity{se: se, score: b.score(se, self, radius, relevant, now)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].se.ID < ranked[j].se.ID
+	})
+
+	// Every entity costs at least its deferred id. If even the ids do not fit,
+	// the lowest priority entities are left out of this snapshot entirely.
+	used := 0
+	kept := 0
+	for _, r := range ranked {
+		cost := deferredCost(r.se.ID)
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		kept++
+	}
+	ranked = ranked[:kept]
+
+	// Upgrade deferred ids to full updates in priority order while they fit.
+	var out entitySelection
+	for _, r := range ranked {
+		upgrade := len(r.se.JSON) + 1 - deferredCost(r.se.ID)
+		if used+upgrade <= budget {
+			used += upgrade
+			out.Entities = append(out.Entities, r.se.JSON)
+			b.lastSent[r.se.ID] = now
+			continue
+		}
+		out.Deferred = append(out.Deferred, r.se.ID)
+	}
+	if out.Entities == nil {
+		out.Entities = []json.RawMessage{}
+	}
+	return out
+}
+
+// score ranks an entity; higher is sent first.
+func (b *entityBudget) score(se sim.SnapshotEntity, self spatial.Vec2, radius float64, relevant func(id string) bool, now time.Time) float64 {
+	s := 0.0
+	if radius > 0 {
+		d := math.Sqrt(spatial.Dist2(se.Pos, self))
+		s += priorityDistanceWeight * (1 - math.Min(d/radius, 1))
+	}
+	stale := maxStaleness // never sent: as stale as it gets
+	if last, ok := b.lastSent[se.ID]; ok {
+		stale = min(now.Sub(last), maxStaleness)
+	}
+	s += priorityStalenessWeight * stale.Seconds() / maxStaleness.Seconds()
+	if se.Kind == sim.KindPlayer {
+		s += priorityPlayerBonus
+	}
+	if relevant != nil && relevant(se.ID) {
+		s += priorityRelevantBonus
+	}
+	return s
+}
+
+// prune forgets entities that have left the AOI so the map stays bounded.
+func (b *entityBudget) prune(nearby []sim.SnapshotEntity) {
+	if len(b.lastSent) <= len(nearby) {
+		return
+	}
+	present := make(map[string]struct{}, len(nearby))
+	for _, se := range nearby {
+		present[se.ID] = struct{}{}
+	}
+	for id := range b.lastSent {
+		if _, ok := present[id]; !ok {
+			delete(b.lastSent, id)
+		}
+	}
+}
+
+// deferredCost is the encoded size of an id in the deferred array.
+func deferredCost(id string) int { return len(id) + 3 }
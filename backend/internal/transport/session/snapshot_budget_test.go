@@ -0,0 +1,98 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+// budgetEntities builds n bots spaced 1m apart along x from the origin.
+func budgetEntities(t *testing.T, n int) []sim.SnapshotEntity {
+	t.Helper()
+	out := make([]sim.SnapshotEntity, 0, n)
+	for i := 0; i < n; i++ {
+		ent := sim.Entity{ID: fmt.Sprintf("bot-%02d", i), Kind: sim.KindBot, Pos: spatial.Vec2{X: float64(i + 1)}}
+		bs, err := json.Marshal(map[string]any{"id": ent.ID, "pos": ent.Pos, "kind": int(ent.Kind)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, sim.SnapshotEntity{Entity: ent, JSON: bs})
+	}
+	return out
+}
+
+func selectionBytes(t *testing.T, sel entitySelection) int {
+	t.Helper()
+	bs, err := json.Marshal(map[string]any{"entities": sel.Entities, "deferred": sel.Deferred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Select budgets array elements; keys and brackets are the caller's overhead
+	return len(bs) - len(`{"entities":[],"deferred":[]}`)
+}
+
+func TestEntityBudget_UnderBudgetSendsAll(t *testing.T) {
+	b := newEntityBudget()
+	sel := b.Select(1<<20, spatial.Vec2{}, 50, budgetEntities(t, 10), nil, time.Now())
+	if len(sel.Entities) != 10 || len(sel.Deferred) != 0 {
+		t.Fatalf("expected all 10 entities sent, got %d sent %d deferred", len(sel.Entities), len(sel.Deferred))
+	}
+}
+
+func TestEntityBudget_PrioritizesAndStaysWithinBudget(t *testing.T) {
+	ents := budgetEntities(t, 20)
+	const budget = 300
+	b := newEntityBudget()
+	// bot-19 is the farthest entity but marked relevant (e.g. a target).
+	relevant := func(id string) bool { return id == "bot-19" }
+	sel := b.Select(budget, spatial.Vec2{}, 50, ents, relevant, time.Now())
+
+	if n := selectionBytes(t, sel); n > budget {
+		t.Fatalf("selection is %d bytes, budget %d", n, budget)
+	}
+	if len(sel.Entities) == 0 || len(sel.Deferred) == 0 {
+		t.Fatalf("expected a mix of sent and deferred, got %d sent %d deferred", len(sel.Entities), len(sel.Deferred))
+	}
+	if !strings.Contains(string(sel.Entities[0]), `"bot-19"`) {
+		t.Fatalf("expected relevant entity first, got %s", sel.Entities[0])
+	}
+	if !strings.Contains(string(sel.Entities[1]), `"bot-00"`) {
+		t.Fatalf("expected nearest entity next, got %s", sel.Entities[1])
+	}
+	if sel.Deferred[len(sel.Deferred)-1] != "bot-18" {
+		t.Fatalf("expected farthest bystander deferred last, got %v", sel.Deferred)
+	}
+}
+
+func TestEntityBudget_StalenessRotatesDeferredEntities(t *testing.T) {
+	ents := budgetEntities(t, 20)
+	b := newEntityBudget()
+	now := time.Now()
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		sel := b.Select(300, spatial.Vec2{}, 50, ents, nil, now)
+		for _, raw := range sel.Entities {
+			var e struct {
+				ID string `json:"id"`
+			}
+			_ = json.Unmarshal(raw, &e)
+			seen[e.ID] = true
+		}
+		now = now.Add(100 * time.Millisecond)
+	}
+	if len(seen) != len(ents) {
+		t.Fatalf("expected every entity to be updated eventually, saw %d of %d", len(seen), len(ents))
+	}
+}
+
+func TestEntityBudget_DropsIDsThatDoNotFit(t *testing.T) {
+	sel := newEntityBudget().Select(20, spatial.Vec2{}, 50, budgetEntities(t, 20), nil, time.Now())
+	if n := selectionBytes(t, sel); n > 20 {
+		t.Fatalf("selection is %d bytes, budget 20", n)
+	}
+}
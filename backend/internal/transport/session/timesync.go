@@ -0,0 +1,33 @@
+package session
+
+import "time"
+
+// timeSyncRequest is a client clock probe: {"type":"time_sync","id":N,"client_time":T}.
+// client_time is opaque to the server and echoed back so the client can compute
+// RTT and clock offset without keeping per-request state.
+type timeSyncRequest struct {
+	Type       string  `json:"type"`
+	ID         int64   `json:"id"`
+	ClientTime float64 `json:"client_time"`
+}
+
+// timeSyncReply answers a probe with the server clock as sampled when the probe
+// was read, so queueing delay on the way back only shows up as RTT:
+//
+//	{"type":"time_sync","data":{"id":N,"client_time":T,"server_time_ms":S,"tick":K}}
+func timeSyncReply(req timeSyncRequest, serverTime time.Duration, tick uint64) map[string]any {
+	return map[string]any{
+		"type": "time_sync",
+		"data": map[string]any{
+			"id":             req.ID,
+			"client_time":    req.ClientTime,
+			"server_time_ms": durationMs(serverTime),
+			"tick":           tick,
+		},
+	}
+}
+
+// durationMs converts a duration to fractional milliseconds for the wire.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
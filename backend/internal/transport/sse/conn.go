@@ -0,0 +1,144 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"prototype-game/backend/internal/transport/session"
+)
+
+// CloseError is returned by Read and Write once the session's connection has closed.
+type CloseError struct {
+	Code   session.StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("sse: connection closed: status = %d and reason = %q", e.Code, e.Reason)
+}
+
+// errAttached is returned when a second stream or poll tries to read a
+// session that already has one.
+var errAttached = errors.New("sse: session already has a reader")
+
+// conn is the server end of one fallback session. Messages POSTed by the
+// client arrive on in; messages the session writes wait on out until an event
+// stream or poll request collects them, so a reconnecting EventSource loses
+// nothing while the buffer holds.
+type conn struct {
+	id  string
+	in  chan json.RawMessage
+	out chan json.RawMessage
+
+	reader chan struct{} // holds a token while a stream or poll is attached
+
+	once   sync.Once
+	closed chan struct{}
+	err    *CloseError
+}
+
+var _ session.Conn = (*conn)(nil)
+
+func newConn(id string, inSize, outSize int) *conn {
+	return &conn{
+		id:     id,
+		in:     make(chan json.RawMessage, inSize),
+		out:    make(chan json.RawMessage, outSize),
+		reader: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+// Read returns the next message POSTed by the client.
+func (c *conn) Read(ctx context.Context) (json.RawMessage, error) {
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	case <-c.closed:
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write queues msg for the client's stream or next poll, blocking while the
+// buffer is full so the session's slow-consumer handling applies unchanged.
+func (c *conn) Write(ctx context.Context, msg any) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+	}
+	select {
+	case c.out <- bs:
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping succeeds while the connection is open. Plain HTTP offers no round trip
+// to measure, so telemetry reports the fallback's RTT as zero.
+func (c *conn) Ping(ctx context.Context) error {
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+		return ctx.Err()
+	}
+}
+
+// Close ends the connection. Messages already queued are still delivered,
+// followed by the close status.
+func (c *conn) Close(code session.StatusCode, reason string) error {
+	c.once.Do(func() {
+		c.err = &CloseError{Code: code, Reason: reason}
+		close(c.closed)
+	})
+	return nil
+}
+
+// deliver pushes a POSTed message to the session.
+func (c *conn) deliver(ctx context.Context, msg json.RawMessage) error {
+	select {
+	case c.in <- msg:
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attach claims the reader slot; the returned func releases it.
+func (c *conn) attach() (func(), error) {
+	select {
+	case c.reader <- struct{}{}:
+		return func() { <-c.reader }, nil
+	default:
+		return nil, errAttached
+	}
+}
+
+// pending returns queued messages without blocking, at most max of them.
+func (c *conn) pending(max int) []json.RawMessage {
+	var msgs []json.RawMessage
+	for len(msgs) < max {
+		select {
+		case msg := <-c.out:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,311 @@
+// Package sse is an HTTP fallback transport for session.Server, for clients
+// behind proxies that break WebSockets. Server messages (join_ack, state,
+// handover, telemetry, command results) stream over Server-Sent Events, or
+// over long-poll requests where even streaming responses are buffered, and
+// client messages are POSTed. Sessions run through the same session.Server
+// code as /ws, so joining goes through join.HandleJoin and every command
+// behaves identically.
+//
+// Under a base path such as /sse:
+//
+//	POST /sse/connect          body: hello      -> {"sid": ...}
+//	GET  /sse/events?sid=...   text/event-stream, one JSON message per event
+//	GET  /sse/poll?sid=...     {"messages": [...], "closed": {...}}
+//	POST /sse/send?sid=...     body: one client message (input, equip, ...)
+//
+// The sid returned by connect authorizes the other requests, so it must only
+// travel over the same channel as the token. When the session ends the stream
+// sends a "close" event, and a poll reports "closed", with the status code and
+// reason a websocket close would carry.
+package sse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/transport/session"
+)
+
+// maxMessageBytes matches the websocket read limit.
+const maxMessageBytes = 32 << 10
+
+// Options tunes the fallback. Zero values take the defaults noted on each field.
+type Options struct {
+	// OutBuffer is how many server messages wait for a stream or poll before
+	// session writes block; if zero, defaults to 256 (about 25s of 10Hz state,
+	// enough to ride out an EventSource reconnect).
+	OutBuffer int
+	// PollTimeout is how long a poll waits for the first message; if zero,
+	// defaults to 25 seconds, below common proxy idle timeouts.
+	PollTimeout time.Duration
+	// Heartbeat is the interval of comment lines keeping idle streams open; if
+	// zero, defaults to 15 seconds.
+	Heartbeat time.Duration
+	// Linger is how long a closed session stays collectable so the client can
+	// read its final messages; if zero, defaults to 30 seconds.
+	Linger time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.OutBuffer <= 0 {
+		o.OutBuffer = 256
+	}
+	if o.PollTimeout <= 0 {
+		o.PollTimeout = 25 * time.Second
+	}
+	if o.Heartbeat <= 0 {
+		o.Heartbeat = 15 * time.Second
+	}
+	if o.Linger <= 0 {
+		o.Linger = 30 * time.Second
+	}
+	return o
+}
+
+type handler struct {
+	srv  *session.Server
+	opts Options
+
+	mu    sync.Mutex
+	conns map[string]*conn
+}
+
+// Register installs the fallback endpoints under path, serving players with srv.
+func Register(mux *http.ServeMux, path string, srv *session.Server, opts Options) {
+	h := &handler{srv: srv, opts: opts.withDefaults(), conns: make(map[string]*conn)}
+	mux.HandleFunc(path+"/connect", h.handleConnect)
+	mux.HandleFunc(path+"/events", h.handleEvents)
+	mux.HandleFunc(path+"/poll", h.handlePoll)
+	mux.HandleFunc(path+"/send", h.handleSend)
+}
+
+// handleConnect starts a session with the POSTed hello and returns its sid.
+func (h *handler) handleConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if drain := h.srv.Drainer(); drain.Draining() {
+		retry := int(math.Ceil(drain.Notice().ReconnectAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		http.Error(w, "server draining", http.StatusServiceUnavailable)
+		return
+	}
+	hello, err := readMessage(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ip := session.RemoteIP(r.RemoteAddr)
+	limits := h.srv.ConnLimiter()
+	if !limits.Acquire(ip) {
+		metrics.IncConnectionsRejected("ip_cap")
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+
+	c := newConn(newSID(), 64, h.opts.OutBuffer)
+	c.in <- hello
+	h.mu.Lock()
+	h.conns[c.id] = c
+	h.mu.Unlock()
+	go func() {
+		defer limits.Release(ip)
+		defer c.Close(session.StatusNormalClosure, "bye")
+		h.srv.ServePlayer(context.Background(), c)
+	}()
+	go func() {
+		<-c.closed
+		time.AfterFunc(h.opts.Linger, func() { h.forget(c) })
+	}()
+
+	writeJSON(w, http.StatusOK, map[string]any{"sid": c.id})
+}
+
+// handleEvents streams the session's messages as Server-Sent Events.
+func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	c, release, ok := h.attach(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // ask nginx-style proxies not to buffer
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 1000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.opts.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case msg := <-c.out:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-c.closed:
+			for _, msg := range c.pending(h.opts.OutBuffer) {
+				fmt.Fprintf(w, "data: %s\n\n", msg)
+			}
+			bs, _ := json.Marshal(closeInfo(c))
+			fmt.Fprintf(w, "event: close\ndata: %s\n\n", bs)
+			flusher.Flush()
+			h.forget(c)
+			return
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handlePoll returns queued messages, waiting up to PollTimeout for the first.
+func (h *handler) handlePoll(w http.ResponseWriter, r *http.Request) {
+	c, release, ok := h.attach(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var msgs []json.RawMessage
+	timer := time.NewTimer(h.opts.PollTimeout)
+	defer timer.Stop()
+	select {
+	case msg := <-c.out:
+		msgs = append(msgs, msg)
+	case <-c.closed:
+	case <-timer.C:
+	case <-r.Context().Done():
+		return
+	}
+	msgs = append(msgs, c.pending(h.opts.OutBuffer)...)
+	if msgs == nil {
+		msgs = []json.RawMessage{}
+	}
+	resp := map[string]any{"messages": msgs}
+	select {
+	case <-c.closed:
+		if len(c.out) == 0 {
+			resp["closed"] = closeInfo(c)
+			h.forget(c)
+		}
+	default:
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSend passes one POSTed client message to the session.
+func (h *handler) handleSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	msg, err := readMessage(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.deliver(r.Context(), msg); err != nil {
+		var ce *CloseError
+		if errors.As(err, &ce) {
+			http.Error(w, "session closed", http.StatusGone)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// attach looks up the request's session and claims its reader slot, writing
+// the error response itself when it cannot.
+func (h *handler) attach(w http.ResponseWriter, r *http.Request) (*conn, func(), bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+	c, ok := h.lookup(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	release, err := c.attach()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, nil, false
+	}
+	return c, release, true
+}
+
+func (h *handler) lookup(w http.ResponseWriter, r *http.Request) (*conn, bool) {
+	h.mu.Lock()
+	c, ok := h.conns[r.URL.Query().Get("sid")]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+	}
+	return c, ok
+}
+
+func (h *handler) forget(c *conn) {
+	h.mu.Lock()
+	if h.conns[c.id] == c {
+		delete(h.conns, c.id)
+	}
+	h.mu.Unlock()
+}
+
+func closeInfo(c *conn) map[string]any {
+	return map[string]any{"code": c.err.Code, "reason": c.err.Reason}
+}
+
+// readMessage reads a request body holding one JSON value.
+func readMessage(w http.ResponseWriter, r *http.Request) (json.RawMessage, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageBytes))
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("body is not valid JSON")
+	}
+	return body, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("sse: write response: %v", err)
+	}
+}
+
+// newSID returns an unguessable session id.
+func newSID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
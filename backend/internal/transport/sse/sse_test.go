@@ -0,0 +1,216 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/transport/session"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	if token == "tok" {
+		return "p1", "Alice", true
+	}
+	return "", "", false
+}
+
+type envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	t.Cleanup(func() { eng.Stop(context.Background()) })
+	mux := http.NewServeMux()
+	Register(mux, "/sse", session.NewServer(fakeAuth{}, eng, nil, session.Options{}), opts)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, url string, body any) *http.Response {
+	t.Helper()
+	bs, _ := json.Marshal(body)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(bs))
+	if err != nil {
+		t.Fatalf("post %s: %v", url, err)
+	}
+	return resp
+}
+
+func connect(t *testing.T, ts *httptest.Server, token string) string {
+	t.Helper()
+	resp := post(t, ts.URL+"/sse/connect", join.Hello{Token: token})
+	defer resp.Body.Close()
+	var out struct{ SID string }
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.SID == "" {
+		t.Fatalf("connect: status %d, err %v", resp.StatusCode, err)
+	}
+	return out.SID
+}
+
+// eventReader yields (event, data) pairs from a text/event-stream body.
+type eventReader struct{ sc *bufio.Scanner }
+
+func (e eventReader) next(t *testing.T) (string, string) {
+	t.Helper()
+	event := "message"
+	for e.sc.Scan() {
+		line := e.sc.Text()
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			return event, strings.TrimPrefix(line, "data: ")
+		}
+	}
+	t.Fatalf("stream ended: %v", e.sc.Err())
+	return "", ""
+}
+
+func TestSSE_JoinStreamsStateAndAcceptsPostedInput(t *testing.T) {
+	ts := newTestServer(t, Options{})
+	sid := connect(t, ts, "tok")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse/events?sid="+sid, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	events := eventReader{bufio.NewScanner(resp.Body)}
+	var env envelope
+	if _, data := events.next(t); json.Unmarshal([]byte(data), &env) != nil || env.Type != "join_ack" {
+		t.Fatalf("expected join_ack first, got %s", data)
+	}
+
+	// A second reader on the same session is refused rather than splitting the stream.
+	if resp2, err := http.Get(ts.URL + "/sse/poll?sid=" + sid); err != nil || resp2.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for a second reader, got %v %v", resp2.StatusCode, err)
+	}
+
+	input := map[string]any{"type": "input", "seq": 1, "dt": 0.05, "intent": map[string]float64{"x": 1, "z": 0}}
+	if r := post(t, ts.URL+"/sse/send?sid="+sid, input); r.StatusCode != http.StatusAccepted {
+		t.Fatalf("send: status %d", r.StatusCode)
+	}
+	for {
+		_, data := events.next(t)
+		if json.Unmarshal([]byte(data), &env) != nil || env.Type != "state" {
+			continue
+		}
+		var st struct {
+			Ack    int `json:"ack"`
+			Player struct {
+				Pos struct{ X float64 } `json:"pos"`
+			} `json:"player"`
+		}
+		if err := json.Unmarshal(env.Data, &st); err != nil {
+			t.Fatalf("decode state: %v", err)
+		}
+		if st.Ack == 1 && st.Player.Pos.X > 0 {
+			return
+		}
+	}
+}
+
+func TestPoll_DeliversRepliesAndCloseStatus(t *testing.T) {
+	ts := newTestServer(t, Options{PollTimeout: 200 * time.Millisecond})
+	sid := connect(t, ts, "bad")
+
+	type pollResp struct {
+		Messages []envelope
+		Closed   *struct {
+			Code   int
+			Reason string
+		}
+	}
+	var got []string
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(ts.URL + "/sse/poll?sid=" + sid)
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		var pr pollResp
+		err = json.NewDecoder(resp.Body).Decode(&pr)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decode poll: %v", err)
+		}
+		for _, m := range pr.Messages {
+			got = append(got, m.Type)
+		}
+		if pr.Closed != nil {
+			if len(got) != 1 || got[0] != "error" {
+				t.Fatalf("expected an auth error before close, got %v", got)
+			}
+			if pr.Closed.Code != int(session.StatusNormalClosure) {
+				t.Fatalf("close code %d", pr.Closed.Code)
+			}
+			// The closed session is forgotten once its close was collected.
+			if r, _ := http.Get(ts.URL + "/sse/poll?sid=" + sid); r.StatusCode != http.StatusNotFound {
+				t.Fatalf("expected 404 after close, got %d", r.StatusCode)
+			}
+			return
+		}
+	}
+	t.Fatalf("session never reported closed; messages %v", got)
+}
+
+func TestSend_RejectsUnknownSessionsAndBadBodies(t *testing.T) {
+	ts := newTestServer(t, Options{})
+	if r := post(t, ts.URL+"/sse/send?sid=nope", map[string]any{"type": "input"}); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown sid: status %d", r.StatusCode)
+	}
+	sid := connect(t, ts, "tok")
+	resp, err := http.Post(ts.URL+"/sse/send?sid="+sid, "application/json", strings.NewReader("{not json"))
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad body: %v %v", resp.StatusCode, err)
+	}
+	big := strings.NewReader(`"` + strings.Repeat("x", maxMessageBytes) + `"`)
+	resp, err = http.Post(ts.URL+"/sse/send?sid="+sid, "application/json", big)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("oversized body: %v %v", resp.StatusCode, err)
+	}
+	if resp, _ := http.Get(ts.URL + "/sse/connect"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("GET connect: status %d", resp.StatusCode)
+	}
+}
+
+func TestConnect_AppliesPerIPConnectionCap(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	t.Cleanup(func() { eng.Stop(context.Background()) })
+	mux := http.NewServeMux()
+	Register(mux, "/sse", session.NewServer(fakeAuth{}, eng, nil, session.Options{MaxConnsPerIP: 1}), Options{})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	connect(t, ts, "tok")
+	resp := post(t, ts.URL+"/sse/connect", join.Hello{Token: "tok"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for a second session from the same IP, got %d", resp.StatusCode)
+	}
+}
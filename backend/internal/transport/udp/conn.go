@@ -0,0 +1,525 @@
+package udp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"prototype-game/backend/internal/transport/session"
+)
+
+// maxFragments bounds a single message to maxFragments*(MTU-headerSize) bytes.
+const maxFragments = 128
+
+// maxQueued bounds delivered-but-unread messages. Sequenced messages beyond it
+// are dropped; reliable ones are bounded by the sender's in-flight window.
+const maxQueued = 256
+
+// maxSeqAssemblies bounds the sequenced messages being reassembled at once.
+// Only the newest few can still complete usefully; older partials are dropped.
+const maxSeqAssemblies = 4
+
+// closeLinger bounds how long Close waits for reliable messages to be acked.
+const closeLinger = time.Second
+
+// statusAbnormalClosure is reported when the peer stops answering.
+const statusAbnormalClosure session.StatusCode = 1006
+
+// CloseError is returned by Read and Write once either end has closed the connection.
+type CloseError struct {
+	Code   session.StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("udp: connection closed: status = %d and reason = %q", e.Code, e.Reason)
+}
+
+// CloseStatus returns the status code the connection was closed with, or -1
+// if err is not a close error.
+func CloseStatus(err error) session.StatusCode {
+	var ce *CloseError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+	return -1
+}
+
+// Options tunes the transport. Zero values take defaults.
+type Options struct {
+	// MTU is the largest datagram sent, header included. Messages larger than
+	// MTU-16 bytes are fragmented. Default 1200, which fits typical paths
+	// without IP fragmentation.
+	MTU int
+	// ResendInterval is how long a reliable fragment waits for an ack before
+	// being resent. Default 100ms.
+	ResendInterval time.Duration
+	// Timeout closes the connection when nothing is heard from the peer for
+	// this long. Default 10s.
+	Timeout time.Duration
+	// MaxInFlight bounds unacknowledged reliable messages; Write blocks while
+	// the window is full. Default 256.
+	MaxInFlight int
+	// ConnLimits caps connections per remote IP, e.g. the serving
+	// session.Server's ConnLimiter. Nil disables the cap.
+	ConnLimits *session.ConnLimiter
+}
+
+func (o Options) withDefaults() Options {
+	if o.MTU <= headerSize {
+		o.MTU = 1200
+	}
+	if o.ResendInterval <= 0 {
+		o.ResendInterval = 100 * time.Millisecond
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.MaxInFlight <= 0 {
+		o.MaxInFlight = 256
+	}
+	return o
+}
+
+// sequencedTypes are the message types carried on the unreliable sequenced
+// channel: each supersedes the previous one, so a late copy is worthless.
+var sequencedTypes = map[string]bool{
+	"state":     true,
+	"input":     true,
+	"telemetry": true,
+}
+
+// stateDeltaKeys are the one-shot fields a state message may carry. The
+// session sends each of them once, so a state holding any of them must not be
+// lost and goes on the reliable channel instead.
+var stateDeltaKeys = []string{"attributes", "inventory", "equipment", "skills", "level_ups", "skill_xp"}
+
+// outgoing is a reliable message awaiting acknowledgement.
+type outgoing struct {
+	frags    [][]byte
+	acked    []bool
+	left     int
+	lastSent time.Time
+}
+
+// Conn is one end of a UDP connection. It implements session.Conn; the
+// client end returned by Dial offers the same methods from the client's
+// point of view.
+type Conn struct {
+	id      uint32
+	opts    Options
+	send    func([]byte) error
+	onClose func()
+
+	mu        sync.Mutex
+	queue     []json.RawMessage
+	notify    chan struct{}
+	lastHeard time.Time
+
+	// sequenced channel
+	seqOut      uint32
+	seqIn       uint32
+	seqAssembly map[uint32]*assembly
+
+	// reliable channel
+	relOut      uint32
+	inFlight    map[uint32]*outgoing
+	window      chan struct{}
+	relNext     uint32
+	relAssembly map[uint32]*assembly
+	relReady    map[uint32]json.RawMessage
+
+	pingNonce uint32
+	pings     map[uint32]chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	err       *CloseError
+}
+
+var _ session.Conn = (*Conn)(nil)
+
+func newConn(id uint32, opts Options, send func([]byte) error, onClose func()) *Conn {
+	c := &Conn{
+		id:          id,
+		opts:        opts,
+		send:        send,
+		onClose:     onClose,
+		notify:      make(chan struct{}, 1),
+		lastHeard:   time.Now(),
+		seqAssembly: make(map[uint32]*assembly),
+		inFlight:    make(map[uint32]*outgoing),
+		window:      make(chan struct{}, opts.MaxInFlight),
+		relNext:     1,
+		relAssembly: make(map[uint32]*assembly),
+		relReady:    make(map[uint32]json.RawMessage),
+		pings:       make(map[uint32]chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	go c.maintain()
+	return c
+}
+
+// Read returns the next message from the peer. Messages received before the
+// connection closed are still delivered.
+func (c *Conn) Read(ctx context.Context) (json.RawMessage, error) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			msg := c.queue[0]
+			c.queue[0] = nil
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return msg, nil
+		}
+		c.mu.Unlock()
+		select {
+		case <-c.notify:
+		case <-c.closed:
+			c.mu.Lock()
+			n := len(c.queue)
+			c.mu.Unlock()
+			if n == 0 {
+				return nil, c.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Write encodes msg as JSON and sends it on the channel its type calls for.
+// Reliable writes block while MaxInFlight messages are unacknowledged.
+func (c *Conn) Write(ctx context.Context, msg any) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	frags := fragment(bs, c.opts.MTU-headerSize)
+	if len(frags) > maxFragments {
+		return fmt.Errorf("udp: message of %d bytes exceeds %d fragments", len(bs), maxFragments)
+	}
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+	}
+	if channelFor(msg, bs) == chanSequenced {
+		c.mu.Lock()
+		c.seqOut++
+		seq := c.seqOut
+		c.mu.Unlock()
+		return c.sendFragments(chanSequenced, seq, frags, nil)
+	}
+
+	select {
+	case c.window <- struct{}{}:
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	out := &outgoing{frags: frags, acked: make([]bool, len(frags)), left: len(frags), lastSent: time.Now()}
+	c.mu.Lock()
+	c.relOut++
+	seq := c.relOut
+	c.inFlight[seq] = out
+	c.mu.Unlock()
+	return c.sendFragments(chanReliable, seq, frags, nil)
+}
+
+// Ping sends pings until the peer answers or ctx expires, so a single lost
+// datagram does not fail it.
+func (c *Conn) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	c.pingNonce++
+	nonce := c.pingNonce
+	pong := make(chan struct{})
+	c.pings[nonce] = pong
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pings, nonce)
+		c.mu.Unlock()
+	}()
+
+	t := time.NewTicker(c.opts.ResendInterval)
+	defer t.Stop()
+	for {
+		if err := c.send(packet{kind: kindPing, connID: c.id, seq: nonce}.encode()); err != nil {
+			return err
+		}
+		select {
+		case <-pong:
+			return nil
+		case <-t.C:
+		case <-c.closed:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close tells the peer the connection is closed and releases it. Only the
+// first close's status is kept. Reliable messages still in flight get up to
+// closeLinger to be acknowledged first, so a final notice is not overtaken by
+// the close.
+func (c *Conn) Close(code session.StatusCode, reason string) error {
+	c.awaitAcks(closeLinger)
+	c.shutdown(code, reason, true)
+	return nil
+}
+
+// awaitAcks waits until no reliable message is in flight, the connection
+// closes or d elapses.
+func (c *Conn) awaitAcks(d time.Duration) {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		n := len(c.inFlight)
+		c.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(c.opts.ResendInterval / 4):
+		}
+	}
+}
+
+// shutdown marks the connection closed, optionally telling the peer. The close
+// packet is sent a few times since nothing acknowledges it.
+func (c *Conn) shutdown(code session.StatusCode, reason string, notify bool) {
+	c.closeOnce.Do(func() {
+		c.err = &CloseError{Code: code, Reason: reason}
+		close(c.closed)
+		if notify {
+			pkt := packet{kind: kindClose, connID: c.id, payload: closePayload(code, reason)}.encode()
+			for i := 0; i < 3; i++ {
+				_ = c.send(pkt)
+			}
+		}
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+// handle processes one packet addressed to this connection.
+func (c *Conn) handle(p packet) {
+	c.mu.Lock()
+	c.lastHeard = time.Now()
+	c.mu.Unlock()
+
+	switch p.kind {
+	case kindData:
+		if p.channel == chanReliable {
+			c.handleReliable(p)
+		} else {
+			c.handleSequenced(p)
+		}
+	case kindAck:
+		c.handleAck(p)
+	case kindPing:
+		_ = c.send(packet{kind: kindPong, connID: c.id, seq: p.seq}.encode())
+	case kindPong:
+		c.mu.Lock()
+		if ch, ok := c.pings[p.seq]; ok {
+			close(ch)
+			delete(c.pings, p.seq)
+		}
+		c.mu.Unlock()
+	case kindClose:
+		ce := parseClose(p.payload)
+		c.shutdown(ce.Code, ce.Reason, false)
+	}
+}
+
+func (c *Conn) handleSequenced(p packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.seq <= c.seqIn {
+		return // superseded
+	}
+	a, ok := c.seqAssembly[p.seq]
+	if !ok {
+		newest := p.seq
+		for seq := range c.seqAssembly {
+			if seq > newest {
+				newest = seq
+			}
+		}
+		if newest-p.seq >= maxSeqAssemblies {
+			return // a newer message is already arriving
+		}
+		for seq := range c.seqAssembly {
+			if newest-seq >= maxSeqAssemblies {
+				delete(c.seqAssembly, seq)
+			}
+		}
+		a = newAssembly(p.fragCount)
+		c.seqAssembly[p.seq] = a
+	}
+	if !a.add(p.fragIdx, p.payload) {
+		return
+	}
+	c.seqIn = p.seq
+	for seq := range c.seqAssembly {
+		if seq <= c.seqIn {
+			delete(c.seqAssembly, seq)
+		}
+	}
+	if len(c.queue) < maxQueued {
+		c.pushLocked(a.bytes())
+	}
+}
+
+func (c *Conn) handleReliable(p packet) {
+	// Ack every copy, including duplicates whose earlier ack was lost.
+	_ = c.send(packet{kind: kindAck, channel: chanReliable, connID: c.id, seq: p.seq, fragIdx: p.fragIdx, fragCount: p.fragCount}.encode())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.seq < c.relNext || c.relReady[p.seq] != nil {
+		return // already have it
+	}
+	if p.seq-c.relNext >= uint32(c.opts.MaxInFlight) {
+		return // outside any window the peer could be using
+	}
+	a, ok := c.relAssembly[p.seq]
+	if !ok {
+		a = newAssembly(p.fragCount)
+		c.relAssembly[p.seq] = a
+	}
+	if !a.add(p.fragIdx, p.payload) {
+		return
+	}
+	delete(c.relAssembly, p.seq)
+	c.relReady[p.seq] = a.bytes()
+	for {
+		msg, ok := c.relReady[c.relNext]
+		if !ok {
+			break
+		}
+		delete(c.relReady, c.relNext)
+		c.relNext++
+		c.pushLocked(msg)
+	}
+}
+
+func (c *Conn) handleAck(p packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out, ok := c.inFlight[p.seq]
+	if !ok || int(p.fragIdx) >= len(out.acked) || out.acked[p.fragIdx] {
+		return
+	}
+	out.acked[p.fragIdx] = true
+	out.left--
+	if out.left == 0 {
+		delete(c.inFlight, p.seq)
+		<-c.window
+	}
+}
+
+// pushLocked queues a delivered message for Read. c.mu must be held.
+func (c *Conn) pushLocked(msg []byte) {
+	c.queue = append(c.queue, msg)
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// sendFragments sends the fragments of message seq, skipping acked ones.
+func (c *Conn) sendFragments(ch channel, seq uint32, frags [][]byte, acked []bool) error {
+	for i, f := range frags {
+		if acked != nil && acked[i] {
+			continue
+		}
+		pkt := packet{kind: kindData, channel: ch, connID: c.id, seq: seq, fragIdx: uint16(i), fragCount: uint16(len(frags)), payload: f}
+		if err := c.send(pkt.encode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maintain resends unacknowledged reliable fragments and times out silent peers.
+func (c *Conn) maintain() {
+	t := time.NewTicker(c.opts.ResendInterval / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case now := <-t.C:
+			c.mu.Lock()
+			if now.Sub(c.lastHeard) > c.opts.Timeout {
+				c.mu.Unlock()
+				c.shutdown(statusAbnormalClosure, "timeout", false)
+				return
+			}
+			type resend struct {
+				seq   uint32
+				frags [][]byte
+				acked []bool
+			}
+			var due []resend
+			for seq, out := range c.inFlight {
+				if now.Sub(out.lastSent) >= c.opts.ResendInterval {
+					out.lastSent = now
+					due = append(due, resend{seq, out.frags, append([]bool(nil), out.acked...)})
+				}
+			}
+			c.mu.Unlock()
+			for _, r := range due {
+				_ = c.sendFragments(chanReliable, r.seq, r.frags, r.acked)
+			}
+		}
+	}
+}
+
+// channelFor picks the channel for a message by its "type" field. Sessions
+// write maps, so the encoded form is only parsed for other values.
+func channelFor(msg any, encoded []byte) channel {
+	var typ string
+	hasDelta := false
+	if m, ok := msg.(map[string]any); ok {
+		typ, _ = m["type"].(string)
+		if data, ok := m["data"].(map[string]any); ok {
+			for _, k := range stateDeltaKeys {
+				if _, ok := data[k]; ok {
+					hasDelta = true
+					break
+				}
+			}
+		}
+	} else {
+		var env struct {
+			Type string                     `json:"type"`
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		_ = json.Unmarshal(encoded, &env)
+		typ = env.Type
+		for _, k := range stateDeltaKeys {
+			if _, ok := env.Data[k]; ok {
+				hasDelta = true
+				break
+			}
+		}
+	}
+	if typ == "state" && hasDelta {
+		return chanReliable
+	}
+	if sequencedTypes[typ] {
+		return chanSequenced
+	}
+	return chanReliable
+}
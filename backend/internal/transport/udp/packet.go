@@ -0,0 +1,158 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"prototype-game/backend/internal/transport/session"
+)
+
+// Wire format: every datagram starts with a fixed 16 byte header.
+//
+//	0-1   magic 0x5047 ("PG")
+//	2     packet kind
+//	3     channel (data and ack packets)
+//	4-7   connection id, assigned by the server in the accept packet
+//	8-11  sequence number (message seq for data/ack, nonce for ping/pong)
+//	12-13 fragment index
+//	14-15 fragment count
+//
+// followed by the payload: a message fragment for data packets, a close code
+// and reason for close packets, a cookie for challenge packets and the
+// connect packets answering them, nothing otherwise.
+const (
+	magic      = 0x5047
+	headerSize = 16
+)
+
+type packetKind uint8
+
+const (
+	kindConnect packetKind = iota + 1 // client -> server, conn id 0
+	kindAccept                        // server -> client, carries the new conn id
+	kindData
+	kindAck
+	kindPing
+	kindPong
+	kindClose
+	kindChallenge // server -> client, carries a cookie the next connect must echo
+)
+
+// channel selects delivery guarantees for a message.
+type channel uint8
+
+const (
+	// chanSequenced delivers the newest message only: late or out-of-order
+	// messages are dropped and nothing is retransmitted. Used for state and input.
+	chanSequenced channel = iota
+	// chanReliable retransmits until acknowledged and delivers in order. Used
+	// for the join handshake, commands and state carrying one-shot deltas.
+	chanReliable
+)
+
+type packet struct {
+	kind      packetKind
+	channel   channel
+	connID    uint32
+	seq       uint32
+	fragIdx   uint16
+	fragCount uint16
+	payload   []byte
+}
+
+var errBadPacket = errors.New("udp: malformed packet")
+
+func (p packet) encode() []byte {
+	buf := make([]byte, headerSize+len(p.payload))
+	binary.BigEndian.PutUint16(buf[0:2], magic)
+	buf[2] = byte(p.kind)
+	buf[3] = byte(p.channel)
+	binary.BigEndian.PutUint32(buf[4:8], p.connID)
+	binary.BigEndian.PutUint32(buf[8:12], p.seq)
+	binary.BigEndian.PutUint16(buf[12:14], p.fragIdx)
+	binary.BigEndian.PutUint16(buf[14:16], p.fragCount)
+	copy(buf[headerSize:], p.payload)
+	return buf
+}
+
+func decodePacket(buf []byte) (packet, error) {
+	if len(buf) < headerSize || binary.BigEndian.Uint16(buf[0:2]) != magic {
+		return packet{}, errBadPacket
+	}
+	p := packet{
+		kind:      packetKind(buf[2]),
+		channel:   channel(buf[3]),
+		connID:    binary.BigEndian.Uint32(buf[4:8]),
+		seq:       binary.BigEndian.Uint32(buf[8:12]),
+		fragIdx:   binary.BigEndian.Uint16(buf[12:14]),
+		fragCount: binary.BigEndian.Uint16(buf[14:16]),
+		payload:   append([]byte(nil), buf[headerSize:]...),
+	}
+	if p.kind < kindConnect || p.kind > kindChallenge {
+		return packet{}, errBadPacket
+	}
+	if p.kind == kindData && (p.fragCount == 0 || p.fragIdx >= p.fragCount || p.fragCount > maxFragments) {
+		return packet{}, errBadPacket
+	}
+	return p, nil
+}
+
+// closePayload encodes a close code and reason.
+func closePayload(code session.StatusCode, reason string) []byte {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, reason...)
+}
+
+// parseClose decodes a close payload; an empty one reports an abnormal closure.
+func parseClose(payload []byte) *CloseError {
+	if len(payload) < 2 {
+		return &CloseError{Code: statusAbnormalClosure}
+	}
+	return &CloseError{Code: session.StatusCode(binary.BigEndian.Uint16(payload)), Reason: string(payload[2:])}
+}
+
+// fragment splits a message into payloads of at most size bytes.
+func fragment(msg []byte, size int) [][]byte {
+	if len(msg) == 0 {
+		return [][]byte{{}}
+	}
+	frags := make([][]byte, 0, (len(msg)+size-1)/size)
+	for len(msg) > 0 {
+		n := min(size, len(msg))
+		frags = append(frags, msg[:n])
+		msg = msg[n:]
+	}
+	return frags
+}
+
+// assembly collects the fragments of one message.
+type assembly struct {
+	frags [][]byte
+	have  int
+}
+
+func newAssembly(count uint16) *assembly {
+	return &assembly{frags: make([][]byte, count)}
+}
+
+// add stores a fragment and reports whether the message is complete.
+func (a *assembly) add(idx uint16, payload []byte) bool {
+	if int(idx) < len(a.frags) && a.frags[idx] == nil {
+		a.frags[idx] = payload
+		a.have++
+	}
+	return a.have == len(a.frags)
+}
+
+func (a *assembly) bytes() []byte {
+	n := 0
+	for _, f := range a.frags {
+		n += len(f)
+	}
+	out := make([]byte, 0, n)
+	for _, f := range a.frags {
+		out = append(out, f...)
+	}
+	return out
+}
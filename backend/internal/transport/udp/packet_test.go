@@ -0,0 +1,117 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPacket_RoundTrip(t *testing.T) {
+	in := packet{kind: kindData, channel: chanReliable, connID: 42, seq: 7, fragIdx: 1, fragCount: 3, payload: []byte("hi")}
+	out, err := decodePacket(in.encode())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.kind != in.kind || out.channel != in.channel || out.connID != in.connID || out.seq != in.seq ||
+		out.fragIdx != in.fragIdx || out.fragCount != in.fragCount || !bytes.Equal(out.payload, in.payload) {
+		t.Fatalf("round trip mismatch: got %+v want %+v", out, in)
+	}
+}
+
+func TestPacket_RejectsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"short":     {0x50, 0x47, 1},
+		"bad magic": packet{kind: kindPing}.encode()[1:],
+		"bad kind":  packet{kind: 99}.encode(),
+		"frag idx":  packet{kind: kindData, fragIdx: 2, fragCount: 2}.encode(),
+		"no frags":  packet{kind: kindData}.encode(),
+		"too many":  packet{kind: kindData, fragCount: maxFragments + 1}.encode(),
+	}
+	for name, buf := range cases {
+		if _, err := decodePacket(buf); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFragment_ReassemblesInAnyOrder(t *testing.T) {
+	msg := bytes.Repeat([]byte("abcdefg"), 100)
+	frags := fragment(msg, 64)
+	if len(frags) != 11 {
+		t.Fatalf("expected 11 fragments of <=64 bytes, got %d", len(frags))
+	}
+	a := newAssembly(uint16(len(frags)))
+	for i := len(frags) - 1; i >= 0; i-- {
+		done := a.add(uint16(i), frags[i])
+		a.add(uint16(i), frags[i]) // duplicates are ignored
+		if done != (i == 0) {
+			t.Fatalf("complete after fragment %d = %v", i, done)
+		}
+	}
+	if !bytes.Equal(a.bytes(), msg) {
+		t.Fatal("reassembled message differs")
+	}
+}
+
+func TestConn_SequencedDropsStaleMessages(t *testing.T) {
+	c := newConn(1, Options{}.withDefaults(), func([]byte) error { return nil }, nil)
+	defer c.shutdown(1000, "", false)
+
+	data := func(seq uint32, idx, count uint16, s string) packet {
+		return packet{kind: kindData, channel: chanSequenced, connID: 1, seq: seq, fragIdx: idx, fragCount: count, payload: []byte(s)}
+	}
+	c.handle(data(2, 0, 2, `{"n":`)) // seq 2 partially received
+	c.handle(data(3, 0, 1, `{"n":3}`))
+	c.handle(data(2, 1, 2, `2}`)) // completes seq 2, but 3 superseded it
+	c.handle(data(1, 0, 1, `{"n":1}`))
+	c.handle(data(4, 0, 1, `{"n":4}`))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queue) != 2 || string(c.queue[0]) != `{"n":3}` || string(c.queue[1]) != `{"n":4}` {
+		t.Fatalf("expected only seq 3 and 4 delivered, got %q", c.queue)
+	}
+	if len(c.seqAssembly) != 0 {
+		t.Fatalf("stale partial assemblies kept: %d", len(c.seqAssembly))
+	}
+}
+
+func TestChannelFor_StateDeltasAreReliable(t *testing.T) {
+	plain := map[string]any{"type": "state", "data": map[string]any{"tick": 1}}
+	delta := map[string]any{"type": "state", "data": map[string]any{"tick": 2, "inventory": []int{}}}
+	if ch := channelFor(plain, nil); ch != chanSequenced {
+		t.Fatalf("plain state: expected sequenced channel, got %d", ch)
+	}
+	if ch := channelFor(delta, nil); ch != chanReliable {
+		t.Fatalf("state with a delta: expected reliable channel, got %d", ch)
+	}
+	if ch := channelFor(json.RawMessage(`{"type":"state","data":{"level_ups":[]}}`), []byte(`{"type":"state","data":{"level_ups":[]}}`)); ch != chanReliable {
+		t.Fatalf("encoded state with a delta: expected reliable channel, got %d", ch)
+	}
+}
+
+func TestConn_SequencedBoundsPartialAssemblies(t *testing.T) {
+	c := newConn(1, Options{}.withDefaults(), func([]byte) error { return nil }, nil)
+	defer c.shutdown(1000, "", false)
+
+	// First fragments only, for many seqs: none of them ever completes.
+	for seq := uint32(1); seq <= 100; seq++ {
+		c.handle(packet{kind: kindData, channel: chanSequenced, connID: 1, seq: seq, fragIdx: 0, fragCount: 2, payload: []byte(`{`)})
+	}
+	// A fragment of a seq far behind the newest partial is not kept either.
+	c.handle(packet{kind: kindData, channel: chanSequenced, connID: 1, seq: 50, fragIdx: 1, fragCount: 2, payload: []byte(`}`)})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.seqAssembly) > maxSeqAssemblies {
+		t.Fatalf("expected at most %d partial assemblies, got %d", maxSeqAssemblies, len(c.seqAssembly))
+	}
+	for seq := range c.seqAssembly {
+		if 100-seq >= maxSeqAssemblies {
+			t.Fatalf("kept a partial assembly for seq %d behind the newest partial seq 100", seq)
+		}
+	}
+	if len(c.queue) != 0 {
+		t.Fatalf("expected nothing delivered, got %q", c.queue)
+	}
+}
@@ -0,0 +1,354 @@
+// Package udp is a datagram transport for session.Server, an alternative to
+// transport/ws for fast-paced movement. It carries the same JSON messages over
+// two channels:
+//
+//   - an unreliable sequenced channel for state, input and telemetry, where
+//     only the newest message matters and a late one is dropped;
+//   - a reliable ordered channel for everything else (hello/join_ack, commands
+//     such as equip, errors), retransmitted until acknowledged.
+//
+// Messages larger than the MTU are fragmented and reassembled. A connection
+// starts with a connect/challenge/connect/accept handshake: the server answers
+// a first connect with a stateless cookie and only allocates a connection id
+// once the client echoes it, so spoofed sources cannot open connections. The
+// client then sends its hello on the reliable channel and is joined through
+// the same session.Server path (join.HandleJoin) as websocket clients:
+//
+//	l, _ := udp.Listen(ctx, ":8082", srv.ServePlayer, udp.Options{})
+//	c, _ := udp.Dial(ctx, l.Addr().String(), udp.Options{})
+//	_ = c.Write(ctx, join.Hello{Token: tok})
+//
+// Channels are not ordered relative to each other, so clients must wait for
+// join_ack before sending inputs.
+package udp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/transport/session"
+)
+
+// cookieSize is the length of a handshake cookie. Connect packets are padded
+// to it so a challenge is never larger than the datagram that provoked it.
+const cookieSize = 16
+
+// cookieLifetime is how long an issued cookie is accepted, at least one and
+// at most two periods.
+const cookieLifetime = 10 * time.Second
+
+// Listener accepts UDP connections and serves each in its own goroutine.
+type Listener struct {
+	pc     net.PacketConn
+	opts   Options
+	serve  func(ctx context.Context, c session.Conn)
+	ctx    context.Context
+	secret [32]byte // keys handshake cookies
+	limits *session.ConnLimiter
+
+	mu     sync.Mutex
+	conns  map[uint32]*Conn
+	byAddr map[string]uint32 // for answering repeated connect packets
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+// Listen opens a UDP socket on addr and serves connections with serve (e.g.
+// session.Server.ServePlayer) until ctx is cancelled or Close is called.
+func Listen(ctx context.Context, addr string, serve func(ctx context.Context, c session.Conn), opts Options) (*Listener, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return Serve(ctx, pc, serve, opts), nil
+}
+
+// Serve is Listen over an existing packet connection, which the Listener takes
+// ownership of.
+func Serve(ctx context.Context, pc net.PacketConn, serve func(ctx context.Context, c session.Conn), opts Options) *Listener {
+	l := &Listener{
+		pc:     pc,
+		opts:   opts.withDefaults(),
+		serve:  serve,
+		ctx:    ctx,
+		conns:  make(map[uint32]*Conn),
+		byAddr: make(map[string]uint32),
+		done:   make(chan struct{}),
+		limits: opts.ConnLimits,
+	}
+	if l.limits == nil {
+		l.limits = session.NewConnLimiter(0)
+	}
+	_, _ = rand.Read(l.secret[:])
+	go l.readLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Close()
+		case <-l.done:
+		}
+	}()
+	return l
+}
+
+// Addr returns the local address the listener receives on.
+func (l *Listener) Addr() net.Addr { return l.pc.LocalAddr() }
+
+// Close stops accepting packets and waits for connection handlers to return.
+// Sessions see their connections closed and end as if the client left.
+func (l *Listener) Close() error {
+	select {
+	case <-l.done:
+		return nil
+	default:
+		close(l.done)
+	}
+	l.mu.Lock()
+	conns := make([]*Conn, 0, len(l.conns))
+	for _, c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.mu.Unlock()
+	for _, c := range conns {
+		c.shutdown(session.StatusNormalClosure, "listener closed", true)
+	}
+	l.wg.Wait()
+	return l.pc.Close()
+}
+
+func (l *Listener) readLoop() {
+	buf := make([]byte, 64<<10)
+	for {
+		n, addr, err := l.pc.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("udp: read: %v", err)
+			continue
+		}
+		p, err := decodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		if p.kind == kindConnect {
+			l.handleConnect(addr, p.payload)
+			continue
+		}
+		l.mu.Lock()
+		c := l.conns[p.connID]
+		l.mu.Unlock()
+		if c != nil {
+			c.handle(p)
+		}
+	}
+}
+
+// handleConnect answers a connect packet. A connect without a valid cookie
+// only gets a challenge, so nothing is allocated for addresses that cannot
+// receive replies; undersized connects are ignored.
+func (l *Listener) handleConnect(addr net.Addr, payload []byte) {
+	if len(payload) < cookieSize {
+		return
+	}
+	now := time.Now()
+	if !l.validCookie(addr, payload[:cookieSize], now) {
+		l.mu.Lock()
+		_, known := l.byAddr[addr.String()]
+		l.mu.Unlock()
+		if !known {
+			_, _ = l.pc.WriteTo(packet{kind: kindChallenge, payload: l.cookie(addr, now)}.encode(), addr)
+			return
+		}
+	}
+	l.accept(addr)
+}
+
+// cookie derives the handshake cookie for addr in the period containing t.
+func (l *Listener) cookie(addr net.Addr, t time.Time) []byte {
+	var period [8]byte
+	binary.BigEndian.PutUint64(period[:], uint64(t.UnixNano()/int64(cookieLifetime)))
+	mac := hmac.New(sha256.New, l.secret[:])
+	mac.Write(period[:])
+	mac.Write([]byte(addr.String()))
+	return mac.Sum(nil)[:cookieSize]
+}
+
+// validCookie accepts cookies issued to addr in the current or previous period.
+func (l *Listener) validCookie(addr net.Addr, cookie []byte, now time.Time) bool {
+	return hmac.Equal(cookie, l.cookie(addr, now)) || hmac.Equal(cookie, l.cookie(addr, now.Add(-cookieLifetime)))
+}
+
+// accept answers a verified connect packet, creating the connection on first
+// sight. Clients resend connect until accepted, so a known address just gets
+// its id again; a new one is refused once its IP is at the connection cap.
+func (l *Listener) accept(addr net.Addr) {
+	key := addr.String()
+	l.mu.Lock()
+	id, ok := l.byAddr[key]
+	if !ok {
+		select {
+		case <-l.done:
+			l.mu.Unlock()
+			return
+		default:
+		}
+		ip := session.RemoteIP(key)
+		if !l.limits.Acquire(ip) {
+			l.mu.Unlock()
+			metrics.IncConnectionsRejected("ip_cap")
+			_, _ = l.pc.WriteTo(packet{kind: kindClose, payload: closePayload(session.StatusTryAgainLater, "too many connections")}.encode(), addr)
+			return
+		}
+		id = l.newIDLocked()
+		c := newConn(id, l.opts, func(b []byte) error {
+			_, err := l.pc.WriteTo(b, addr)
+			return err
+		}, func() {
+			l.mu.Lock()
+			delete(l.conns, id)
+			delete(l.byAddr, key)
+			l.mu.Unlock()
+			l.limits.Release(ip)
+		})
+		l.conns[id] = c
+		l.byAddr[key] = id
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			defer c.Close(session.StatusNormalClosure, "bye")
+			l.serve(l.ctx, c)
+		}()
+	}
+	l.mu.Unlock()
+	_, _ = l.pc.WriteTo(packet{kind: kindAccept, connID: id}.encode(), addr)
+}
+
+// newIDLocked picks an unused, non-zero, unpredictable connection id. l.mu must be held.
+func (l *Listener) newIDLocked() uint32 {
+	var b [4]byte
+	for {
+		_, _ = rand.Read(b[:])
+		id := binary.BigEndian.Uint32(b[:])
+		if _, taken := l.conns[id]; id != 0 && !taken {
+			return id
+		}
+	}
+}
+
+// Dial connects to a Listener at addr, resending the handshake until it is
+// accepted, refused or ctx expires. The returned connection is the client end.
+func Dial(ctx context.Context, addr string, opts Options) (*Conn, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	c, err := dial(ctx, pc, raddr, opts)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial runs the client handshake over pc; the connection closes pc when it closes.
+func dial(ctx context.Context, pc net.PacketConn, raddr net.Addr, opts Options) (*Conn, error) {
+	opts = opts.withDefaults()
+	accepted := make(chan uint32, 1)
+	challenged := make(chan []byte, 1)
+	refused := make(chan *CloseError, 1)
+	var (
+		mu   sync.Mutex
+		conn *Conn
+	)
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				mu.Lock()
+				c := conn
+				mu.Unlock()
+				if c != nil {
+					c.shutdown(statusAbnormalClosure, err.Error(), false)
+				}
+				return
+			}
+			if from.String() != raddr.String() {
+				continue
+			}
+			p, err := decodePacket(buf[:n])
+			if err != nil {
+				continue
+			}
+			mu.Lock()
+			c := conn
+			mu.Unlock()
+			switch {
+			case p.kind == kindAccept && c == nil:
+				select {
+				case accepted <- p.connID:
+				default:
+				}
+			case p.kind == kindChallenge && c == nil && len(p.payload) == cookieSize:
+				select {
+				case challenged <- p.payload:
+				default:
+				}
+			case p.kind == kindClose && p.connID == 0 && c == nil:
+				select {
+				case refused <- parseClose(p.payload):
+				default:
+				}
+			case c != nil && p.connID == c.id:
+				c.handle(p)
+			}
+		}
+	}()
+
+	hello := packet{kind: kindConnect, payload: make([]byte, cookieSize)}.encode()
+	t := time.NewTicker(opts.ResendInterval)
+	defer t.Stop()
+	for {
+		if _, err := pc.WriteTo(hello, raddr); err != nil {
+			return nil, err
+		}
+		select {
+		case cookie := <-challenged:
+			hello = packet{kind: kindConnect, payload: cookie}.encode()
+			continue
+		case ce := <-refused:
+			return nil, ce
+		case id := <-accepted:
+			c := newConn(id, opts, func(b []byte) error {
+				_, err := pc.WriteTo(b, raddr)
+				return err
+			}, func() { pc.Close() })
+			mu.Lock()
+			conn = c
+			mu.Unlock()
+			return c, nil
+		case <-t.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
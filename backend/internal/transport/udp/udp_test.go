@@ -0,0 +1,293 @@
+package udp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/transport/session"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	if token == "tok" {
+		return "p1", "Alice", true
+	}
+	return "", "", false
+}
+
+type envelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func readEnvelope(t *testing.T, ctx context.Context, c *Conn) envelope {
+	t.Helper()
+	raw, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return env
+}
+
+// lossyConn drops every nth datagram it sends (after the first few, so the
+// handshake is not starved).
+type lossyConn struct {
+	net.PacketConn
+	n    int64
+	sent atomic.Int64
+}
+
+func (l *lossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if i := l.sent.Add(1); i > 2 && i%l.n == 0 {
+		return len(b), nil
+	}
+	return l.PacketConn.WriteTo(b, addr)
+}
+
+func listenLoopback(t *testing.T, dropEvery int64) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	if dropEvery > 0 {
+		return &lossyConn{PacketConn: pc, n: dropEvery}
+	}
+	return pc
+}
+
+// pair serves one connection with serve over loopback and returns the client end.
+func pair(t *testing.T, ctx context.Context, dropEvery int64, opts Options, serve func(ctx context.Context, c session.Conn)) *Conn {
+	t.Helper()
+	l := Serve(ctx, listenLoopback(t, dropEvery), serve, opts)
+	t.Cleanup(func() { l.Close() })
+	c, err := dial(ctx, listenLoopback(t, dropEvery), l.Addr(), opts)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { c.shutdown(session.StatusNormalClosure, "bye", true) })
+	return c
+}
+
+func TestConn_ReliableInOrderUnderLoss(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const total = 50
+	c := pair(t, ctx, 3, Options{ResendInterval: 20 * time.Millisecond}, func(ctx context.Context, sc session.Conn) {
+		for i := 1; i <= total; i++ {
+			if err := sc.Write(ctx, map[string]any{"type": "cmd", "n": i}); err != nil {
+				t.Errorf("write %d: %v", i, err)
+				return
+			}
+		}
+		sc.Close(session.StatusPolicyViolation, "done")
+	})
+
+	for i := 1; i <= total; i++ {
+		raw, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+		var m struct{ N int }
+		if err := json.Unmarshal(raw, &m); err != nil || m.N != i {
+			t.Fatalf("got %s, want n=%d", raw, i)
+		}
+	}
+	if _, err := c.Read(ctx); CloseStatus(err) != session.StatusPolicyViolation {
+		t.Fatalf("expected close status %d after all messages, got %v", session.StatusPolicyViolation, err)
+	}
+}
+
+func TestConn_FragmentsLargeMessagesBothWays(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	big := strings.Repeat("x", 5000)
+	echo := func(ctx context.Context, sc session.Conn) {
+		for {
+			raw, err := sc.Read(ctx)
+			if err != nil {
+				return
+			}
+			var m map[string]any
+			_ = json.Unmarshal(raw, &m)
+			if sc.Write(ctx, m) != nil {
+				return
+			}
+		}
+	}
+	c := pair(t, ctx, 0, Options{MTU: 256}, echo)
+
+	for _, typ := range []string{"cmd", "state"} { // reliable, then sequenced
+		if err := c.Write(ctx, map[string]any{"type": typ, "blob": big}); err != nil {
+			t.Fatalf("write %s: %v", typ, err)
+		}
+		env := readEnvelope(t, ctx, c)
+		if env.Type != typ {
+			t.Fatalf("expected %s echo, got %s", typ, env.Type)
+		}
+	}
+	if err := c.Write(ctx, map[string]any{"blob": strings.Repeat("y", maxFragments*240)}); err == nil {
+		t.Fatal("expected error for a message exceeding maxFragments")
+	}
+}
+
+func TestConn_ClosedWhenPeerGoesSilent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opts := Options{ResendInterval: 20 * time.Millisecond, Timeout: 200 * time.Millisecond}
+	served := make(chan error, 1)
+	c := pair(t, ctx, 0, opts, func(ctx context.Context, sc session.Conn) {
+		_, err := sc.Read(ctx)
+		served <- err
+	})
+	c.shutdown(session.StatusNormalClosure, "gone", false) // vanish without telling the server
+
+	select {
+	case err := <-served:
+		if CloseStatus(err) != statusAbnormalClosure {
+			t.Fatalf("expected timeout close %d, got %v", statusAbnormalClosure, err)
+		}
+	case <-ctx.Done():
+		t.Fatal("server never timed out the silent client")
+	}
+}
+
+func TestListener_ConnectWithoutCookieOnlyGetsChallenge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	l := Serve(ctx, listenLoopback(t, 0), func(ctx context.Context, c session.Conn) { _, _ = c.Read(ctx) }, Options{})
+	defer l.Close()
+	pc := listenLoopback(t, 0)
+	defer pc.Close()
+
+	// An undersized connect is ignored; a padded one is challenged.
+	_, _ = pc.WriteTo(packet{kind: kindConnect}.encode(), l.Addr())
+	_, _ = pc.WriteTo(packet{kind: kindConnect, payload: make([]byte, cookieSize)}.encode(), l.Addr())
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read challenge: %v", err)
+	}
+	p, err := decodePacket(buf[:n])
+	if err != nil || p.kind != kindChallenge || len(p.payload) != cookieSize {
+		t.Fatalf("expected a challenge with a cookie, got %+v (%v)", p, err)
+	}
+	if n > headerSize+cookieSize {
+		t.Fatalf("challenge of %d bytes is larger than the connect", n)
+	}
+	l.mu.Lock()
+	conns := len(l.conns)
+	l.mu.Unlock()
+	if conns != 0 {
+		t.Fatalf("expected no connection before the cookie is echoed, got %d", conns)
+	}
+
+	// Echoing the cookie gets the connection accepted.
+	_, _ = pc.WriteTo(packet{kind: kindConnect, payload: p.payload}.encode(), l.Addr())
+	n, _, err = pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read accept: %v", err)
+	}
+	if p, err := decodePacket(buf[:n]); err != nil || p.kind != kindAccept || p.connID == 0 {
+		t.Fatalf("expected accept, got %+v (%v)", p, err)
+	}
+}
+
+func TestListener_PerIPConnectionCap(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opts := Options{ConnLimits: session.NewConnLimiter(1)}
+	l := Serve(ctx, listenLoopback(t, 0), func(ctx context.Context, c session.Conn) { _, _ = c.Read(ctx) }, opts)
+	defer l.Close()
+
+	c, err := dial(ctx, listenLoopback(t, 0), l.Addr(), opts)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.shutdown(session.StatusNormalClosure, "bye", true)
+	pc := listenLoopback(t, 0)
+	defer pc.Close()
+	if _, err := dial(ctx, pc, l.Addr(), opts); CloseStatus(err) != session.StatusTryAgainLater {
+		t.Fatalf("expected second connection from the same IP to be refused with %d, got %v", session.StatusTryAgainLater, err)
+	}
+}
+
+func TestSession_JoinInputAndStateOverUDP(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+	srv := session.NewServer(fakeAuth{}, eng, nil, session.Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	l, err := Listen(ctx, "127.0.0.1:0", srv.ServePlayer, Options{})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	c, err := Dial(ctx, l.Addr().String(), Options{})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(session.StatusNormalClosure, "bye")
+
+	if err := c.Write(ctx, join.Hello{Token: "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	if env := readEnvelope(t, ctx, c); env.Type != "join_ack" {
+		t.Fatalf("expected join_ack, got %s", env.Type)
+	}
+	input := map[string]any{"type": "input", "seq": 1, "dt": 0.05, "intent": map[string]float64{"x": 1, "z": 0}}
+	if err := c.Write(ctx, input); err != nil {
+		t.Fatalf("input: %v", err)
+	}
+	for {
+		env := readEnvelope(t, ctx, c)
+		if env.Type != "state" {
+			continue
+		}
+		var st struct {
+			Ack    int `json:"ack"`
+			Player struct {
+				Pos struct{ X float64 } `json:"pos"`
+			} `json:"player"`
+		}
+		if err := json.Unmarshal(env.Data, &st); err != nil {
+			t.Fatalf("decode state: %v", err)
+		}
+		if st.Ack == 1 && st.Player.Pos.X > 0 {
+			return
+		}
+	}
+}
+
+func TestSession_RejectedJoinGetsErrorOnReliableChannel(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	srv := session.NewServer(fakeAuth{}, eng, nil, session.Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := pair(t, ctx, 3, Options{ResendInterval: 20 * time.Millisecond}, srv.ServePlayer)
+
+	if err := c.Write(ctx, join.Hello{Token: "nope"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	if env := readEnvelope(t, ctx, c); env.Type != "error" {
+		t.Fatalf("expected error, got %s", env.Type)
+	}
+	if _, err := c.Read(ctx); CloseStatus(err) != session.StatusNormalClosure {
+		t.Fatalf("expected normal close after the error, got %v", err)
+	}
+}
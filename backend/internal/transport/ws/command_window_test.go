@@ -0,0 +1,82 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/sim"
+)
+
+// readEquipResult reads until the next equipment_result and returns its data.
+func readEquipResult(t *testing.T, ctx context.Context, c *nws.Conn) map[string]any {
+	t.Helper()
+	for {
+		var msg struct {
+			Type string         `json:"type"`
+			Data map[string]any `json:"data"`
+		}
+		if err := wsjson.Read(ctx, c, &msg); err != nil {
+			t.Fatalf("read equipment_result: %v", err)
+		}
+		if msg.Type == "equipment_result" {
+			return msg.Data
+		}
+	}
+}
+
+func TestWS_CommandDedupSurvivesResume(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	Register(mux, "/ws", fakeAuthR{}, eng)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c1, ack := joinWithResume(t, ctx, wsURL, "", 0)
+	token, _ := ack["resume"].(string)
+	if err := eng.DevAddItemToPlayer("p1", "sword_iron", 1, sim.CompartmentBackpack); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.DevGivePlayerSkill("p1", "melee", 10); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := eng.GetPlayer("p1")
+	equip := map[string]any{
+		"type":        "equip",
+		"seq":         7,
+		"instance_id": string(p.Inventory.Items[len(p.Inventory.Items)-1].Instance.InstanceID),
+		"slot":        "main_hand",
+	}
+	if err := wsjson.Write(ctx, c1, equip); err != nil {
+		t.Fatal(err)
+	}
+	if res := readEquipResult(t, ctx, c1); res["success"] != true {
+		t.Fatalf("expected equip to succeed, got %v", res)
+	}
+	_ = c1.Close(nws.StatusNormalClosure, "bye")
+
+	// The client retries the command after resuming; it must not run twice.
+	c2, _ := joinWithResume(t, ctx, wsURL, token, 0)
+	defer c2.Close(nws.StatusNormalClosure, "bye")
+	if err := wsjson.Write(ctx, c2, equip); err != nil {
+		t.Fatal(err)
+	}
+	if res := readEquipResult(t, ctx, c2); res["success"] != true || res["code"] != "success" {
+		t.Fatalf("expected cached success result after resume, got %v", res)
+	}
+}
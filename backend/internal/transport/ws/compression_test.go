@@ -0,0 +1,108 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/sim"
+)
+
+func TestParseCompressionMode(t *testing.T) {
+	for in, want := range map[string]CompressionMode{
+		"":                    CompressionDisabled,
+		"off":                 CompressionDisabled,
+		"context-takeover":    CompressionContextTakeover,
+		"no-context-takeover": CompressionNoContextTakeover,
+	} {
+		if got, err := ParseCompressionMode(in); err != nil || got != want {
+			t.Errorf("ParseCompressionMode(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseCompressionMode("gzip"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+// wireCount scrapes the number of state messages measured for a compression label.
+func wireCount(t *testing.T, srvURL, compression string) int {
+	t.Helper()
+	resp, err := http.Get(srvURL + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	m := regexp.MustCompile(`ws_snapshot_wire_bytes_count\{compression="` + compression + `"\} (\d+)`).FindSubmatch(body)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+func TestCompression_NegotiationAndWireMetrics(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/deflate", fakeAuth{}, eng, nil, WSOptions{Compression: CompressionContextTakeover, CompressionThreshold: 64})
+	RegisterWithOptions(mux, "/plain", fakeAuth{}, eng, nil, WSOptions{})
+	mux.Handle("/metrics", metrics.Handler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path, label string
+		negotiated  bool
+	}{
+		{"/deflate", "deflate", true},
+		{"/plain", "none", false}, // the client offers deflate but the server has it off
+	} {
+		before := wireCount(t, srv.URL, tc.label)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + tc.path
+		c, resp, err := nws.Dial(ctx, wsURL, &nws.DialOptions{CompressionMode: nws.CompressionContextTakeover})
+		if err != nil {
+			t.Fatalf("%s: dial: %v", tc.path, err)
+		}
+		ext := resp.Header.Get("Sec-WebSocket-Extensions")
+		if strings.Contains(ext, "permessage-deflate") != tc.negotiated {
+			t.Fatalf("%s: extensions %q, want negotiated=%v", tc.path, ext, tc.negotiated)
+		}
+		if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+			t.Fatalf("%s: hello: %v", tc.path, err)
+		}
+		for states := 0; states < 3; {
+			var env struct {
+				Type string `json:"type"`
+			}
+			var raw json.RawMessage
+			if err := wsjson.Read(ctx, c, &raw); err != nil {
+				t.Fatalf("%s: read: %v", tc.path, err)
+			}
+			if json.Unmarshal(raw, &env) == nil && env.Type == "state" {
+				states++
+			}
+		}
+		c.Close(nws.StatusNormalClosure, "bye")
+		cancel()
+		if after := wireCount(t, srv.URL, tc.label); after < before+3 {
+			t.Fatalf("%s: expected >=3 new compression=%s wire samples, got %d -> %d", tc.path, tc.label, before, after)
+		}
+	}
+}
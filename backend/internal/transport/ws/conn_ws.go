@@ -0,0 +1,90 @@
+//go:build ws
+
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/transport/session"
+)
+
+// wsConn adapts a websocket connection to session.Conn. Each message is one
+// JSON text frame.
+type wsConn struct {
+	c           *nws.Conn
+	wire        *countingConn // bytes written to the socket; nil if unknown
+	compression string        // negotiated compression, as a metrics label
+}
+
+func (w wsConn) Read(ctx context.Context) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := wsjson.Read(ctx, w.c, &raw)
+	return raw, err
+}
+
+// Write sends msg as one frame. State messages also record their size on the
+// wire, after any compression, next to the JSON size in ws_snapshot_bytes. A
+// ping written concurrently can add its few bytes to a sample.
+func (w wsConn) Write(ctx context.Context, msg any) error {
+	m, ok := msg.(map[string]any)
+	if !ok || m["type"] != "state" || w.wire == nil {
+		return wsjson.Write(ctx, w.c, msg)
+	}
+	before := w.wire.written.Load()
+	err := wsjson.Write(ctx, w.c, msg)
+	if err == nil {
+		metrics.ObserveSnapshotWireBytes(int(w.wire.written.Load()-before), w.compression)
+	}
+	return err
+}
+
+func (w wsConn) Ping(ctx context.Context) error { return w.c.Ping(ctx) }
+
+func (w wsConn) Close(code session.StatusCode, reason string) error {
+	return w.c.Close(nws.StatusCode(code), reason)
+}
+
+// hijackCounter wraps the upgrade's ResponseWriter so the hijacked socket
+// counts the bytes the websocket library writes to it.
+type hijackCounter struct {
+	http.ResponseWriter
+	conn *countingConn
+}
+
+func (h *hijackCounter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := h.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	nc, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.conn = &countingConn{Conn: nc}
+	if err := brw.Writer.Flush(); err != nil {
+		return nil, nil, err
+	}
+	brw.Writer.Reset(h.conn)
+	return h.conn, brw, nil
+}
+
+// countingConn counts bytes written through it.
+type countingConn struct {
+	net.Conn
+	written atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,109 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/state"
+)
+
+func TestWS_DrainNotifiesPersistsAndCloses(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+	store := state.NewMemStore()
+	eng.SetPersistenceStore(store)
+	persistCtx, persistCancel := context.WithCancel(context.Background())
+	defer persistCancel()
+	eng.StartPersistence(persistCtx)
+	defer eng.StopPersistence()
+
+	drain := NewDrainer()
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuthT{}, eng, store, WSOptions{Drain: drain})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(nws.StatusNormalClosure, "bye")
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	var ack map[string]any
+	if err := wsjson.Read(ctx, c, &ack); err != nil || ack["type"] != "join_ack" {
+		t.Fatalf("join_ack: %v %v", ack, err)
+	}
+	// Move so the final persist has something new to save.
+	if err := wsjson.Write(ctx, c, map[string]any{"type": "input", "seq": 1, "dt": 0.05, "intent": map[string]any{"x": 1, "z": 0}}); err != nil {
+		t.Fatalf("input: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	drain.Start(DrainNotice{ReconnectAfter: 1500 * time.Millisecond, Address: "ws://alt/ws"})
+
+	var notice struct {
+		Type string `json:"type"`
+		Data struct {
+			ReconnectAfterMs int64  `json:"reconnect_after_ms"`
+			Address          string `json:"address"`
+		} `json:"data"`
+	}
+	for notice.Type != "server_shutdown" {
+		if err := wsjson.Read(ctx, c, &notice); err != nil {
+			t.Fatalf("expected server_shutdown before close, got %v", err)
+		}
+	}
+	if notice.Data.ReconnectAfterMs != 1500 || notice.Data.Address != "ws://alt/ws" {
+		t.Fatalf("unexpected server_shutdown data: %+v", notice.Data)
+	}
+	for {
+		var msg map[string]any
+		err := wsjson.Read(ctx, c, &msg)
+		if err == nil {
+			continue
+		}
+		if status := nws.CloseStatus(err); status != nws.StatusServiceRestart {
+			t.Fatalf("expected close status %d, got %v", nws.StatusServiceRestart, err)
+		}
+		break
+	}
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancelWait()
+	if err := drain.Wait(waitCtx); err != nil {
+		t.Fatalf("drain did not complete: %v", err)
+	}
+	ps, ok, err := store.Load(context.Background(), "p1")
+	if err != nil || !ok {
+		t.Fatalf("expected player persisted on drain: ok=%v err=%v", ok, err)
+	}
+	if ps.Pos.X <= 0 {
+		t.Fatalf("expected final position to be persisted, got %+v", ps.Pos)
+	}
+
+	// New connections are refused with a retry hint.
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "2" {
+		t.Fatalf("expected 503 with Retry-After 2, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
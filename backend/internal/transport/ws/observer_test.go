@@ -0,0 +1,172 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+// observerAuth grants "tok" the player role and "otok" the observer role.
+type observerAuth struct{}
+
+func (a observerAuth) Validate(ctx context.Context, token string) (string, string, bool) {
+	pid, name, _, ok := a.ValidateRole(ctx, token)
+	return pid, name, ok
+}
+
+func (observerAuth) ValidateRole(ctx context.Context, token string) (string, string, string, bool) {
+	switch token {
+	case "tok":
+		return "p1", "Alice", join.RolePlayer, true
+	case "otok":
+		return "qa1", "QA", join.RoleObserver, true
+	}
+	return "", "", "", false
+}
+
+type observerMsg struct {
+	Type string         `json:"type"`
+	Data map[string]any `json:"data"`
+}
+
+func newObserverServer(t *testing.T, eng *sim.Engine) (string, func()) {
+	t.Helper()
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", observerAuth{}, eng, nil, WSOptions{})
+	RegisterObserver(mux, "/observe", observerAuth{}, eng, WSOptions{})
+	srv := httptest.NewServer(mux)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), srv.Close
+}
+
+func dialObserver(t *testing.T, ctx context.Context, url string, hello map[string]any) *nws.Conn {
+	t.Helper()
+	c, _, err := nws.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial observer: %v", err)
+	}
+	if err := wsjson.Write(ctx, c, hello); err != nil {
+		t.Fatalf("observer hello: %v", err)
+	}
+	var ack observerMsg
+	if err := wsjson.Read(ctx, c, &ack); err != nil || ack.Type != "observe_ack" {
+		t.Fatalf("expected observe_ack, got %+v err=%v", ack, err)
+	}
+	return c
+}
+
+func TestWS_ObserverFollowsPlayerWithoutSpawning(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	base, stop := newObserverServer(t, eng)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pc := dialAndJoin(t, ctx, base+"/ws")
+	defer pc.Close(nws.StatusNormalClosure, "bye")
+	eng.DevSpawn("p2", "Bob", spatial.Vec2{X: 2, Z: 0})
+
+	oc := dialObserver(t, ctx, base+"/observe", map[string]any{"token": "otok", "follow": "p1"})
+	defer oc.Close(nws.StatusNormalClosure, "bye")
+
+	for {
+		var msg observerMsg
+		if err := wsjson.Read(ctx, oc, &msg); err != nil {
+			t.Fatalf("observer read: %v", err)
+		}
+		if msg.Type != "state" {
+			continue
+		}
+		player, _ := msg.Data["player"].(map[string]any)
+		if player["id"] != "p1" {
+			t.Fatalf("expected followed player in state, got %v", msg.Data)
+		}
+		ents, _ := msg.Data["entities"].([]any)
+		if len(ents) == 0 {
+			continue
+		}
+		break
+	}
+
+	if _, ok := eng.GetPlayer("qa1"); ok {
+		t.Fatal("observer must not spawn a player")
+	}
+	if got := eng.QueryAOI(spatial.Vec2{}, 100, ""); len(got) != 2 {
+		t.Fatalf("expected only the two players in the world, got %d entities", len(got))
+	}
+}
+
+func TestWS_ObserverRetargetEmitsHandover(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	base, stop := newObserverServer(t, eng)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	oc := dialObserver(t, ctx, base+"/observe", map[string]any{"token": "otok", "pos": map[string]float64{"x": 5, "z": 5}})
+	defer oc.Close(nws.StatusNormalClosure, "bye")
+
+	if err := wsjson.Write(ctx, oc, map[string]any{"type": "observe", "pos": map[string]float64{"x": 15, "z": 5}}); err != nil {
+		t.Fatalf("retarget: %v", err)
+	}
+	for {
+		var msg observerMsg
+		if err := wsjson.Read(ctx, oc, &msg); err != nil {
+			t.Fatalf("observer read: %v", err)
+		}
+		if msg.Type == "handover" {
+			to, _ := msg.Data["to"].(map[string]any)
+			if to["Cx"] != float64(1) || to["Cz"] != float64(0) {
+				t.Fatalf("unexpected handover target: %v", msg.Data)
+			}
+			return
+		}
+	}
+}
+
+func TestWS_ObserverRequiresObserverRole(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	base, stop := newObserverServer(t, eng)
+	defer stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := nws.Dial(ctx, base+"/observe", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(nws.StatusNormalClosure, "bye")
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	var env struct {
+		Type  string        `json:"type"`
+		Error join.ErrorMsg `json:"error"`
+	}
+	if err := wsjson.Read(ctx, c, &env); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if env.Type != "error" || env.Error.Code != "forbidden" {
+		t.Fatalf("expected forbidden error, got %+v", env)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build ws
+
+package ws
+
+import (
+	"net/http"
+
+	"prototype-game/backend/internal/join"
+	"prototype-game/backend/internal/metrics"
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/transport/session"
+)
+
+// RegisterObserver installs a read-only spectator endpoint served by
+// session.Server.ServeObserver. Observers authenticate with an observer-role
+// token and either follow a player or watch a fixed position; no player is
+// spawned and nothing is persisted.
+func RegisterObserver(mux *http.ServeMux, path string, auth join.AuthService, eng *sim.Engine, opts WSOptions) {
+	srv := session.NewServer(auth, eng, nil, opts.sessionOptions())
+	mux.HandleFunc(path, acceptHandler(srv, opts, func(r *http.Request, c session.Conn) {
+		metrics.IncObserversConnected()
+		defer metrics.DecObserversConnected()
+		srv.ServeObserver(r.Context(), c)
+	}))
+}
@@ -0,0 +1,144 @@
+package ws
+
+import (
+	"fmt"
+	"time"
+
+	"prototype-game/backend/internal/state"
+	"prototype-game/backend/internal/transport/session"
+)
+
+// Session layer types configured through WSOptions.
+type (
+	RateLimit       = session.RateLimit
+	RateLimitConfig = session.RateLimitConfig
+	TakeoverPolicy  = session.TakeoverPolicy
+	SessionRegistry = session.SessionRegistry
+	ResumeManager   = session.ResumeManager
+	Drainer         = session.Drainer
+	ConnLimiter     = session.ConnLimiter
+	CommandLog      = session.CommandLog
+	DrainNotice     = session.DrainNotice
+)
+
+const (
+	TakeoverKickOld   = session.TakeoverKickOld
+	TakeoverRejectNew = session.TakeoverRejectNew
+)
+
+// NewSessionRegistry creates a registry enforcing the given takeover policy.
+func NewSessionRegistry(policy TakeoverPolicy) *SessionRegistry {
+	return session.NewSessionRegistry(policy)
+}
+
+// NewResumeManager creates a resume token manager backed by an in-memory store.
+func NewResumeManager(ttl time.Duration) *ResumeManager { return session.NewResumeManager(ttl) }
+
+// NewResumeManagerWithStore creates a resume token manager backed by store.
+func NewResumeManagerWithStore(store state.ResumeStore, ttl time.Duration) *ResumeManager {
+	return session.NewResumeManagerWithStore(store, ttl)
+}
+
+// NewDrainer creates a drainer in the accepting state.
+func NewDrainer() *Drainer { return session.NewDrainer() }
+
+// NewCommandLog creates a per-player command dedup log remembering size seqs
+// and dropping windows left unused for ttl.
+func NewCommandLog(size int, ttl time.Duration) *CommandLog {
+	return session.NewCommandLog(size, ttl)
+}
+
+// NewConnLimiter creates a per-IP connection cap; zero disables it.
+func NewConnLimiter(max int) *ConnLimiter { return session.NewConnLimiter(max) }
+
+// CompressionMode selects permessage-deflate behaviour. Compression is only
+// used when the client offers the extension.
+type CompressionMode int
+
+const (
+	// CompressionDisabled never negotiates permessage-deflate.
+	CompressionDisabled CompressionMode = iota
+	// CompressionContextTakeover keeps the deflate window across messages:
+	// better ratios on repetitive state streams for about 32KB more memory per
+	// connection. Falls back to no context takeover if the client asks.
+	CompressionContextTakeover
+	// CompressionNoContextTakeover compresses each message on its own.
+	CompressionNoContextTakeover
+)
+
+// ParseCompressionMode parses "off", "context-takeover" or "no-context-takeover".
+func ParseCompressionMode(s string) (CompressionMode, error) {
+	switch s {
+	case "", "off":
+		return CompressionDisabled, nil
+	case "context-takeover":
+		return CompressionContextTakeover, nil
+	case "no-context-takeover":
+		return CompressionNoContextTakeover, nil
+	}
+	return CompressionDisabled, fmt.Errorf("unknown compression mode %q (want off, context-takeover or no-context-takeover)", s)
+}
+
+// WSOptions contains configuration options for WebSocket behavior
+type WSOptions struct {
+	IdleTimeout time.Duration // if zero, defaults to 30 seconds
+	DevMode     bool          // if true, enables relaxed security for local testing
+
+	// Origin policy (ignored in DevMode). Patterns match the Origin header's host
+	// using path.Match syntax, e.g. "game.example.com" or "*.example.com:*".
+	// The request's own host is always allowed.
+	OriginPatterns []string // if empty, defaults to localhost, 127.0.0.1 and [::1] on any port
+
+	// permessage-deflate; ws_snapshot_wire_bytes vs ws_snapshot_bytes shows the saving
+	Compression          CompressionMode // defaults to CompressionDisabled
+	CompressionThreshold int             // smallest message compressed, in bytes; if zero, 128 with context takeover and 512 without
+
+	// Outbound queue tuning (see session.Options)
+	SendQueueSize       int           // max pending outbound messages per session; if zero, defaults to 64
+	WriteTimeout        time.Duration // per-message socket write deadline; if zero, defaults to 2 seconds
+	SlowConsumerTimeout time.Duration // disconnect when the oldest pending message is older; if zero, defaults to 5 seconds
+
+	// Abuse protection
+	RateLimits    RateLimitConfig // per-session message limits; zero fields use defaults
+	MaxConnsPerIP int             // concurrent connections allowed per remote IP when ConnLimits is nil; zero disables the cap
+	ConnLimits    *ConnLimiter    // optional limiter shared with other handlers and transports
+
+	// Duplicate login handling
+	TakeoverPolicy TakeoverPolicy   // applied when Sessions is nil; defaults to TakeoverKickOld
+	Sessions       *SessionRegistry // optional registry shared with other handlers
+
+	// Session resume
+	Resume    *ResumeManager // optional token manager (e.g. backed by a durable store)
+	ResumeTTL time.Duration  // lifetime of tokens issued by the default manager; if zero, defaults to 60 seconds
+
+	// Command idempotency
+	CommandWindow int         // recent command seqs remembered per player for dedup when CommandLog is nil; if zero, defaults to 128
+	CommandLog    *CommandLog // optional log shared with other handlers and transports
+
+	// Snapshot size
+	SnapshotByteBudget int // max encoded bytes of a state message; lowest priority entities are deferred; if zero, defaults to 32KB
+
+	// Graceful shutdown
+	Drain *Drainer // optional; share one drainer across handlers so a single Start drains them all
+}
+
+// sessionOptions extracts the transport-independent settings.
+func (o WSOptions) sessionOptions() session.Options {
+	return session.Options{
+		IdleTimeout:         o.IdleTimeout,
+		SendQueueSize:       o.SendQueueSize,
+		WriteTimeout:        o.WriteTimeout,
+		SlowConsumerTimeout: o.SlowConsumerTimeout,
+		RateLimits:          o.RateLimits,
+		MaxConnsPerIP:       o.MaxConnsPerIP,
+		ConnLimits:          o.ConnLimits,
+		TakeoverPolicy:      o.TakeoverPolicy,
+		Sessions:            o.Sessions,
+		Resume:              o.Resume,
+		ResumeTTL:           o.ResumeTTL,
+		CommandWindow:       o.CommandWindow,
+		CommandLog:          o.CommandLog,
+		SnapshotByteBudget:  o.SnapshotByteBudget,
+		Drain:               o.Drain,
+	}
+}
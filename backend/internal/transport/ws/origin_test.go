@@ -140,3 +140,39 @@ func TestOriginValidation_ProductionMode_SameOriginAllowed(t *testing.T) {
 		t.Fatalf("write hello: %v", err)
 	}
 }
+
+func TestOriginValidation_ConfiguredPatternsReplaceDefaults(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuth{}, eng, nil, WSOptions{OriginPatterns: []string{"*.example.com"}})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cases := []struct {
+		origin string
+		allow  bool
+	}{
+		{"https://game.example.com", true},
+		{srv.URL, true}, // same-origin is always allowed
+		{"http://localhost:3000", false},
+		{"https://evil.example.org", false},
+	}
+	for _, tc := range cases {
+		headers := http.Header{}
+		headers.Set("Origin", tc.origin)
+		c, _, err := nws.Dial(ctx, wsURL, &nws.DialOptions{HTTPHeader: headers})
+		if tc.allow != (err == nil) {
+			t.Fatalf("origin %s: allowed=%v, err=%v", tc.origin, tc.allow, err)
+		}
+		if c != nil {
+			c.Close(nws.StatusNormalClosure, "bye")
+		}
+	}
+}
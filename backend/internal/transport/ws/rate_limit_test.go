@@ -0,0 +1,111 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/sim"
+)
+
+func TestWS_PerIPConnectionCap(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuth{}, eng, nil, WSOptions{MaxConnsPerIP: 1})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c1, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial1: %v", err)
+	}
+	defer c1.Close(nws.StatusNormalClosure, "bye")
+
+	_, resp, err := nws.Dial(ctx, wsURL, nil)
+	if err == nil {
+		t.Fatal("expected second connection from same IP to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 response, got %+v", resp)
+	}
+}
+
+func TestWS_RateLimitWarnsThenDisconnects(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuth{}, eng, nil, WSOptions{
+		RateLimits: RateLimitConfig{
+			Chat:            RateLimit{Rate: 0.001, Burst: 1},
+			WarnAfter:       2,
+			DisconnectAfter: 5,
+			Window:          time.Minute,
+		},
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(nws.StatusNormalClosure, "bye")
+
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("write hello: %v", err)
+	}
+	var raw json.RawMessage
+	if err := wsjson.Read(ctx, c, &raw); err != nil {
+		t.Fatalf("read join_ack: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		if err := wsjson.Write(ctx, c, map[string]any{"type": "chat", "text": "spam"}); err != nil {
+			break
+		}
+	}
+
+	sawWarning := false
+	for {
+		var msg struct {
+			Type string `json:"type"`
+			Data struct {
+				Code string `json:"code"`
+			} `json:"data"`
+		}
+		if err := wsjson.Read(ctx, c, &msg); err != nil {
+			if status := nws.CloseStatus(err); status != nws.StatusPolicyViolation {
+				t.Fatalf("expected policy violation close, got %v", err)
+			}
+			break
+		}
+		if msg.Type == "error" && msg.Data.Code == "rate_limited" {
+			sawWarning = true
+		}
+	}
+	if !sawWarning {
+		t.Fatal("expected rate_limited warning before disconnect")
+	}
+}
@@ -120,46 +120,78 @@ func TestWS_ReconnectAndResume(t *testing.T) {
 	}
 }
 
-func TestResumeManager_Validate(t *testing.T) {
-	rm := NewResumeManager(time.Second)
-
-	// Test validation with empty inputs
-	if rm.Validate("", "") {
-		t.Error("expected false for empty token and playerID")
+// joinWithResume sends a hello carrying resume/last_seq and returns the join_ack data.
+func joinWithResume(t *testing.T, ctx context.Context, wsURL, resume string, lastSeq int) (*nws.Conn, map[string]any) {
+	t.Helper()
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
 	}
-	if rm.Validate("token", "") {
-		t.Error("expected false for empty playerID")
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok", "resume": resume, "last_seq": lastSeq}); err != nil {
+		t.Fatalf("hello: %v", err)
 	}
-	if rm.Validate("", "player1") {
-		t.Error("expected false for empty token")
+	var env struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
 	}
-
-	// Issue a token for player1
-	token := rm.Issue("player1")
-	if token == "" {
-		t.Fatal("expected non-empty token")
+	if err := wsjson.Read(ctx, c, &env); err != nil || env.Type != "join_ack" {
+		t.Fatalf("join_ack: type=%q err=%v", env.Type, err)
 	}
+	return c, env.Data
+}
 
-	// Test successful validation
-	if !rm.Validate(token, "player1") {
-		t.Error("expected true for valid token and matching playerID")
+// firstStateAck reads until the first state message and returns its ack.
+func firstStateAck(t *testing.T, ctx context.Context, c *nws.Conn) int {
+	t.Helper()
+	for {
+		var e struct {
+			Type string         `json:"type"`
+			Data map[string]any `json:"data"`
+		}
+		if err := wsjson.Read(ctx, c, &e); err != nil {
+			t.Fatalf("read state: %v", err)
+		}
+		if e.Type == "state" {
+			v, _ := e.Data["ack"].(float64)
+			return int(v)
+		}
 	}
+}
 
-	// Test validation with wrong player ID
-	if rm.Validate(token, "player2") {
-		t.Error("expected false for valid token but wrong playerID")
-	}
+func TestWS_ResumeTokenRotatesAndIgnoresClientSeq(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 60, SnapshotHz: 30, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	Register(mux, "/ws", fakeAuthR{}, eng)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
 
-	// Test validation with invalid token
-	if rm.Validate("invalid", "player1") {
-		t.Error("expected false for invalid token")
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c1, ack1 := joinWithResume(t, ctx, wsURL, "", 0)
+	first, _ := ack1["resume"].(string)
+	_ = wsjson.Write(ctx, c1, map[string]any{"type": "input", "seq": 3, "dt": 0.05, "intent": map[string]float64{"x": 1, "z": 0}})
+	_ = c1.Close(nws.StatusNormalClosure, "bye")
+
+	// Resume with a bogus client-reported last_seq: the server-side value wins.
+	c2, ack2 := joinWithResume(t, ctx, wsURL, first, 99)
+	second, _ := ack2["resume"].(string)
+	if second == "" || second == first {
+		t.Fatalf("expected a rotated resume token, got %q", second)
+	}
+	if got := firstStateAck(t, ctx, c2); got != 3 {
+		t.Fatalf("expected ack 3 from server-side resume state, got %d", got)
 	}
+	_ = c2.Close(nws.StatusNormalClosure, "bye")
 
-	// Test validation after expiration
-	rmShort := NewResumeManager(1 * time.Millisecond)
-	tokenShort := rmShort.Issue("player1")
-	time.Sleep(2 * time.Millisecond)
-	if rmShort.Validate(tokenShort, "player1") {
-		t.Error("expected false for expired token")
+	// The consumed token cannot be replayed.
+	c3, _ := joinWithResume(t, ctx, wsURL, first, 99)
+	defer c3.Close(nws.StatusNormalClosure, "bye")
+	if got := firstStateAck(t, ctx, c3); got != 0 {
+		t.Fatalf("expected replayed token to be ignored (ack 0), got %d", got)
 	}
 }
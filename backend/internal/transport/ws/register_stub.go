@@ -22,7 +22,19 @@ func RegisterWithStore(mux *http.ServeMux, path string, auth join.AuthService, e
 }
 
 // RegisterWithStoreAndDevMode is a placeholder when ws is disabled.
-func RegisterWithStoreAndDevMode(mux *http.ServeMux, path string, auth join.AuthService, eng *sim.Engine, _ state.Store, _ bool) {
+func RegisterWithStoreAndDevMode(mux *http.ServeMux, path string, auth join.AuthService, eng *sim.Engine, _ state.Store, devMode bool) {
+	RegisterWithOptions(mux, path, auth, eng, nil, WSOptions{DevMode: devMode})
+}
+
+// RegisterWithOptions is a placeholder when ws is disabled.
+func RegisterWithOptions(mux *http.ServeMux, path string, auth join.AuthService, eng *sim.Engine, _ state.Store, _ WSOptions) {
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "websocket transport not built (use -tags ws)", http.StatusNotImplemented)
+	})
+}
+
+// RegisterObserver is a placeholder when ws is disabled.
+func RegisterObserver(mux *http.ServeMux, path string, auth join.AuthService, eng *sim.Engine, _ WSOptions) {
 	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "websocket transport not built (use -tags ws)", http.StatusNotImplemented)
 	})
@@ -0,0 +1,153 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/state"
+)
+
+// dialAndJoin connects to wsURL and completes the hello/join_ack handshake.
+func dialAndJoin(t *testing.T, ctx context.Context, wsURL string) *nws.Conn {
+	t.Helper()
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("write hello: %v", err)
+	}
+	var env struct {
+		Type string `json:"type"`
+	}
+	if err := wsjson.Read(ctx, c, &env); err != nil {
+		t.Fatalf("read join_ack: %v", err)
+	}
+	if env.Type != "join_ack" {
+		t.Fatalf("expected join_ack, got %q", env.Type)
+	}
+	return c
+}
+
+func TestWS_DuplicateLoginKicksOldSession(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	store := state.NewMemStore()
+	eng.SetPersistenceStore(store)
+	persistCtx, persistCancel := context.WithCancel(context.Background())
+	defer persistCancel()
+	eng.StartPersistence(persistCtx)
+	defer eng.StopPersistence()
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuth{}, eng, store, WSOptions{})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c1 := dialAndJoin(t, ctx, wsURL)
+	defer c1.Close(nws.StatusNormalClosure, "bye")
+	c2 := dialAndJoin(t, ctx, wsURL)
+	defer c2.Close(nws.StatusNormalClosure, "bye")
+
+	sawReplaced := false
+	for {
+		var raw json.RawMessage
+		err := wsjson.Read(ctx, c1, &raw)
+		if err != nil {
+			if status := nws.CloseStatus(err); status != statusSessionReplaced {
+				t.Fatalf("expected close status %d, got %v", statusSessionReplaced, err)
+			}
+			break
+		}
+		var env struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(raw, &env) == nil && env.Type == "session_replaced" {
+			sawReplaced = true
+		}
+	}
+	if !sawReplaced {
+		t.Fatal("expected session_replaced message on displaced connection")
+	}
+
+	// The displaced session hands off its state before the new one restores.
+	if _, ok, _ := store.Load(context.Background(), "p1"); !ok {
+		t.Fatal("expected player state to be persisted during takeover")
+	}
+
+	// The new session keeps receiving state.
+	var env struct {
+		Type string `json:"type"`
+	}
+	for env.Type != "state" {
+		if err := wsjson.Read(ctx, c2, &env); err != nil {
+			t.Fatalf("new session read: %v", err)
+		}
+	}
+}
+
+func TestWS_DuplicateLoginRejectNew(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuth{}, eng, nil, WSOptions{TakeoverPolicy: TakeoverRejectNew})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c1 := dialAndJoin(t, ctx, wsURL)
+	defer c1.Close(nws.StatusNormalClosure, "bye")
+
+	c2, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial2: %v", err)
+	}
+	defer c2.Close(nws.StatusNormalClosure, "bye")
+	if err := wsjson.Write(ctx, c2, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("write hello2: %v", err)
+	}
+	var env struct {
+		Type  string `json:"type"`
+		Error *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := wsjson.Read(ctx, c2, &env); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if env.Type != "error" || env.Error == nil || env.Error.Code != "session_active" {
+		t.Fatalf("expected session_active error, got %+v", env)
+	}
+
+	// The original session is untouched.
+	var msg struct {
+		Type string `json:"type"`
+	}
+	for msg.Type != "state" {
+		if err := wsjson.Read(ctx, c1, &msg); err != nil {
+			t.Fatalf("original session read: %v", err)
+		}
+	}
+}
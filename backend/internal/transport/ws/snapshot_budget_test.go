@@ -0,0 +1,74 @@
+//go:build ws
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nws "nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"prototype-game/backend/internal/sim"
+	"prototype-game/backend/internal/spatial"
+)
+
+func TestWS_StateMessagesRespectByteBudget(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 50, AOIRadius: 40, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+	for i := 0; i < 80; i++ {
+		eng.DevSpawn(fmt.Sprintf("crowd-%02d", i), strings.Repeat("n", 40), spatial.Vec2{X: float64(i%9) * 2, Z: float64(i/9) * 2})
+	}
+
+	const budget = 2048
+	mux := http.NewServeMux()
+	RegisterWithOptions(mux, "/ws", fakeAuthT{}, eng, nil, WSOptions{SnapshotByteBudget: budget})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(nws.StatusNormalClosure, "bye")
+	c.SetReadLimit(1 << 20)
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+
+	deferred := 0
+	for states := 0; states < 5; {
+		_, raw, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var msg struct {
+			Type string `json:"type"`
+			Data struct {
+				Entities []json.RawMessage `json:"entities"`
+				Deferred []string          `json:"deferred"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil || msg.Type != "state" {
+			continue
+		}
+		states++
+		if len(raw) > budget+1 {
+			t.Fatalf("state message is %d bytes, budget %d", len(raw), budget)
+		}
+		deferred += len(msg.Data.Deferred)
+	}
+	if deferred == 0 {
+		t.Fatal("expected a crowded AOI to defer some entities")
+	}
+}
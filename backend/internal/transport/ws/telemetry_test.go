@@ -99,3 +99,90 @@ func TestTelemetry_TickAndRTT(t *testing.T) {
 		t.Fatalf("did not receive telemetry in time")
 	}
 }
+
+func TestWS_TimeSyncAndStateStamps(t *testing.T) {
+	eng := sim.NewEngine(sim.Config{CellSize: 10, AOIRadius: 5, TickHz: 50, SnapshotHz: 20, HandoverHysteresisM: 1})
+	eng.Start()
+	defer eng.Stop(context.Background())
+
+	mux := http.NewServeMux()
+	Register(mux, "/ws", fakeAuthT{}, eng)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := nws.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close(nws.StatusNormalClosure, "bye")
+	if err := wsjson.Write(ctx, c, map[string]any{"token": "tok"}); err != nil {
+		t.Fatalf("hello: %v", err)
+	}
+	var ack struct {
+		Data struct {
+			Config struct {
+				InterpDelayMs float64 `json:"interp_delay_ms"`
+			} `json:"config"`
+		} `json:"data"`
+	}
+	if err := wsjson.Read(ctx, c, &ack); err != nil {
+		t.Fatalf("join_ack: %v", err)
+	}
+	if ack.Data.Config.InterpDelayMs != 100 {
+		t.Fatalf("interp_delay_ms = %v, want 100 (two 20Hz snapshots)", ack.Data.Config.InterpDelayMs)
+	}
+
+	type stamped struct {
+		Type string `json:"type"`
+		Data struct {
+			ID           int64   `json:"id"`
+			ClientTime   float64 `json:"client_time"`
+			Tick         uint64  `json:"tick"`
+			ServerTimeMs float64 `json:"server_time_ms"`
+		} `json:"data"`
+	}
+
+	// States are stamped with a non-decreasing tick and server time.
+	var last stamped
+	for states := 0; states < 3; {
+		var msg stamped
+		if err := wsjson.Read(ctx, c, &msg); err != nil {
+			t.Fatalf("read state: %v", err)
+		}
+		if msg.Type != "state" {
+			continue
+		}
+		if msg.Data.ServerTimeMs <= 0 {
+			t.Fatalf("state missing server_time_ms: %+v", msg.Data)
+		}
+		if msg.Data.Tick < last.Data.Tick || msg.Data.ServerTimeMs < last.Data.ServerTimeMs {
+			t.Fatalf("state stamps went backwards: %+v after %+v", msg.Data, last.Data)
+		}
+		last = msg
+		states++
+	}
+
+	if err := wsjson.Write(ctx, c, map[string]any{"type": "time_sync", "id": 7, "client_time": 123.5}); err != nil {
+		t.Fatalf("time_sync: %v", err)
+	}
+	for {
+		var msg stamped
+		if err := wsjson.Read(ctx, c, &msg); err != nil {
+			t.Fatalf("read time_sync reply: %v", err)
+		}
+		if msg.Type != "time_sync" {
+			continue
+		}
+		if msg.Data.ID != 7 || msg.Data.ClientTime != 123.5 {
+			t.Fatalf("time_sync did not echo request: %+v", msg.Data)
+		}
+		if msg.Data.ServerTimeMs < last.Data.ServerTimeMs || msg.Data.Tick < last.Data.Tick {
+			t.Fatalf("time_sync stamp %+v older than last state %+v", msg.Data, last.Data)
+		}
+		return
+	}
+}